@@ -16,7 +16,11 @@ package adabas
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"fmt"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -27,6 +31,9 @@ import (
 	"github.com/tknie/log"
 )
 
+// repositoryConfig map repository of the connection URL
+var repositoryConfig = regexp.MustCompile(`config=\[([^,\]]*),(\d+)`)
+
 type Adabas struct {
 	common.CommonDatabase
 	dbURL        string
@@ -43,6 +50,26 @@ func syncLog() {
 	adatypes.Central.Log.Debugf("Init debugging adatypes")
 }
 
+func init() {
+	for _, scheme := range []string{"acj", "adatcp"} {
+		common.RegisterDriverDefinition(scheme, &common.Driver{Type: common.AdabasType,
+			Name: "Adabas", Parse: parseURL, Factory: NewInstance})
+	}
+}
+
+// parseURL parse the Adabas URL like `adatcp://host:60001/4`, the
+// database id is 4 if not given
+func parseURL(url string) (*common.Reference, string, error) {
+	ref, passwd, err := common.ParseUrl(url)
+	if err != nil {
+		return nil, "", err
+	}
+	if ref.Database == "" {
+		ref.Database = "4"
+	}
+	return ref, passwd, nil
+}
+
 // NewInstance create new postgres reference instance
 func NewInstance(id common.RegDbID, reference *common.Reference, password string) (common.Database, error) {
 	url := fmt.Sprintf("acj;map;config=[adatcp://%s:%d,%s]",
@@ -85,6 +112,12 @@ func (ada *Adabas) URL() string {
 	return ada.dbURL
 }
 
+// IsnOrder records of queries without sort fields are read in ISN order,
+// keyset pages continue after the ISN of the last record
+func (ada *Adabas) IsnOrder() bool {
+	return true
+}
+
 // Maps database maps, tables or views
 func (ada *Adabas) Maps() ([]string, error) {
 	if ada.dbTableNames == nil {
@@ -98,6 +131,15 @@ func (ada *Adabas) Maps() ([]string, error) {
 
 // Ping create short test database connection
 func (ada *Adabas) Ping() error {
+	return ada.PingContext(context.Background())
+}
+
+// PingContext create short test database connection, Adabas does not
+// support cancel, so the context is only checked before the call
+func (ada *Adabas) PingContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	c, err := ada.Open()
 	if err != nil {
 		return err
@@ -141,12 +183,23 @@ func (ada *Adabas) FreeHandler() {
 
 // Insert insert record into table
 func (ada *Adabas) Insert(name string, insert *common.Entries) ([][]any, error) {
+	return ada.InsertContext(context.Background(), name, insert)
+}
+
+// InsertContext insert record into table, the context is checked before
+// each record is stored
+func (ada *Adabas) InsertContext(ctx context.Context, name string, insert *common.Entries) ([][]any, error) {
 	con, err := ada.Open()
 	if err != nil {
 		return nil, err
 	}
+	return ada.insert(ctx, con.(*adabas.Connection), name, insert, true)
+}
 
-	conn := con.(*adabas.Connection)
+// insert store the records using the connection, the transaction is
+// ended after each record if endTransaction is set
+func (ada *Adabas) insert(ctx context.Context, conn *adabas.Connection, name string,
+	insert *common.Entries, endTransaction bool) ([][]any, error) {
 	req, err := conn.CreateMapStoreRequest(name)
 	if err != nil {
 		return nil, err
@@ -156,7 +209,17 @@ func (ada *Adabas) Insert(name string, insert *common.Entries) ([][]any, error)
 	if err != nil {
 		return nil, err
 	}
+	var returning [][]any
+	if len(insert.Returning) > 0 {
+		returning = make([][]any, 0, len(insert.Values))
+	}
 	for _, v := range insert.Values {
+		if err = ctx.Err(); err != nil {
+			if endTransaction {
+				req.BackoutTransaction()
+			}
+			return nil, err
+		}
 		record, rerr := req.CreateRecord()
 		if rerr != nil {
 			return nil, rerr
@@ -174,30 +237,180 @@ func (ada *Adabas) Insert(name string, insert *common.Entries) ([][]any, error)
 			log.Log.Debugf("Error %v\n", err)
 			return nil, err
 		}
+		if len(insert.Returning) > 0 {
+			rv, err := insertReturning(name, insert, v, uint64(record.Isn))
+			if err != nil {
+				return nil, err
+			}
+			returning = append(returning, rv)
+		}
+		if !endTransaction {
+			continue
+		}
 		err = req.EndTransaction()
 		if err != nil {
 			log.Log.Debugf("ET Error %v\n", err)
 			return nil, err
 		}
 	}
+	if !endTransaction {
+		return returning, nil
+	}
 	err = req.EndTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return returning, nil
+}
+
+// insertReturning returning fields of the stored record. The field ISN
+// returns the ISN of the new record, the other fields the stored values.
+func insertReturning(name string, insert *common.Entries, values []any, isn uint64) ([]any, error) {
+	rv := make([]any, 0, len(insert.Returning))
+	for _, r := range insert.Returning {
+		if strings.EqualFold(r, "ISN") || r == "#ISN" {
+			rv = append(rv, isn)
+			continue
+		}
+		i := slices.IndexFunc(insert.Fields, func(f string) bool { return strings.EqualFold(f, r) })
+		if i < 0 || i >= len(values) {
+			return nil, errorrepo.NewError("DB000039", r, name)
+		}
+		rv = append(rv, values[i])
+	}
+	return rv, nil
+}
 
-	return nil, err
+// isnValue ISN of the value
+func isnValue(value any) (adatypes.Isn, error) {
+	switch v := value.(type) {
+	case int:
+		return adatypes.Isn(v), nil
+	case int32:
+		return adatypes.Isn(v), nil
+	case int64:
+		return adatypes.Isn(v), nil
+	case uint:
+		return adatypes.Isn(v), nil
+	case uint32:
+		return adatypes.Isn(v), nil
+	case uint64:
+		return adatypes.Isn(v), nil
+	case string:
+		iv, err := strconv.ParseUint(v, 0, 10)
+		if err != nil {
+			return 0, errorrepo.NewError("DB23445")
+		}
+		return adatypes.Isn(iv), nil
+	}
+	return 0, errorrepo.NewError("DB23445")
 }
 
 // Update update record in table
 func (ada *Adabas) Update(name string, insert *common.Entries) ([][]any, int64, error) {
-	return nil, 0, errorrepo.NewError("DB065535")
+	return ada.UpdateContext(context.Background(), name, insert)
+}
+
+// UpdateContext update records in table, the records are searched by the
+// ISN given in the field ISN named in Update
+func (ada *Adabas) UpdateContext(ctx context.Context, name string, insert *common.Entries) ([][]any, int64, error) {
+	con, err := ada.Open()
+	if err != nil {
+		return nil, 0, err
+	}
+	conn := con.(*adabas.Connection)
+	defer conn.Close()
+	return ada.update(ctx, conn, name, insert, true)
+}
+
+// update update the records by ISN using the connection, the transaction
+// is ended if endTransaction is set
+func (ada *Adabas) update(ctx context.Context, conn *adabas.Connection, name string,
+	updateInfo *common.Entries, endTransaction bool) ([][]any, int64, error) {
+	isnField := slices.IndexFunc(updateInfo.Fields, func(f string) bool { return strings.EqualFold(f, "ISN") })
+	if isnField < 0 || !slices.ContainsFunc(updateInfo.Update, func(u string) bool { return strings.EqualFold(u, "ISN") }) {
+		return nil, 0, errorrepo.NewError("DB065535")
+	}
+	req, err := conn.CreateMapStoreRequest(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = req.StoreFields(slices.Delete(slices.Clone(updateInfo.Fields), isnField, isnField+1))
+	if err != nil {
+		return nil, 0, err
+	}
+	var returning [][]any
+	if len(updateInfo.Returning) > 0 {
+		returning = make([][]any, 0, len(updateInfo.Values))
+	}
+	for _, v := range updateInfo.Values {
+		if err = ctx.Err(); err != nil {
+			if endTransaction {
+				req.BackoutTransaction()
+			}
+			return nil, 0, err
+		}
+		isn, err := isnValue(v[isnField])
+		if err != nil {
+			return nil, 0, err
+		}
+		record, err := req.CreateRecord()
+		if err != nil {
+			return nil, 0, err
+		}
+		record.Isn = isn
+		for i, rv := range v {
+			if i == isnField {
+				continue
+			}
+			err = record.SetValue(updateInfo.Fields[i], rv)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		log.Log.Debugf("Update ISN=%d values %#v\n", isn, v)
+		err = req.Update(record)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(updateInfo.Returning) > 0 {
+			rv, err := insertReturning(name, updateInfo, v, uint64(isn))
+			if err != nil {
+				return nil, 0, err
+			}
+			returning = append(returning, rv)
+		}
+	}
+	if endTransaction {
+		err = req.EndTransaction()
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return returning, int64(len(updateInfo.Values)), nil
 }
 
 // Delete Delete database records
 func (ada *Adabas) Delete(name string, remove *common.Entries) (int64, error) {
+	return ada.DeleteContext(context.Background(), name, remove)
+}
+
+// DeleteContext Delete database records, the context is checked before
+// the records are deleted
+func (ada *Adabas) DeleteContext(ctx context.Context, name string, remove *common.Entries) (int64, error) {
 	con, err := ada.Open()
 	if err != nil {
 		return 0, err
 	}
 	conn := con.(*adabas.Connection)
 	defer conn.Close()
+	return ada.delete(ctx, conn, name, remove, true)
+}
+
+// delete delete the records using the connection, the transaction is
+// ended if endTransaction is set
+func (ada *Adabas) delete(ctx context.Context, conn *adabas.Connection, name string,
+	remove *common.Entries, endTransaction bool) (int64, error) {
 	req, err := conn.CreateMapDeleteRequest(name)
 	if err != nil {
 		return 0, err
@@ -238,33 +451,24 @@ func (ada *Adabas) Delete(name string, remove *common.Entries) (int64, error) {
 
 		for i := 0; i < len(remove.Values); i++ {
 
-			switch v := remove.Values[i][0].(type) {
-			case int:
-				isns = append(isns, adatypes.Isn(v))
-			case int32:
-				isns = append(isns, adatypes.Isn(v))
-			case int64:
-				isns = append(isns, adatypes.Isn(v))
-			case uint:
-				isns = append(isns, adatypes.Isn(v))
-			case uint32:
-				isns = append(isns, adatypes.Isn(v))
-			case uint64:
-				isns = append(isns, adatypes.Isn(v))
-			case string:
-				iv, err := strconv.ParseUint(v, 0, 10)
-				if err != nil {
-					return 0, errorrepo.NewError("DB23445")
-				}
-				isns = append(isns, adatypes.Isn(iv))
+			isn, err := isnValue(remove.Values[i][0])
+			if err != nil {
+				return 0, err
 			}
+			isns = append(isns, isn)
 		}
 	}
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
 	log.Log.Debugf("Start deleting %d ISNs/records\n", len(isns))
 	err = req.DeleteList(isns)
 	if err != nil {
 		return 0, err
 	}
+	if !endTransaction {
+		return int64(len(isns)), nil
+	}
 	log.Log.Debugf("Commit deleting %d ISNs/records\n", len(isns))
 	err = req.EndTransaction()
 	if err != nil {
@@ -305,9 +509,116 @@ func (ada *Adabas) GetTableColumn(tableName string) ([]string, error) {
 	return conn.GetMaps()
 }
 
+// DescribeTableContext read the field definitions of the map, the types
+// and options are read out of the FDT of the data file. Adabas does not
+// support cancel, so the context is only checked before the call.
+func (ada *Adabas) DescribeTableContext(ctx context.Context, tableName string) (*common.TableInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	config := repositoryConfig.FindStringSubmatch(ada.URL())
+	if config == nil {
+		return nil, errorrepo.NewError("DB065535")
+	}
+	url, err := adabas.NewURL(config[1])
+	if err != nil {
+		return nil, err
+	}
+	fnr, err := strconv.Atoi(config[2])
+	if err != nil {
+		return nil, err
+	}
+	repositoryAda, err := adabas.NewAdabas(url)
+	if err != nil {
+		return nil, err
+	}
+	defer repositoryAda.Close()
+	adabasMap, err := adabas.NewMapRepository(url, adabas.Fnr(fnr)).SearchMap(repositoryAda, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if adabasMap.Data == nil {
+		return nil, errorrepo.NewError("DB000074", tableName)
+	}
+	dataAda, err := adabas.NewAdabas(&adabasMap.Data.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer dataAda.Close()
+	definition, err := dataAda.ReadFileDefinition(adabasMap.Data.Fnr)
+	if err != nil {
+		return nil, err
+	}
+	info := &common.TableInfo{Name: adabasMap.Name, Columns: make([]*common.Column, 0, len(adabasMap.Fields))}
+	for _, f := range adabasMap.Fields {
+		if strings.HasPrefix(f.ShortName, "#") {
+			log.Log.Debugf("Skip special field %s", f.LongName)
+			continue
+		}
+		adaType, err := definition.SearchType(f.ShortName)
+		if err != nil {
+			return nil, err
+		}
+		info.Columns = append(info.Columns, fieldColumn(f.LongName, adaType))
+	}
+	return info, nil
+}
+
+// fieldColumn column definition of the Adabas field. Null suppression is
+// the default, only fields with the NN option are not nullable. Unique
+// descriptors are unique, all descriptors are part of an index.
+func fieldColumn(name string, adaType adatypes.IAdaType) *common.Column {
+	column := &common.Column{Name: name, Length: uint16(adaType.Length()),
+		Nullable: !adaType.IsOption(adatypes.FieldOptionNN),
+		Unique:   adaType.IsOption(adatypes.FieldOptionUQ),
+		Index:    adaType.IsOption(adatypes.FieldOptionDE) || adaType.IsOption(adatypes.FieldOptionUQ)}
+	switch adaType.Type() {
+	case adatypes.FieldTypeString:
+		column.DataType = common.Alpha
+	case adatypes.FieldTypeLAString, adatypes.FieldTypeLBString:
+		column.DataType = common.Text
+	case adatypes.FieldTypeUnicode, adatypes.FieldTypeLAUnicode, adatypes.FieldTypeLBUnicode:
+		column.DataType = common.Unicode
+	case adatypes.FieldTypeCharacter:
+		column.DataType = common.Character
+	case adatypes.FieldTypeByte, adatypes.FieldTypeUByte, adatypes.FieldTypeInt2, adatypes.FieldTypeUInt2,
+		adatypes.FieldTypeShort, adatypes.FieldTypeInt4, adatypes.FieldTypeUInt4:
+		column.DataType = common.Integer
+		column.Length = 0
+	case adatypes.FieldTypeInt8, adatypes.FieldTypeUInt8, adatypes.FieldTypeLong:
+		column.DataType = common.BigInteger
+		column.Length = 0
+	case adatypes.FieldTypePacked, adatypes.FieldTypeUnpacked:
+		column.DataType = common.Number
+		if adaType.Fractional() > 0 {
+			column.DataType = common.Decimal
+			column.Digits = uint8(adaType.Fractional())
+		}
+	case adatypes.FieldTypeFloat, adatypes.FieldTypeDouble:
+		column.DataType = common.Decimal
+	case adatypes.FieldTypeByteArray:
+		column.DataType = common.Bytes
+		if adaType.IsOption(adatypes.FieldOptionLB) {
+			column.DataType = common.BLOB
+		}
+	default:
+		column.DataType = common.None
+	}
+	return column
+}
+
 // Query query database records with search or SELECT
 func (ada *Adabas) Query(search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	return ada.QueryContext(context.Background(), search, f)
+}
+
+// QueryContext query database records with search or SELECT, the context
+// is checked before each record is read
+func (ada *Adabas) QueryContext(ctx context.Context, search *common.Query, f common.ResultFunction) (*common.Result, error) {
 	search.Driver = common.AdabasType
+	if search.Join != "" || len(search.Joins) > 0 {
+		return nil, errorrepo.NewError("DB065535")
+	}
 	con, err := ada.Open()
 	if err != nil {
 		return nil, err
@@ -340,12 +651,33 @@ func (ada *Adabas) Query(search *common.Query, f common.ResultFunction) (*common
 		return nil, err
 	}
 
-	cursor, err := request.ReadPhysicalWithCursoring()
+	cursor, err := readCursor(request, search)
 	if err != nil {
 		return nil, err
 	}
 	result := &common.Result{}
+	skip := search.Offset
+	read := uint64(0)
 	for cursor.HasNextRecord() {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		if search.LimitRows > 0 && read == search.LimitRows {
+			break
+		}
+		if skip > 0 {
+			skip--
+			if search.DataStruct != nil {
+				_, err = cursor.NextData()
+			} else {
+				_, err = cursor.NextRecord()
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		read++
 		if search.DataStruct != nil {
 			record, err := cursor.NextData()
 			if err != nil {
@@ -361,6 +693,7 @@ func (ada *Adabas) Query(search *common.Query, f common.ResultFunction) (*common
 			if err != nil {
 				return nil, err
 			}
+			result.Isn = uint64(record.Isn)
 			result.Rows = make([]any, 0)
 			for _, v := range record.Value {
 				var vi interface{}
@@ -384,6 +717,110 @@ func (ada *Adabas) Query(search *common.Query, f common.ResultFunction) (*common
 	return result, nil
 }
 
+// readCursor start the read of the query. With a search the records are
+// searched, with one ascending sort field the records are ordered by the
+// descriptor. Keyset pages without sort fields are read in ISN order
+// after the ISN of the key, otherwise the records are read physically.
+func readCursor(request *adabas.ReadRequest, search *common.Query) (*adabas.Cursoring, error) {
+	if search.LimitRows > 0 {
+		// number of records read by one cursor call
+		request.Limit = search.LimitRows + search.Offset
+	}
+	where := search.Where
+	isnOrder := len(search.After) > 0 && len(search.Sort) == 0
+	if len(search.After) > 0 && !isnOrder {
+		keyset, err := search.KeysetPredicate()
+		if err != nil {
+			return nil, err
+		}
+		where = common.AndPredicate(where, keyset)
+	}
+	query := search.Search
+	if where != nil {
+		condition, err := adabasSearch(where)
+		if err != nil {
+			return nil, err
+		}
+		if query != "" {
+			query += " AND "
+		}
+		query += condition
+	}
+	switch {
+	case len(search.Sort) > 1 || (len(search.Sort) == 1 &&
+		(search.Sort[0].Descending || search.Sort[0].Nulls != common.NullsDefault)):
+		return nil, errorrepo.NewError("DB000051", "sort", "Adabas")
+	case len(search.Sort) == 1 && query != "":
+		log.Log.Debugf("Adabas search: %s ordered by %s", query, search.Sort[0].Field)
+		return request.SearchAndOrderWithCursoring(query, search.Sort[0].Field)
+	case len(search.Sort) == 1:
+		return request.ReadLogicalByCursoring(search.Sort[0].Field)
+	case isnOrder && query != "":
+		return nil, errorrepo.NewError("DB000051", "ISN keyset with search", "Adabas")
+	case isnOrder:
+		isn, err := afterIsn(search.After[0])
+		if err != nil {
+			return nil, err
+		}
+		log.Log.Debugf("Adabas read in ISN order after %d", isn)
+		request.Start = isn + 1
+		return request.ReadLogicalWithCursoring("")
+	case query != "":
+		log.Log.Debugf("Adabas search: %s", query)
+		return request.ReadLogicalWithCursoring(query)
+	}
+	return request.ReadPhysicalWithCursoring()
+}
+
+// afterIsn ISN of the keyset key
+func afterIsn(key any) (uint64, error) {
+	switch v := key.(type) {
+	case uint64:
+		return v, nil
+	case int64:
+		if v >= 0 {
+			return uint64(v), nil
+		}
+	case int:
+		if v >= 0 {
+			return uint64(v), nil
+		}
+	}
+	return 0, errorrepo.NewError("DB000051", fmt.Sprintf("ISN key %v", key), "Adabas")
+}
+
+// CountContext count all records of the query
+func (ada *Adabas) CountContext(ctx context.Context, search *common.Query) (int64, error) {
+	con, err := ada.Open()
+	if err != nil {
+		return 0, err
+	}
+	conn := con.(*adabas.Connection)
+	request, err := conn.CreateMapReadRequest(search.TableName)
+	if err != nil {
+		return 0, err
+	}
+	err = request.QueryFields("")
+	if err != nil {
+		return 0, err
+	}
+	cursor, err := readCursor(request, search.CountQuery())
+	if err != nil {
+		return 0, err
+	}
+	count := int64(0)
+	for cursor.HasNextRecord() {
+		if err = ctx.Err(); err != nil {
+			return 0, err
+		}
+		if _, err = cursor.NextRecord(); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, cursor.Error()
+}
+
 // CreateTable create a new table
 func (ada *Adabas) CreateTable(string, any) error {
 	return errorrepo.NewError("DB065535")
@@ -404,16 +841,31 @@ func (ada *Adabas) Batch(batch string) error {
 	return errorrepo.NewError("DB065535")
 }
 
+// BatchContext batch SQL query in table
+func (ada *Adabas) BatchContext(context.Context, string) error {
+	return errorrepo.NewError("DB065535")
+}
+
 // BatchSelect batch SQL query in table with values returned
 func (ada *Adabas) BatchSelect(batch string) ([][]interface{}, error) {
 	return nil, errorrepo.NewError("DB065535")
 }
 
+// BatchSelectContext batch SQL query in table with values returned
+func (ada *Adabas) BatchSelectContext(context.Context, string) ([][]interface{}, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
 // BatchSelectFct batch SQL query in table with fct called
 func (ada *Adabas) BatchSelectFct(*common.Query, common.ResultFunction) error {
 	return errorrepo.NewError("DB065535")
 }
 
+// BatchSelectFctContext batch SQL query in table with fct called
+func (ada *Adabas) BatchSelectFctContext(context.Context, *common.Query, common.ResultFunction) error {
+	return errorrepo.NewError("DB065535")
+}
+
 func (ada *Adabas) BeginTransaction() error {
 	return errorrepo.NewError("DB065535")
 }
@@ -426,7 +878,91 @@ func (ada *Adabas) Rollback() error {
 	return errorrepo.NewError("DB065535")
 }
 
+// BeginTx begin a transaction handle using its own connection. The
+// records are stored or deleted in one Adabas transaction, which is
+// ended with ET at commit or backed out with BT at rollback.
+func (ada *Adabas) BeginTx(ctx context.Context, opts *common.TxOptions) (common.Transaction, error) {
+	if opts != nil && opts.Isolation != sql.LevelDefault {
+		return nil, errorrepo.NewError("DB000047", opts.Isolation.String(), "adabas")
+	}
+	txAda := ada.Clone().(*Adabas)
+	txAda.conn = nil
+	con, err := txAda.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{ada: txAda, conn: con.(*adabas.Connection),
+		readOnly: opts != nil && opts.ReadOnly}, nil
+}
+
+// transaction transaction handle of Adabas
+type transaction struct {
+	ada      *Adabas
+	conn     *adabas.Connection
+	readOnly bool
+}
+
+// QueryContext query database records, the query is not part of the
+// Adabas transaction
+func (transaction *transaction) QueryContext(ctx context.Context, search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	return transaction.ada.QueryContext(ctx, search, f)
+}
+
+// InsertContext insert records in the transaction
+func (transaction *transaction) InsertContext(ctx context.Context, name string, insert *common.Entries) ([][]any, error) {
+	if transaction.readOnly {
+		return nil, errorrepo.NewError("DB000048", name)
+	}
+	return transaction.ada.insert(ctx, transaction.conn, name, insert, false)
+}
+
+// UpdateContext update records by ISN in the transaction
+func (transaction *transaction) UpdateContext(ctx context.Context, name string, updateInfo *common.Entries) ([][]any, int64, error) {
+	if transaction.readOnly {
+		return nil, 0, errorrepo.NewError("DB000048", name)
+	}
+	return transaction.ada.update(ctx, transaction.conn, name, updateInfo, false)
+}
+
+// DeleteContext delete records in the transaction
+func (transaction *transaction) DeleteContext(ctx context.Context, name string, remove *common.Entries) (int64, error) {
+	if transaction.readOnly {
+		return 0, errorrepo.NewError("DB000048", name)
+	}
+	return transaction.ada.delete(ctx, transaction.conn, name, remove, false)
+}
+
+// StreamContext streaming data from a field, the stream is not part of
+// the Adabas transaction
+func (transaction *transaction) StreamContext(ctx context.Context, search *common.Query, sf common.StreamFunction) error {
+	return transaction.ada.StreamContext(ctx, search, sf)
+}
+
+// BatchContext batch SQL query, not implemented
+func (transaction *transaction) BatchContext(ctx context.Context, batch string) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// Commit end the Adabas transaction and close the connection
+func (transaction *transaction) Commit(ctx context.Context) error {
+	defer transaction.conn.Close()
+	return transaction.conn.EndTransaction()
+}
+
+// Rollback back out the Adabas transaction and close the connection
+func (transaction *transaction) Rollback(ctx context.Context) error {
+	defer transaction.conn.Close()
+	return transaction.conn.BackoutTransaction()
+}
+
+// Stream streaming data from a field
 func (ada *Adabas) Stream(search *common.Query, sf common.StreamFunction) error {
+	return ada.StreamContext(context.Background(), search, sf)
+}
+
+// StreamContext streaming data from a field, the context is checked
+// before each segment is read
+func (ada *Adabas) StreamContext(ctx context.Context, search *common.Query, sf common.StreamFunction) error {
 	con, err := ada.Open()
 	if err != nil {
 		return err
@@ -451,6 +987,9 @@ func (ada *Adabas) Stream(search *common.Query, sf common.StreamFunction) error
 	stream := &common.Stream{}
 	dataRead := 0
 	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		stream.Data, err = sread.ReadLOBSegment(result.Values[0].Isn, search.Fields[0], uint64(search.Blocksize))
 		if err != nil {
 			fmt.Printf("Error read LOB segment: %v\n", err)
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tknie/adabas-go-api/adatypes"
 	"github.com/tknie/flynn/common"
 )
 
@@ -47,6 +48,16 @@ func TestAdabasInit(t *testing.T) {
 		"TESTTABLE"}, m)
 }
 
+func TestAdaReference(t *testing.T) {
+	ref, _, err := common.NewReference("adatcp://adahost:123")
+	assert.NoError(t, err)
+	assert.Equal(t, &common.Reference{Driver: common.AdabasType, Host: "adahost", Port: 123, Database: "4"}, ref)
+	ref, _, err = common.NewReference("acj://adahost:123/12")
+	assert.NoError(t, err)
+	assert.Equal(t, &common.Reference{Driver: common.AdabasType, Host: "adahost", Port: 123, Database: "12"}, ref)
+	assert.Equal(t, "Adabas", common.AdabasType.String())
+}
+
 func TestAdaSearch(t *testing.T) {
 	e := &common.Entries{Fields: []string{"aaa"},
 		Values: [][]any{{"XXX"}}}
@@ -59,3 +70,51 @@ func TestAdaSearch(t *testing.T) {
 	assert.Equal(t, "aaa=['XXX'0x00:'XXX'0xff]", search)
 
 }
+
+func TestAdaPredicate(t *testing.T) {
+	search, err := adabasSearch(common.And(common.Eq("AA", "11100301"),
+		common.Or(common.In("AE", "SMITH", "JONES"), common.Like("AC", "JO%")),
+		common.Between("AH", 10, 20), common.Ne("AD", 5)))
+	assert.NoError(t, err)
+	assert.Equal(t, "AA='11100301' AND AE='SMITH' OR AE='JONES' OR AC=['JO'0x00:'JO'0xff] AND AH=[10:20] AND AD!=5", search)
+
+	for _, p := range []common.Predicate{common.Not(common.Eq("AA", 1)), common.IsNull("AA"),
+		common.Like("AA", "%X"), common.Or(common.And(common.Eq("AA", 1), common.Eq("AB", 2))),
+		common.Eq("AA", "O'Brien"), common.Eq("AA=1 OR AB", 1)} {
+		_, err = adabasSearch(p)
+		assert.Error(t, err)
+	}
+}
+
+func TestAdaSearchInsertReturning(t *testing.T) {
+	insert := &common.Entries{Fields: []string{"AA", "AE"}, Returning: []string{"ISN", "ae"}}
+	rv, err := insertReturning("EMPLOYEES", insert, []any{"11100301", "SMITH"}, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{uint64(1024), "SMITH"}, rv)
+
+	insert.Returning = []string{"AB"}
+	_, err = insertReturning("EMPLOYEES", insert, []any{"11100301", "SMITH"}, 1024)
+	assert.Error(t, err)
+}
+
+func TestAdaSchemaColumn(t *testing.T) {
+	name := adatypes.NewTypeWithLength(adatypes.FieldTypeString, "AE", 20)
+	name.AddOption(adatypes.FieldOptionDE)
+	assert.Equal(t, &common.Column{Name: "Name", DataType: common.Alpha, Length: 20, Nullable: true, Index: true},
+		fieldColumn("Name", name))
+
+	id := adatypes.NewTypeWithLength(adatypes.FieldTypeString, "AA", 8)
+	id.AddOption(adatypes.FieldOptionUQ)
+	id.AddOption(adatypes.FieldOptionNN)
+	column := fieldColumn("PersonnelId", id)
+	assert.Equal(t, []bool{false, true, true}, []bool{column.Nullable, column.Unique, column.Index})
+
+	salary := adatypes.NewTypeWithLength(adatypes.FieldTypePacked, "AS", 5)
+	assert.Equal(t, common.Number, fieldColumn("Salary", salary).DataType)
+	salary.SetFractional(2)
+	column = fieldColumn("Salary", salary)
+	assert.Equal(t, []any{common.Decimal, uint16(5), uint8(2)}, []any{column.DataType, column.Length, column.Digits})
+	column = fieldColumn("Count", adatypes.NewTypeWithLength(adatypes.FieldTypeUInt4, "AD", 4))
+	assert.Equal(t, []any{common.Integer, uint16(0)}, []any{column.DataType, column.Length})
+	assert.Equal(t, common.Unicode, fieldColumn("Remark", adatypes.NewTypeWithLength(adatypes.FieldTypeUnicode, "AF", 0)).DataType)
+}
@@ -0,0 +1,168 @@
+//go:build !flynn_noadabas
+// +build !flynn_noadabas
+
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package adabas
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+)
+
+var adabasFieldRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// adabasSearch render the predicate to an Adabas search. Adabas splits the
+// search at AND first and at OR second, so only OR conditions inside AND
+// conditions can be searched. NOT, NULL checks and LIKE patterns other
+// than a prefix are not available.
+func adabasSearch(predicate common.Predicate) (string, error) {
+	var buffer strings.Builder
+	for _, term := range andTerms(predicate) {
+		conditions, err := orConditions(term)
+		if err != nil {
+			return "", err
+		}
+		if buffer.Len() > 0 {
+			buffer.WriteString(" AND ")
+		}
+		buffer.WriteString(strings.Join(conditions, " OR "))
+	}
+	return buffer.String(), nil
+}
+
+// andTerms flatten nested AND predicates
+func andTerms(predicate common.Predicate) []common.Predicate {
+	if l, ok := predicate.(*common.LogicalPredicate); ok && !l.Or {
+		terms := make([]common.Predicate, 0, len(l.Predicates))
+		for _, p := range l.Predicates {
+			terms = append(terms, andTerms(p)...)
+		}
+		return terms
+	}
+	return []common.Predicate{predicate}
+}
+
+// orConditions Adabas conditions of the predicate combined with OR
+func orConditions(predicate common.Predicate) ([]string, error) {
+	switch p := predicate.(type) {
+	case *common.LogicalPredicate:
+		if !p.Or || len(p.Predicates) == 0 {
+			return nil, errorrepo.NewError("DB000051", "AND inside OR", "Adabas")
+		}
+		conditions := make([]string, 0, len(p.Predicates))
+		for _, sub := range p.Predicates {
+			c, err := orConditions(sub)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, c...)
+		}
+		return conditions, nil
+	case *common.InPredicate:
+		if len(p.Values) == 0 {
+			return nil, errorrepo.NewError("DB000051", "empty IN", "Adabas")
+		}
+		conditions := make([]string, 0, len(p.Values))
+		for _, v := range p.Values {
+			c, err := adabasCondition(p.Field, "=", v)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, c)
+		}
+		return conditions, nil
+	case *common.ComparePredicate:
+		operator := string(p.Operator)
+		if p.Operator == common.OpNe {
+			operator = "!="
+		}
+		c, err := adabasCondition(p.Field, operator, p.Value)
+		if err != nil {
+			return nil, err
+		}
+		return []string{c}, nil
+	case *common.BetweenPredicate:
+		low, err := adabasValue(p.Low)
+		if err != nil {
+			return nil, err
+		}
+		high, err := adabasValue(p.High)
+		if err != nil {
+			return nil, err
+		}
+		if !adabasFieldRegexp.MatchString(p.Field) {
+			return nil, errorrepo.NewError("DB000052", p.Field)
+		}
+		return []string{p.Field + "=[" + low + ":" + high + "]"}, nil
+	case *common.LikePredicate:
+		prefix, wildcard := strings.CutSuffix(p.Pattern, "%")
+		if strings.ContainsAny(prefix, "%_") {
+			return nil, errorrepo.NewError("DB000051", "LIKE "+p.Pattern, "Adabas")
+		}
+		if !wildcard {
+			c, err := adabasCondition(p.Field, "=", prefix)
+			if err != nil {
+				return nil, err
+			}
+			return []string{c}, nil
+		}
+		value, err := adabasValue(prefix)
+		if err != nil {
+			return nil, err
+		}
+		if !adabasFieldRegexp.MatchString(p.Field) {
+			return nil, errorrepo.NewError("DB000052", p.Field)
+		}
+		return []string{p.Field + "=[" + value + "0x00:" + value + "0xff]"}, nil
+	case *common.NullPredicate:
+		return nil, errorrepo.NewError("DB000051", "NULL check", "Adabas")
+	case *common.NotPredicate:
+		return nil, errorrepo.NewError("DB000051", "NOT", "Adabas")
+	}
+	return nil, errorrepo.NewError("DB000051", "nil", "Adabas")
+}
+
+// adabasCondition Adabas condition comparing field and value
+func adabasCondition(field, operator string, v any) (string, error) {
+	if !adabasFieldRegexp.MatchString(field) {
+		return "", errorrepo.NewError("DB000052", field)
+	}
+	value, err := adabasValue(v)
+	if err != nil {
+		return "", err
+	}
+	return field + operator + value, nil
+}
+
+// adabasValue Adabas search constant of the value, strings are quoted
+func adabasValue(v any) (string, error) {
+	switch value := v.(type) {
+	case string:
+		if strings.ContainsRune(value, '\'') {
+			return "", errorrepo.NewError("DB000051", "quote in value", "Adabas")
+		}
+		return "'" + value + "'", nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(value), nil
+	case bool:
+		if value {
+			return "1", nil
+		}
+		return "0", nil
+	}
+	return "", errorrepo.NewError("DB000051", fmt.Sprintf("value type %T", v), "Adabas")
+}
@@ -0,0 +1,164 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/log"
+)
+
+// AdaptChangeType type of a column change adapting a table
+type AdaptChangeType byte
+
+const (
+	// AddColumn column of the structure is missing in the table
+	AddColumn AdaptChangeType = iota
+	// DropColumn column of the table is not part of the structure
+	DropColumn
+	// AlterColumn type, length, nullability or default of the column
+	// differs
+	AlterColumn
+)
+
+var adaptChangeTypeNames = []string{"add", "drop", "alter"}
+
+func (ct AdaptChangeType) String() string {
+	if int(ct) >= len(adaptChangeTypeNames) {
+		return "unknown"
+	}
+	return adaptChangeTypeNames[ct]
+}
+
+// AdaptChange change of one column adapting a table. From and To are the
+// column definitions in the table and in the structure, Reasons describe
+// the differences of altered columns. Destructive changes may lose data,
+// like dropped columns, narrowed types or new NOT NULL constraints.
+// Unsupported changes cannot be applied by the database, they have no
+// statements and are skipped.
+type AdaptChange struct {
+	Type        AdaptChangeType
+	Column      string
+	From        string
+	To          string
+	Reasons     []string
+	Destructive bool
+	Unsupported bool
+	Statements  []string
+}
+
+// AdaptPlan changes adapting the table to the structure or columns. The
+// plan can be rendered as SQL for a dry run or applied using
+// ApplyAdaptPlan.
+type AdaptPlan struct {
+	TableName string
+	Changes   []*AdaptChange
+}
+
+// AdaptOptions options applying an adapt plan. Destructive allows the
+// destructive changes of the plan.
+type AdaptOptions struct {
+	Destructive bool
+}
+
+// TableAdapter database driver planning the changes adapting a table
+type TableAdapter interface {
+	PlanAdaptTableContext(ctx context.Context, name string, columns any) (*AdaptPlan, error)
+}
+
+// Destructive check if the plan contains destructive changes, unsupported
+// changes are not applied and not counted
+func (plan *AdaptPlan) Destructive() bool {
+	for _, c := range plan.Changes {
+		if c.Destructive && !c.Unsupported {
+			return true
+		}
+	}
+	return false
+}
+
+// Statements SQL statements of the plan, the destructive changes are
+// skipped unless they are allowed
+func (plan *AdaptPlan) Statements(destructive bool) []string {
+	statements := make([]string, 0, len(plan.Changes))
+	for _, c := range plan.Changes {
+		if !c.Destructive || destructive {
+			statements = append(statements, c.Statements...)
+		}
+	}
+	return statements
+}
+
+// SQL SQL script of all changes of the plan for a dry run
+func (plan *AdaptPlan) SQL() string {
+	var buffer strings.Builder
+	for _, s := range plan.Statements(true) {
+		buffer.WriteString(s + ";\n")
+	}
+	return buffer.String()
+}
+
+// PlanAdaptTable plan the changes adapting the table to the structure or
+// columns without changing the table
+func (id RegDbID) PlanAdaptTable(tableName string, columns any) (*AdaptPlan, error) {
+	return id.PlanAdaptTableContext(context.Background(), tableName, columns)
+}
+
+// PlanAdaptTableContext plan the changes adapting the table using context
+func (id RegDbID) PlanAdaptTableContext(ctx context.Context, tableName string, columns any) (*AdaptPlan, error) {
+	driver, err := searchDataDriverContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	adapter, ok := driver.(TableAdapter)
+	if !ok {
+		log.Log.Debugf("%s: adapt plan not supported", id)
+		return nil, errorrepo.NewError("DB065535")
+	}
+	plan, err := adapter.PlanAdaptTableContext(ctx, tableName, columns)
+	return plan, ContextError(ctx, err)
+}
+
+// ApplyAdaptPlan apply the changes of the plan. A plan with destructive
+// changes is only applied if the options allow destructive changes.
+func (id RegDbID) ApplyAdaptPlan(plan *AdaptPlan, options *AdaptOptions) error {
+	return id.ApplyAdaptPlanContext(context.Background(), plan, options)
+}
+
+// ApplyAdaptPlanContext apply the changes of the plan using context. The
+// statements are executed one after the other, DDL statements are not
+// rolled back by all databases.
+func (id RegDbID) ApplyAdaptPlanContext(ctx context.Context, plan *AdaptPlan, options *AdaptOptions) error {
+	destructive := options != nil && options.Destructive
+	if plan.Destructive() && !destructive {
+		columns := make([]string, 0)
+		for _, c := range plan.Changes {
+			if c.Destructive && !c.Unsupported {
+				columns = append(columns, c.Column)
+			}
+		}
+		return errorrepo.NewError("DB000072", plan.TableName, strings.Join(columns, ","))
+	}
+	driver, err := searchDataDriverContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, s := range plan.Statements(destructive) {
+		log.Log.Debugf("Adapt cmd %s", s)
+		if err := driver.BatchContext(ctx, s); err != nil {
+			return ContextError(ctx, err)
+		}
+	}
+	return nil
+}
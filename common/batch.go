@@ -0,0 +1,135 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"context"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/log"
+)
+
+// BatchModifier database driver sending the update or delete statements of
+// all value rows as one batch. The rows affected by each value row are
+// returned.
+type BatchModifier interface {
+	BatchUpdateContext(ctx context.Context, name string, update *Entries) ([]int64, error)
+	BatchDeleteContext(ctx context.Context, name string, remove *Entries) ([]int64, error)
+}
+
+// BatchUpdate update records of all value rows in one batch and return the
+// rows affected by each value row
+func (id RegDbID) BatchUpdate(name string, update *Entries) ([]int64, error) {
+	return id.BatchUpdateContext(context.Background(), name, update)
+}
+
+// BatchUpdateContext update records of all value rows in one batch, the
+// update is canceled and rolled back if the context is done
+func (id RegDbID) BatchUpdateContext(ctx context.Context, name string, update *Entries) ([]int64, error) {
+	modifier, err := id.batchModifier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	update.updateTimestamps()
+	rowsAffected, err := modifier.BatchUpdateContext(ctx, name, update)
+	if err == nil {
+		update.incrementVersion()
+	}
+	return rowsAffected, ContextError(ctx, err)
+}
+
+// BatchDelete delete records of all value rows in one batch and return the
+// rows affected by each value row
+func (id RegDbID) BatchDelete(name string, remove *Entries) ([]int64, error) {
+	return id.BatchDeleteContext(context.Background(), name, remove)
+}
+
+// BatchDeleteContext delete records of all value rows in one batch, the
+// delete is canceled and rolled back if the context is done
+func (id RegDbID) BatchDeleteContext(ctx context.Context, name string, remove *Entries) ([]int64, error) {
+	modifier, err := id.batchModifier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, err := batchDeleteEntries(ctx, modifier, name, remove)
+	return rowsAffected, ContextError(ctx, err)
+}
+
+// batchDeleteEntries delete the records or update the deleted tagged field
+// of the data structure in one batch
+func batchDeleteEntries(ctx context.Context, modifier BatchModifier, name string, remove *Entries) ([]int64, error) {
+	update, err := remove.softDelete(name)
+	if err != nil {
+		return nil, err
+	}
+	if update != nil {
+		return modifier.BatchUpdateContext(ctx, name, update)
+	}
+	return modifier.BatchDeleteContext(ctx, name, remove)
+}
+
+func (id RegDbID) batchModifier(ctx context.Context) (BatchModifier, error) {
+	driver, err := searchDataDriverContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	modifier, ok := driver.(BatchModifier)
+	if !ok {
+		log.Log.Debugf("%s: batch update and delete not supported", id)
+		return nil, errorrepo.NewError("DB065535")
+	}
+	return modifier, nil
+}
+
+// BatchUpdate update records of all value rows in one batch in the
+// transaction
+func (tx *Tx) BatchUpdate(name string, update *Entries) ([]int64, error) {
+	return tx.BatchUpdateContext(tx.ctx, name, update)
+}
+
+// BatchUpdateContext update records of all value rows in one batch in the
+// transaction using context
+func (tx *Tx) BatchUpdateContext(ctx context.Context, name string, update *Entries) ([]int64, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	modifier, ok := tx.transaction.(BatchModifier)
+	if !ok {
+		return nil, errorrepo.NewError("DB065535")
+	}
+	update.updateTimestamps()
+	rowsAffected, err := modifier.BatchUpdateContext(ctx, name, update)
+	if err == nil {
+		update.incrementVersion()
+	}
+	return rowsAffected, ContextError(ctx, err)
+}
+
+// BatchDelete delete records of all value rows in one batch in the
+// transaction
+func (tx *Tx) BatchDelete(name string, remove *Entries) ([]int64, error) {
+	return tx.BatchDeleteContext(tx.ctx, name, remove)
+}
+
+// BatchDeleteContext delete records of all value rows in one batch in the
+// transaction using context
+func (tx *Tx) BatchDeleteContext(ctx context.Context, name string, remove *Entries) ([]int64, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	modifier, ok := tx.transaction.(BatchModifier)
+	if !ok {
+		return nil, errorrepo.NewError("DB065535")
+	}
+	rowsAffected, err := batchDeleteEntries(ctx, modifier, name, remove)
+	return rowsAffected, ContextError(ctx, err)
+}
@@ -0,0 +1,107 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+)
+
+// BulkThreshold number of rows an insert uses the bulk insert without
+// being requested by Bulk, zero disables the automatic bulk insert
+var BulkThreshold = 1000
+
+// BulkBatchSize maximum number of rows inserted by one bulk statement
+var BulkBatchSize = 1000
+
+// UseBulk check if the insert of the given number of rows uses the bulk
+// insert. The bulk insert cannot return fields, so an explicit request
+// together with Returning is an error.
+func (entries *Entries) UseBulk(name string, rows int) (bool, error) {
+	if len(entries.Returning) > 0 {
+		if entries.Bulk {
+			return false, errorrepo.NewError("DB000059", name)
+		}
+		return false, nil
+	}
+	return entries.Bulk || (BulkThreshold > 0 && rows >= BulkThreshold), nil
+}
+
+// InsertValues fields and values of each row of the insert. The values are
+// taken from the data structure, from maps of field names or directly from
+// the Values rows. Map entries use all map keys if Fields contains `*`,
+// missing map keys are inserted as NULL.
+func (entries *Entries) InsertValues() ([]string, [][]any, error) {
+	fields, values, _, err := entries.insertValues()
+	return fields, values, err
+}
+
+func (entries *Entries) insertValues() ([]string, [][]any, *typeInterface, error) {
+	if entries.DataStruct != nil {
+		dynamic := CreateInterface(entries.DataStruct, entries.Fields)
+		values := make([][]any, 0, len(entries.Values))
+		for _, vi := range entries.Values {
+			v, err := dynamic.CreateValues(vi[0])
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			values = append(values, v)
+		}
+		return dynamic.RowFields, values, dynamic, nil
+	}
+	if len(entries.Values) == 0 || len(entries.Values[0]) == 0 {
+		return entries.Fields, entries.Values, nil, nil
+	}
+	if _, isMap := entries.Values[0][0].(map[string]any); !isMap {
+		return entries.Fields, entries.Values, nil, nil
+	}
+	maps := make([]map[string]any, 0)
+	for _, row := range entries.Values {
+		for _, v := range row {
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, nil, nil, errorrepo.NewError("DB000060", fmt.Sprintf("%T", v))
+			}
+			maps = append(maps, m)
+		}
+	}
+	fields := entries.Fields
+	if slices.Contains(fields, "*") {
+		fields = make([]string, 0, len(maps[0]))
+		for f := range maps[0] {
+			fields = append(fields, f)
+		}
+		sort.Strings(fields)
+	}
+	values := make([][]any, 0, len(maps))
+	for _, m := range maps {
+		rv := make([]any, 0, len(fields))
+		for _, f := range fields {
+			v, ok := m[f]
+			if !ok {
+				for k, mv := range m {
+					if strings.EqualFold(k, f) {
+						v = mv
+						break
+					}
+				}
+			}
+			rv = append(rv, v)
+		}
+		values = append(values, rv)
+	}
+	return fields, values, nil, nil
+}
@@ -0,0 +1,70 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsertValues(t *testing.T) {
+	InitLog(t)
+
+	m1 := map[string]any{"ID": "A", "Name": "Anna"}
+	m2 := map[string]any{"ID": "B", "Counter": 2}
+	fields, values, err := (&Entries{Fields: []string{"ID", "Counter"}, Values: [][]any{{m1, m2}}}).InsertValues()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ID", "Counter"}, fields)
+	assert.Equal(t, [][]any{{"A", nil}, {"B", 2}}, values)
+
+	fields, values, err = (&Entries{Fields: []string{"*"}, Values: [][]any{{m1}, {m2}}}).InsertValues()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ID", "Name"}, fields)
+	assert.Equal(t, [][]any{{"A", "Anna"}, {"B", nil}}, values)
+
+	_, _, err = (&Entries{Fields: []string{"*"}, Values: [][]any{{m1, "B"}}}).InsertValues()
+	assert.Error(t, err)
+
+	fields, values, err = (&Entries{Fields: []string{"ID"}, Values: [][]any{{"A"}}}).InsertValues()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ID"}, fields)
+	assert.Equal(t, [][]any{{"A"}}, values)
+}
+
+func TestUseBulk(t *testing.T) {
+	InitLog(t)
+
+	threshold := BulkThreshold
+	defer func() { BulkThreshold = threshold }()
+	BulkThreshold = 3
+
+	bulk, err := (&Entries{}).UseBulk("ABC", 2)
+	assert.NoError(t, err)
+	assert.False(t, bulk)
+	bulk, err = (&Entries{}).UseBulk("ABC", 3)
+	assert.NoError(t, err)
+	assert.True(t, bulk)
+	bulk, err = (&Entries{Bulk: true}).UseBulk("ABC", 1)
+	assert.NoError(t, err)
+	assert.True(t, bulk)
+	bulk, err = (&Entries{Returning: []string{"ID"}}).UseBulk("ABC", 5)
+	assert.NoError(t, err)
+	assert.False(t, bulk)
+	_, err = (&Entries{Bulk: true, Returning: []string{"ID"}}).UseBulk("ABC", 5)
+	assert.Error(t, err)
+
+	BulkThreshold = 0
+	bulk, err = (&Entries{}).UseBulk("ABC", 5000)
+	assert.NoError(t, err)
+	assert.False(t, bulk)
+}
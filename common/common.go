@@ -12,6 +12,7 @@
 package common
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -32,9 +33,15 @@ const (
 	JSONTag
 	IndexTag
 	KeyTag
+	JoinTag
+	VersionTag
+	CreatedTag
+	UpdatedTag
+	DeletedTag
 )
 
-var tagInfoNames = []string{"Normal", "Ignore", "Sub", "YAML", "XML", "JSON", "Index", "Key"}
+var tagInfoNames = []string{"Normal", "Ignore", "Sub", "YAML", "XML", "JSON", "Index", "Key", "Join", "Version",
+	"Created", "Updated", "Deleted"}
 
 func (tagInfo TagInfo) String() string {
 	return tagInfoNames[tagInfo] + " Tag"
@@ -53,6 +60,14 @@ func TagInfoParse(info string) (string, TagInfo) {
 			return infoSplit[0], KeyTag
 		case "isn":
 			return infoSplit[0], IndexTag
+		case "version":
+			return infoSplit[0], VersionTag
+		case "created":
+			return infoSplit[0], CreatedTag
+		case "updated":
+			return infoSplit[0], UpdatedTag
+		case "deleted":
+			return infoSplit[0], DeletedTag
 		case "sub":
 			return infoSplit[0], SubTag
 		case "yaml":
@@ -62,10 +77,49 @@ func TagInfoParse(info string) (string, TagInfo) {
 		case "json":
 			return infoSplit[0], JSONTag
 		}
+		if _, ok := TagJoinAlias(info); ok {
+			return infoSplit[0], JoinTag
+		}
 	}
 	return infoSplit[0], NormalTag
 }
 
+// SplitTag split the tag into its colon separated parts. Colons inside
+// single or double quotes like in `default='12:00'` are part of the value.
+func SplitTag(tag string) []string {
+	parts := make([]string, 0, 4)
+	var quote rune
+	start := 0
+	for i, r := range tag {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ':':
+			parts = append(parts, tag[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tag[start:])
+}
+
+// TagJoinAlias alias of the joined table of a tag like `author:join=authors`.
+// The columns of the joined table are mapped into the tagged struct field.
+func TagJoinAlias(info string) (string, bool) {
+	infoSplit := strings.Split(info, ":")
+	if len(infoSplit) < 2 {
+		return "", false
+	}
+	option, alias, ok := strings.Cut(infoSplit[1], "=")
+	if !ok || strings.ToLower(option) != "join" || alias == "" {
+		return "", false
+	}
+	return alias, true
+}
+
 type CreateStatus byte
 
 const (
@@ -84,6 +138,12 @@ type Result struct {
 	Header  []*Column
 	Rows    []any
 	Data    any
+	// Isn Adabas ISN of the record
+	Isn uint64
+	// NextToken continuation token of the next page, empty on the last page
+	NextToken string
+	// Total number of rows of the query if requested by the page
+	Total int64
 }
 
 type Stream struct {
@@ -93,10 +153,21 @@ type Stream struct {
 type Entries struct {
 	Fields     []string
 	DataStruct any
-	Update     []string
-	Values     [][]any
-	Returning  []string
-	Criteria   string
+	// Update key fields searching the records to update, the values are
+	// taken from the value rows. Comparisons like `Amount > 0` bind quoted
+	// strings and placeholders only, numbers and field names like in
+	// `A=B` stay part of the condition and other values are rejected.
+	Update    []string
+	Values    [][]any
+	Returning []string
+	Criteria  string
+	// Parameters bound to the placeholders of Criteria and of the
+	// comparisons in Update
+	Parameters []any
+	// Version field used for optimistic locking, by default the field of
+	// the data structure tagged with `:version`
+	Version string
+	Bulk    bool
 }
 
 type Database interface {
@@ -104,6 +175,7 @@ type Database interface {
 	ID() RegDbID
 	URL() string
 	Ping() error
+	PingContext(ctx context.Context) error
 	SetCredentials(string, string) error
 	Maps() ([]string, error)
 	Clone() Database
@@ -115,16 +187,33 @@ type Database interface {
 	Close()
 	FreeHandler()
 	Insert(name string, insert *Entries) ([][]any, error)
+	InsertContext(ctx context.Context, name string, insert *Entries) ([][]any, error)
 	Update(name string, insert *Entries) ([][]any, int64, error)
+	UpdateContext(ctx context.Context, name string, insert *Entries) ([][]any, int64, error)
 	Delete(name string, remove *Entries) (int64, error)
+	DeleteContext(ctx context.Context, name string, remove *Entries) (int64, error)
 	Batch(batch string) error
+	BatchContext(ctx context.Context, batch string) error
 	BatchSelect(batch string) ([][]interface{}, error)
+	BatchSelectContext(ctx context.Context, batch string) ([][]interface{}, error)
 	BatchSelectFct(search *Query, f ResultFunction) error
+	BatchSelectFctContext(ctx context.Context, search *Query, f ResultFunction) error
 	Query(search *Query, f ResultFunction) (*Result, error)
+	QueryContext(ctx context.Context, search *Query, f ResultFunction) (*Result, error)
 	BeginTransaction() error
 	Commit() error
 	Rollback() error
 	Stream(search *Query, sf StreamFunction) error
+	StreamContext(ctx context.Context, search *Query, sf StreamFunction) error
+}
+
+// TableContexter database driver creating, deleting and reading the
+// columns of tables using context. Drivers without it check the context
+// only before and after the call.
+type TableContexter interface {
+	CreateTableContext(ctx context.Context, name string, columns any) error
+	DeleteTableContext(ctx context.Context, name string) error
+	GetTableColumnContext(ctx context.Context, tableName string) ([]string, error)
 }
 
 type Column struct {
@@ -133,6 +222,14 @@ type Column struct {
 	Length     uint16
 	Digits     uint8
 	SubColumns []*Column
+	// Nullable is only provided by DescribeTable. Default is the default
+	// expression of the database, Default, PrimaryKey, Unique and Index are
+	// used by CreateTable, too.
+	Nullable   bool
+	Default    string
+	PrimaryKey bool
+	Unique     bool
+	Index      bool
 }
 
 type ResultFunction func(search *Query, result *Result) error
@@ -168,6 +265,11 @@ func (cd *CommonDatabase) Used() {
 	cd.LastUsed = time.Now()
 }
 
+// DriverName name of the database driver like `postgres` or `sqlite`
+func (cd *CommonDatabase) DriverName() string {
+	return cd.Driver
+}
+
 func (id RegDbID) String() string {
 	return fmt.Sprintf("ID:%04d", id)
 }
@@ -183,19 +285,43 @@ func (id RegDbID) SetCredentials(user, password string) error {
 
 // Query query database records with search or SELECT
 func (id RegDbID) Query(query *Query, f ResultFunction) (*Result, error) {
-	driver, err := searchDataDriver(id)
+	return id.QueryContext(context.Background(), query, f)
+}
+
+// QueryContext query database records with search or SELECT, the query
+// is canceled if the context is done
+func (id RegDbID) QueryContext(ctx context.Context, query *Query, f ResultFunction) (*Result, error) {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	return driver.Query(query, f)
+	if query.Page != nil {
+		result, err := queryPage(ctx, driver, query, f)
+		return result, ContextError(ctx, err)
+	}
+	result, err := driver.QueryContext(ctx, query, f)
+	return result, ContextError(ctx, err)
 }
 
 // CreateTable create a new table
 func (id RegDbID) CreateTable(tableName string, columns any) error {
-	driver, err := searchDataDriver(id)
+	return id.CreateTableContext(context.Background(), tableName, columns)
+}
+
+// CreateTableContext create a new table using context
+func (id RegDbID) CreateTableContext(ctx context.Context, tableName string, columns any) error {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return err
 	}
+	return ContextError(ctx, createTable(ctx, driver, tableName, columns))
+}
+
+// createTable create the table using the context if the driver supports it
+func createTable(ctx context.Context, driver Database, tableName string, columns any) error {
+	if tc, ok := driver.(TableContexter); ok {
+		return tc.CreateTableContext(ctx, tableName, columns)
+	}
 	return driver.CreateTable(tableName, columns)
 }
 
@@ -210,16 +336,25 @@ func (id RegDbID) AdaptTable(tableName string, columns any) error {
 
 // CreateTableIfNotExists create a new table if not exists
 func (id RegDbID) CreateTableIfNotExists(tableName string, columns any) (CreateStatus, error) {
-	driver, err := searchDataDriver(id)
+	return id.CreateTableIfNotExistsContext(context.Background(), tableName, columns)
+}
+
+// CreateTableIfNotExistsContext create a new table if not exists using
+// context
+func (id RegDbID) CreateTableIfNotExistsContext(ctx context.Context, tableName string, columns any) (CreateStatus, error) {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return CreateDriver, err
 	}
 	dbTables, err := driver.Maps()
 	if err != nil {
 		if dbTables == nil {
-			return CreateConnError, err
+			return CreateConnError, ContextError(ctx, err)
 		}
-		return CreateError, err
+		return CreateError, ContextError(ctx, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return CreateError, context.Cause(ctx)
 	}
 	for _, d := range dbTables {
 		if d == tableName {
@@ -227,59 +362,90 @@ func (id RegDbID) CreateTableIfNotExists(tableName string, columns any) (CreateS
 		}
 	}
 
-	err = driver.CreateTable(tableName, columns)
+	err = createTable(ctx, driver, tableName, columns)
 	if err != nil {
 		if strings.Contains(err.Error(), "already exists") {
 			return CreateExists, nil
 		}
-		return CreateError, err
+		return CreateError, ContextError(ctx, err)
 	}
 	return CreateCreated, nil
 }
 
 // DeleteTable delete a table
 func (id RegDbID) DeleteTable(tableName string) error {
-	driver, err := searchDataDriver(id)
+	return id.DeleteTableContext(context.Background(), tableName)
+}
+
+// DeleteTableContext delete a table using context
+func (id RegDbID) DeleteTableContext(ctx context.Context, tableName string) error {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return err
 	}
-	return driver.DeleteTable(tableName)
+	if tc, ok := driver.(TableContexter); ok {
+		return ContextError(ctx, tc.DeleteTableContext(ctx, tableName))
+	}
+	return ContextError(ctx, driver.DeleteTable(tableName))
 }
 
 // Batch batch SQL with no return data in table
 func (id RegDbID) Batch(batch string) error {
-	driver, err := searchDataDriver(id)
+	return id.BatchContext(context.Background(), batch)
+}
+
+// BatchContext batch SQL with no return data in table using context
+func (id RegDbID) BatchContext(ctx context.Context, batch string) error {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return err
 	}
-	return driver.Batch(batch)
+	return ContextError(ctx, driver.BatchContext(ctx, batch))
 }
 
 // BatchSelect batch SQL query in table
 func (id RegDbID) BatchSelect(batch string) ([][]interface{}, error) {
-	driver, err := searchDataDriver(id)
+	return id.BatchSelectContext(context.Background(), batch)
+}
+
+// BatchSelectContext batch SQL query in table using context
+func (id RegDbID) BatchSelectContext(ctx context.Context, batch string) ([][]interface{}, error) {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	return driver.BatchSelect(batch)
+	result, err := driver.BatchSelectContext(ctx, batch)
+	return result, ContextError(ctx, err)
 }
 
 // BatchSelect batch SQL query in table calling function
 func (id RegDbID) BatchSelectFct(batch *Query, f ResultFunction) error {
-	driver, err := searchDataDriver(id)
+	return id.BatchSelectFctContext(context.Background(), batch, f)
+}
+
+// BatchSelectFctContext batch SQL query in table calling function using context
+func (id RegDbID) BatchSelectFctContext(ctx context.Context, batch *Query, f ResultFunction) error {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return err
 	}
-	return driver.BatchSelectFct(batch, f)
+	return ContextError(ctx, driver.BatchSelectFctContext(ctx, batch, f))
 }
 
 // Open open the database connection
 func (id RegDbID) Open() (any, error) {
-	driver, err := searchDataDriver(id)
+	return id.OpenContext(context.Background())
+}
+
+// OpenContext open the database connection, an already done context
+// returns its cause
+func (id RegDbID) OpenContext(ctx context.Context) (any, error) {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	return driver.Open()
+	dbOpen, err := driver.Open()
+	return dbOpen, ContextError(ctx, err)
 }
 
 // Close close the database connection
@@ -294,51 +460,93 @@ func (id RegDbID) Close() {
 
 // Ping create short test database connection
 func (id RegDbID) Ping() error {
-	driver, err := searchDataDriver(id)
+	return id.PingContext(context.Background())
+}
+
+// PingContext create short test database connection using context
+func (id RegDbID) PingContext(ctx context.Context) error {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return err
 	}
-	return driver.Ping()
+	return ContextError(ctx, driver.PingContext(ctx))
 }
 
 // Insert insert record into table
 func (id RegDbID) Insert(name string, insert *Entries) ([][]any, error) {
+	return id.InsertContext(context.Background(), name, insert)
+}
+
+// InsertContext insert record into table, the insert is canceled and
+// rolled back if the context is done
+func (id RegDbID) InsertContext(ctx context.Context, name string, insert *Entries) ([][]any, error) {
 	log.Log.Debugf("%s Searching id", id.String())
-	driver, err := searchDataDriver(id)
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	if id != driver.ID() {
 		log.Log.Fatal("ID mismatch")
 	}
-	return driver.Insert(name, insert)
+	insert.initVersion()
+	insert.insertTimestamps()
+	returning, err := driver.InsertContext(ctx, name, insert)
+	return returning, ContextError(ctx, err)
 }
 
 // Update update record in table
 func (id RegDbID) Update(name string, insert *Entries) ([][]any, int64, error) {
-	driver, err := searchDataDriver(id)
+	return id.UpdateContext(context.Background(), name, insert)
+}
+
+// UpdateContext update record in table, the update is canceled and
+// rolled back if the context is done
+func (id RegDbID) UpdateContext(ctx context.Context, name string, insert *Entries) ([][]any, int64, error) {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return nil, 0, err
 	}
-	return driver.Update(name, insert)
+	insert.updateTimestamps()
+	returning, rowsAffected, err := driver.UpdateContext(ctx, name, insert)
+	if err == nil {
+		insert.incrementVersion()
+	}
+	return returning, rowsAffected, ContextError(ctx, err)
 }
 
 // Delete Delete database records
 func (id RegDbID) Delete(name string, remove *Entries) (int64, error) {
-	driver, err := searchDataDriver(id)
+	return id.DeleteContext(context.Background(), name, remove)
+}
+
+// DeleteContext Delete database records, the delete is canceled and
+// rolled back if the context is done
+func (id RegDbID) DeleteContext(ctx context.Context, name string, remove *Entries) (int64, error) {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return 0, err
 	}
-	return driver.Delete(name, remove)
+	rowsAffected, err := deleteEntries(ctx, driver, name, remove)
+	return rowsAffected, ContextError(ctx, err)
 }
 
 // GetTableColumn get table columne names
 func (id RegDbID) GetTableColumn(tableName string) ([]string, error) {
-	driver, err := searchDataDriver(id)
+	return id.GetTableColumnContext(context.Background(), tableName)
+}
+
+// GetTableColumnContext get table column names using context
+func (id RegDbID) GetTableColumnContext(ctx context.Context, tableName string) ([]string, error) {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	return driver.GetTableColumn(tableName)
+	if tc, ok := driver.(TableContexter); ok {
+		columns, err := tc.GetTableColumnContext(ctx, tableName)
+		return columns, ContextError(ctx, err)
+	}
+	columns, err := driver.GetTableColumn(tableName)
+	return columns, ContextError(ctx, err)
 }
 
 func (result *Result) GenerateColumnByStruct(search *Query) (*ValueDefinition, error) {
@@ -389,14 +597,32 @@ func (id RegDbID) URL() string {
 	return driver.URL()
 }
 
+// DriverName name of the database driver of the registry id like
+// `postgres` or `sqlite`, empty if the driver does not provide it
+func (id RegDbID) DriverName() string {
+	driver, err := searchDataDriver(id)
+	if err != nil {
+		return ""
+	}
+	if named, ok := driver.(interface{ DriverName() string }); ok {
+		return named.DriverName()
+	}
+	return ""
+}
+
 // Stream streaming data from a field
 func (id RegDbID) Stream(search *Query, sf StreamFunction) error {
-	driver, err := searchDataDriver(id)
+	return id.StreamContext(context.Background(), search, sf)
+}
+
+// StreamContext streaming data from a field, streaming stops if the
+// context is done
+func (id RegDbID) StreamContext(ctx context.Context, search *Query, sf StreamFunction) error {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return err
 	}
-	return driver.Stream(search, sf)
-
+	return ContextError(ctx, driver.StreamContext(ctx, search, sf))
 }
 
 // RegisterDbClient register database
@@ -433,12 +659,18 @@ func (id RegDbID) FreeHandler() error {
 
 // Tables tables list of an database
 func (id RegDbID) Tables() ([]string, error) {
-	driver, err := searchDataDriver(id)
+	return id.TablesContext(context.Background())
+}
+
+// TablesContext tables list of an database, an already done context
+// returns its cause
+func (id RegDbID) TablesContext(ctx context.Context) ([]string, error) {
+	driver, err := searchDataDriverContext(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	return driver.Maps()
-
+	tables, err := driver.Maps()
+	return tables, ContextError(ctx, err)
 }
 
 func DBHelper() string {
@@ -132,11 +132,21 @@ func CreateHeader(ct []*sql.ColumnType) []*Column {
 	return header
 }
 
+// columnTypeName database type name of the column without length part,
+// SQLite returns the declared type like `VARCHAR(255)`
+func columnTypeName(ct *sql.ColumnType) string {
+	typeName := ct.DatabaseTypeName()
+	if index := strings.IndexByte(typeName, '('); index > 0 {
+		typeName = typeName[:index]
+	}
+	return typeName
+}
+
 func CreateTypeData(ct []*sql.ColumnType) []interface{} {
 	scanData := make([]interface{}, 0)
 	for _, t := range ct {
-		switch t.DatabaseTypeName() {
-		case "VARCHAR", "TEXT", "UNICODE":
+		switch columnTypeName(t) {
+		case "VARCHAR", "TEXT", "UNICODE", "CHAR", "":
 			//if nok, _ := t.Nullable(); nok {
 			v := sql.NullString{}
 			scanData = append(scanData, &v)
@@ -144,7 +154,7 @@ func CreateTypeData(ct []*sql.ColumnType) []interface{} {
 			//	s := ""
 			//	scanData = append(scanData, &s)
 			//}
-		case "NUMBER", "INT4", "INTEGER":
+		case "NUMBER", "INT4", "INTEGER", "INT":
 			if nok, _ := t.Nullable(); nok {
 				scanData = append(scanData, &sql.NullInt32{})
 			} else {
@@ -200,7 +210,7 @@ func CreateTypeData(ct []*sql.ColumnType) []interface{} {
 				v := uint64(0)
 				scanData = append(scanData, &v)
 			}
-		case "BOOLEAN":
+		case "BOOLEAN", "BOOL":
 			if nok, _ := t.Nullable(); nok {
 				scanData = append(scanData, &sql.NullBool{})
 			} else {
@@ -0,0 +1,74 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/log"
+)
+
+// TableInfo definition of a table read out of the database catalog
+type TableInfo struct {
+	Name    string
+	Columns []*Column
+}
+
+// TableDescriber database driver reading the table definition
+type TableDescriber interface {
+	DescribeTableContext(ctx context.Context, name string) (*TableInfo, error)
+}
+
+// Column column of the table, the name is compared case-insensitive
+func (info *TableInfo) Column(name string) *Column {
+	for _, c := range info.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// PrimaryKey names of the primary key columns
+func (info *TableInfo) PrimaryKey() []string {
+	keys := make([]string, 0)
+	for _, c := range info.Columns {
+		if c.PrimaryKey {
+			keys = append(keys, c.Name)
+		}
+	}
+	return keys
+}
+
+// DescribeTable read the column definitions of the table including
+// nullability, default, primary key, unique and index membership
+func (id RegDbID) DescribeTable(tableName string) (*TableInfo, error) {
+	return id.DescribeTableContext(context.Background(), tableName)
+}
+
+// DescribeTableContext read the column definitions of the table using
+// context
+func (id RegDbID) DescribeTableContext(ctx context.Context, tableName string) (*TableInfo, error) {
+	driver, err := searchDataDriverContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	describer, ok := driver.(TableDescriber)
+	if !ok {
+		log.Log.Debugf("%s: describe table not supported", id)
+		return nil, errorrepo.NewError("DB065535")
+	}
+	info, err := describer.DescribeTableContext(ctx, tableName)
+	return info, ContextError(ctx, err)
+}
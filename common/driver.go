@@ -0,0 +1,128 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tknie/log"
+)
+
+// DriverFactory create a new database driver instance for the given
+// registry id, reference and password
+type DriverFactory func(RegDbID, *Reference, string) (Database, error)
+
+// URLParser parse the database URL of a scheme into the reference and
+// the password
+type URLParser func(url string) (*Reference, string, error)
+
+// Driver database driver registered for an URL scheme
+type Driver struct {
+	// Type reference type of the driver. The built-in drivers use their
+	// constant, NoType assigns a new type.
+	Type ReferenceType
+	// Name name of the reference type, default is the scheme
+	Name string
+	// Parse URL parser of the scheme, default is ParseUrl
+	Parse   URLParser
+	Factory DriverFactory
+}
+
+var driverLock sync.RWMutex
+
+// driverSchemes map URL scheme names to the registered drivers
+var driverSchemes = make(map[string]*Driver)
+
+// driverTypes registered drivers by reference type
+var driverTypes = make(map[ReferenceType]*Driver)
+
+// RegisterDriver register a database driver factory for the given URL scheme.
+// The scheme gets a new reference type and is parsed with ParseUrl.
+func RegisterDriver(scheme string, factory func(RegDbID, *Reference, string) (Database, error)) {
+	RegisterDriverDefinition(scheme, &Driver{Factory: factory})
+}
+
+// RegisterDriverDefinition register the database driver for the given URL
+// scheme and return its reference type. The built-in drivers register
+// themselves in their package init function, drivers excluded with the
+// corresponding `flynn_no*` build tag are not registered and their URL
+// scheme is unknown.
+func RegisterDriverDefinition(scheme string, driver *Driver) ReferenceType {
+	if driver == nil || driver.Factory == nil {
+		panic("flynn: register driver factory is nil for " + scheme)
+	}
+	name := strings.ToLower(scheme)
+	driverLock.Lock()
+	defer driverLock.Unlock()
+	if _, dup := driverSchemes[name]; dup {
+		panic("flynn: register driver called twice for " + scheme)
+	}
+	d := *driver
+	if d.Type == NoType {
+		d.Type = nextReferenceType()
+	}
+	if d.Name == "" {
+		d.Name = scheme
+	}
+	if d.Parse == nil {
+		d.Parse = ParseUrl
+	}
+	log.Log.Debugf("Register driver scheme %s as type %d", name, d.Type)
+	driverSchemes[name] = &d
+	driverTypes[d.Type] = &d
+	return d.Type
+}
+
+// nextReferenceType new reference type after the built-in and all
+// registered types
+func nextReferenceType() ReferenceType {
+	next := lastBuiltinType + 1
+	for rt := range driverTypes {
+		if rt >= next {
+			next = rt + 1
+		}
+	}
+	return next
+}
+
+// lookupScheme registered driver of the URL scheme
+func lookupScheme(scheme string) (*Driver, bool) {
+	driverLock.RLock()
+	defer driverLock.RUnlock()
+	driver, ok := driverSchemes[strings.ToLower(scheme)]
+	return driver, ok
+}
+
+// LookupDriver search the registered driver factory for the reference type
+func LookupDriver(rt ReferenceType) (DriverFactory, bool) {
+	driverLock.RLock()
+	defer driverLock.RUnlock()
+	driver, ok := driverTypes[rt]
+	if !ok {
+		return nil, false
+	}
+	return driver.Factory, true
+}
+
+// Drivers list of all URL schemes with a registered driver
+func Drivers() []string {
+	driverLock.RLock()
+	defer driverLock.RUnlock()
+	schemes := make([]string, 0, len(driverSchemes))
+	for n := range driverSchemes {
+		schemes = append(schemes, n)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
@@ -53,6 +53,19 @@ type typeInterface struct {
 	ValueRefTo []any
 	ScanValues []any
 	TagInfo    []TagInfo
+	// QueryFields query fields including the columns of joined tables
+	// qualified with the table alias
+	QueryFields []string
+	joins       []joinReference
+}
+
+// joinReference pointer field of a joined struct with the range of its scan
+// values, the pointer is reset if all values are NULL
+type joinReference struct {
+	field reflect.Value
+	value reflect.Value
+	start int
+	end   int
 }
 
 type SubInterface interface {
@@ -86,20 +99,29 @@ func CreateInterface(i interface{}, createFields []string) *typeInterface {
 		}
 	}
 	log.Log.Debugf("FieldSet defined: %#v", dynamic.FieldSet)
-	dynamic.generateFieldNames(ri)
+	dynamic.generateFieldNames(ri, "")
 	log.Log.Debugf("Final created field list generated %#v", dynamic.RowFields)
 	return dynamic
 }
 
 func (dynamic *typeInterface) CreateQueryFields() string {
+	return dynamic.createQueryFields("")
+}
+
+// createQueryFields query field list, if the alias is given the fields of
+// the main table are qualified with the alias
+func (dynamic *typeInterface) createQueryFields(alias string) string {
 	if dynamic.SetType == EmptySet {
 		return ""
 	}
 	var buffer bytes.Buffer
-	for _, fieldName := range dynamic.RowFields {
+	for _, fieldName := range dynamic.QueryFields {
 		if buffer.Len() > 0 {
 			buffer.WriteRune(',')
 		}
+		if alias != "" && !strings.Contains(fieldName, ".") {
+			buffer.WriteString(alias + ".")
+		}
 		buffer.WriteString(fieldName)
 	}
 	return buffer.String()
@@ -128,7 +150,7 @@ func (dynamic *typeInterface) CreateQueryValues() (*ValueDefinition, error) {
 		log.Log.Debugf("Pointer type: %T", elemValue.Interface())
 	}
 	log.Log.Debugf("Final type: %T", elemValue.Interface())
-	err := dynamic.generateField(elemValue, true)
+	err := dynamic.generateField(elemValue, true, "")
 	if err != nil {
 		return nil, err
 	}
@@ -150,7 +172,7 @@ func (dynamic *typeInterface) CreateValues(value interface{}) ([]any, error) {
 	if valueOf.Type().Kind() == reflect.Pointer {
 		valueOf = valueOf.Elem()
 	}
-	err := dynamic.generateField(valueOf, false)
+	err := dynamic.generateField(valueOf, false, "")
 	if err != nil {
 		return nil, err
 	}
@@ -160,8 +182,9 @@ func (dynamic *typeInterface) CreateValues(value interface{}) ([]any, error) {
 
 // generateField generate field values for dynamic query.
 // 'scan' is used to consider case for read (field creation out of database) or
-// write (no creation, data is used by application)
-func (dynamic *typeInterface) generateField(elemValue reflect.Value, readScan bool) error {
+// write (no creation, data is used by application). 'join' is the alias of
+// the joined table the struct is read from.
+func (dynamic *typeInterface) generateField(elemValue reflect.Value, readScan bool, join string) error {
 	log.Log.Debugf("Generate field of Struct: %T %s -> scan=%v",
 		elemValue.Interface(), elemValue.Type().Name(), readScan)
 	defer log.Log.Debugf("generated field of struct %s", elemValue.Type().Name())
@@ -185,7 +208,7 @@ func (dynamic *typeInterface) generateField(elemValue reflect.Value, readScan bo
 			continue
 		case SubTag:
 			log.Log.Debugf("is nil = %v scan = %v", cv.IsNil(), readScan)
-			checkField := dynamic.checkFieldSet(fieldType.Name)
+			checkField := dynamic.checkFieldSet(joinField(join, fieldType.Name))
 			if checkField {
 				di := cv.Interface()
 				log.Log.Debugf("Sub interface = %v/%T", di, di)
@@ -211,7 +234,7 @@ func (dynamic *typeInterface) generateField(elemValue reflect.Value, readScan bo
 				continue
 			}
 		case YAMLTag, XMLTag, JSONTag:
-			checkField := dynamic.checkFieldSet(fieldType.Name)
+			checkField := dynamic.checkFieldSet(joinField(join, fieldType.Name))
 			if checkField {
 				if cv.Kind() == reflect.Pointer {
 					if !readScan {
@@ -254,7 +277,38 @@ func (dynamic *typeInterface) generateField(elemValue reflect.Value, readScan bo
 				}
 			}
 			continue
-		case NormalTag, KeyTag, IndexTag:
+		case JoinTag:
+			if !readScan || join != "" {
+				continue
+			}
+			alias, _ := TagJoinAlias(d)
+			ref := joinReference{field: cv, start: len(dynamic.ScanValues)}
+			if cv.Kind() == reflect.Pointer {
+				ref.value = reflect.New(cv.Type().Elem())
+				cv.Set(ref.value)
+				cv = ref.value.Elem()
+			}
+			if cv.Kind() != reflect.Struct {
+				continue
+			}
+			err := dynamic.generateField(cv, readScan, alias)
+			if err != nil {
+				return err
+			}
+			if ref.value.IsValid() {
+				ref.end = len(dynamic.ScanValues)
+				dynamic.joins = append(dynamic.joins, ref)
+			}
+			continue
+		case NormalTag, KeyTag, IndexTag, VersionTag, CreatedTag, UpdatedTag, DeletedTag:
+			if tagInfo == DeletedTag && !readScan && isZeroTime(cv) &&
+				dynamic.checkFieldSet(joinField(join, fieldType.Name)) {
+				// records not deleted have no deletion time
+				dynamic.ValueRefTo = append(dynamic.ValueRefTo, nil)
+				dynamic.ScanValues = append(dynamic.ScanValues, &sql.NullTime{})
+				dynamic.TagInfo = append(dynamic.TagInfo, NormalTag)
+				continue
+			}
 			if cv.Kind() == reflect.Pointer {
 				// x := reflect.New(cv.Type().Elem())
 				/*			x := reflect.Indirect(reflect.New(cv.Type().Elem()))
@@ -278,7 +332,7 @@ func (dynamic *typeInterface) generateField(elemValue reflect.Value, readScan bo
 				log.Log.Debugf("Work on struct %s", fieldType.Name)
 				switch cv.Interface().(type) {
 				case time.Time:
-					checkField := dynamic.checkFieldSet(fieldType.Name)
+					checkField := dynamic.checkFieldSet(joinField(join, fieldType.Name))
 					if checkField {
 						ptr := cv.Addr()
 						t := reflect.TypeOf(cv)
@@ -336,7 +390,7 @@ func (dynamic *typeInterface) generateField(elemValue reflect.Value, readScan bo
 						dynamic.TagInfo = append(dynamic.TagInfo, JSONTag)
 						continue
 					default:
-						dynamic.generateField(cv, readScan)
+						dynamic.generateField(cv, readScan, join)
 						//							dynamic.ValueRefTo = append(dynamic.ValueRefTo, "")
 						//							dynamic.TagInfo = append(dynamic.TagInfo, NormalTag)
 						continue
@@ -344,7 +398,7 @@ func (dynamic *typeInterface) generateField(elemValue reflect.Value, readScan bo
 				}
 			} else {
 				log.Log.Debugf("Work on field %s -> scan=%v", fieldName, readScan)
-				checkField := dynamic.checkFieldSet(fieldName)
+				checkField := dynamic.checkFieldSet(joinField(join, fieldName))
 				if checkField {
 					if readScan {
 						var ptr reflect.Value
@@ -412,6 +466,36 @@ func (dynamic *typeInterface) generateField(elemValue reflect.Value, readScan bo
 	return nil
 }
 
+// isZeroTime check if the time or time pointer value is not set
+func isZeroTime(cv reflect.Value) bool {
+	if cv.Kind() == reflect.Pointer {
+		if cv.IsNil() {
+			return true
+		}
+		cv = cv.Elem()
+	}
+	t, ok := cv.Interface().(time.Time)
+	return ok && t.IsZero()
+}
+
+// joinField field name qualified with the alias of the joined table
+func joinField(join, fieldName string) string {
+	if join == "" {
+		return fieldName
+	}
+	return join + "." + fieldName
+}
+
+// addField add field to the row and query fields, fields of joined tables
+// are query fields only
+func (dynamic *typeInterface) addField(fieldName, join string) {
+	if join == "" {
+		dynamic.RowFields = append(dynamic.RowFields, fieldName)
+	}
+	dynamic.QueryFields = append(dynamic.QueryFields, joinField(join, fieldName))
+	log.Log.Debugf("RowFields: Add field name %s", joinField(join, fieldName))
+}
+
 func (dynamic *typeInterface) checkFieldSet(fieldName string) bool {
 	ok := true
 	log.Log.Debugf("Check %s in %#v", strings.ToLower(fieldName), dynamic.FieldSet)
@@ -424,8 +508,9 @@ func (dynamic *typeInterface) checkFieldSet(fieldName string) bool {
 }
 
 // generateFieldNames examine all structure-tags in the given structure and build up
-// field names map pointing to corresponding path with names of structures
-func (dynamic *typeInterface) generateFieldNames(ri reflect.Type) {
+// field names map pointing to corresponding path with names of structures.
+// The fields of a joined struct are qualified with the join alias.
+func (dynamic *typeInterface) generateFieldNames(ri reflect.Type, join string) {
 	if log.IsDebugLevel() {
 		log.Log.Debugf("Generate field names...")
 	}
@@ -448,25 +533,53 @@ func (dynamic *typeInterface) generateFieldNames(ri reflect.Type) {
 		log.Log.Debugf("Field tag option %s", tagInfo)
 		switch tagInfo {
 		case KeyTag:
-			dynamic.RowNames["#key"] = []string{fieldName}
+			if join == "" {
+				dynamic.RowNames["#key"] = append(dynamic.RowNames["#key"], fieldName)
+			}
+		case VersionTag:
+			if join == "" {
+				dynamic.RowNames["#version"] = []string{fieldName}
+			}
+		case CreatedTag:
+			if join == "" {
+				dynamic.RowNames["#created"] = []string{fieldName}
+			}
+		case UpdatedTag:
+			if join == "" {
+				dynamic.RowNames["#updated"] = []string{fieldName}
+			}
+		case DeletedTag:
+			if join == "" {
+				dynamic.RowNames["#deleted"] = []string{fieldName}
+			}
 		case IndexTag:
-			dynamic.RowNames["#index"] = []string{fieldName}
+			if join == "" {
+				dynamic.RowNames["#index"] = []string{fieldName}
+			}
+			continue
+		case JoinTag:
+			st := ct.Type
+			if st.Kind() == reflect.Pointer {
+				st = st.Elem()
+			}
+			if alias, _ := TagJoinAlias(tag); join == "" && st.Kind() == reflect.Struct {
+				dynamic.generateFieldNames(st, alias)
+			}
 			continue
 		case IgnoreTag:
 			log.Log.Debugf("Field skip because ignore tag")
 			continue
 		case SubTag:
 			log.Log.Debugf("Found sub")
-			ok := dynamic.checkFieldSet(fieldName)
+			ok := dynamic.checkFieldSet(joinField(join, fieldName))
 			if ok {
-				dynamic.RowFields = append(dynamic.RowFields, fieldName)
-				log.Log.Debugf("RowFields: Add field name %s", fieldName)
+				dynamic.addField(fieldName, join)
 			}
 			continue
 		case YAMLTag, XMLTag, JSONTag:
-			ok := dynamic.checkFieldSet(fieldName)
+			ok := dynamic.checkFieldSet(joinField(join, fieldName))
 			if ok {
-				dynamic.RowFields = append(dynamic.RowFields, fieldName)
+				dynamic.addField(fieldName, join)
 			}
 			continue
 		default:
@@ -483,22 +596,20 @@ func (dynamic *typeInterface) generateFieldNames(ri reflect.Type) {
 			log.Log.Debugf("Struct-Kind of %s", st.Name())
 			//continue generate field names
 			if st.Name() != "Time" {
-				dynamic.generateFieldNames(st)
+				dynamic.generateFieldNames(st, join)
 			} else {
-				ok := dynamic.checkFieldSet(fieldName)
+				ok := dynamic.checkFieldSet(joinField(join, fieldName))
 				if ok {
-					dynamic.RowFields = append(dynamic.RowFields, fieldName)
-					log.Log.Debugf("RowFields: Add field name %s", fieldName)
+					dynamic.addField(fieldName, join)
 				}
 			}
 		} else {
 			log.Log.Debugf("Kind of %s: %s", fieldName, ct.Type.Kind())
 			// copy of subfields
 			// copy(subFields, fields)
-			ok := dynamic.checkFieldSet(fieldName)
+			ok := dynamic.checkFieldSet(joinField(join, fieldName))
 			if ok {
-				dynamic.RowFields = append(dynamic.RowFields, fieldName)
-				log.Log.Debugf("RowFields: Add field name %s", fieldName)
+				dynamic.addField(fieldName, join)
 			}
 		}
 		// Handle special case for pointer and slices
@@ -510,7 +621,7 @@ func (dynamic *typeInterface) generateFieldNames(ri reflect.Type) {
 			if sliceT.Kind() == reflect.Ptr {
 				sliceT = sliceT.Elem()
 			}
-			dynamic.generateFieldNames(sliceT)
+			dynamic.generateFieldNames(sliceT, join)
 		}
 	}
 	log.Log.Debugf("Field list generated %#v", dynamic.RowFields)
@@ -556,9 +667,25 @@ func (vd *ValueDefinition) ShiftValues() error {
 		case IgnoreTag: // is ignored
 		}
 	}
+	for _, ref := range vd.dynamic.joins {
+		if slices.ContainsFunc(vd.ScanValues[ref.start:ref.end], scanValid) {
+			ref.field.Set(ref.value)
+		} else {
+			ref.field.Set(reflect.Zero(ref.field.Type()))
+		}
+	}
 	return nil
 }
 
+// scanValid check if the scanned value is not NULL
+func scanValid(v any) bool {
+	if n, ok := v.(sqlInterface); ok {
+		vv, err := n.Value()
+		return err == nil && vv != nil
+	}
+	return true
+}
+
 func (vd *ValueDefinition) ShiftNormalValues(d int, v any) error {
 	if _, ok := v.(sqlInterface); ok {
 		vv, err := v.(sqlInterface).Value()
@@ -600,7 +727,7 @@ func (vd *ValueDefinition) ShiftNormalValues(d int, v any) error {
 			case *time.Time:
 				*vt = vv.(time.Time)
 			default:
-				log.Log.Fatalf("Unknown type for shifting %s at index %d value %T <- %T", vd.dynamic.RowFields[d], d, vd.Values[d], vv)
+				log.Log.Fatalf("Unknown type for shifting %s at index %d value %T <- %T", vd.dynamic.QueryFields[d], d, vd.Values[d], vv)
 			}
 		} else {
 			log.Log.Debugf("SQL interface value nil %T reseting struct values", vd.Values[d])
@@ -37,3 +37,23 @@ func init() {
 		}
 	}
 }
+
+// ErrNotFound can be used to check for a NotFoundError using errors.Is
+var ErrNotFound = &NotFoundError{}
+
+// NotFoundError no record found for a query expecting one record
+type NotFoundError struct {
+	TableName string
+	Search    string
+}
+
+// Error error message of the not found error
+func (e *NotFoundError) Error() string {
+	return errorrepo.NewError("DB000045", e.TableName, e.Search).Error()
+}
+
+// Is all not found errors are equal in errors.Is
+func (e *NotFoundError) Is(target error) bool {
+	_, ok := target.(*NotFoundError)
+	return ok
+}
@@ -0,0 +1,72 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"context"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/log"
+)
+
+// Index index on the columns of a table. Indexes without name get the
+// name `<table>_<columns>_idx`.
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// IndexCreator database driver creating and dropping indexes
+type IndexCreator interface {
+	CreateIndexContext(ctx context.Context, tableName string, index *Index) error
+	DropIndexContext(ctx context.Context, tableName, indexName string) error
+}
+
+// CreateIndex create the index on the columns of the table
+func (id RegDbID) CreateIndex(tableName string, index *Index) error {
+	return id.CreateIndexContext(context.Background(), tableName, index)
+}
+
+// CreateIndexContext create the index on the columns of the table using
+// context
+func (id RegDbID) CreateIndexContext(ctx context.Context, tableName string, index *Index) error {
+	driver, err := searchDataDriverContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	creator, ok := driver.(IndexCreator)
+	if !ok {
+		log.Log.Debugf("%s: create index not supported", id)
+		return errorrepo.NewError("DB065535")
+	}
+	return ContextError(ctx, creator.CreateIndexContext(ctx, tableName, index))
+}
+
+// DropIndex drop the index of the table
+func (id RegDbID) DropIndex(tableName, indexName string) error {
+	return id.DropIndexContext(context.Background(), tableName, indexName)
+}
+
+// DropIndexContext drop the index of the table using context
+func (id RegDbID) DropIndexContext(ctx context.Context, tableName, indexName string) error {
+	driver, err := searchDataDriverContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	creator, ok := driver.(IndexCreator)
+	if !ok {
+		log.Log.Debugf("%s: drop index not supported", id)
+		return errorrepo.NewError("DB065535")
+	}
+	return ContextError(ctx, creator.DropIndexContext(ctx, tableName, indexName))
+}
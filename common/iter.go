@@ -0,0 +1,111 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"context"
+	"iter"
+	"reflect"
+	"slices"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/log"
+)
+
+// errStopIteration returned by the result function if the loop using the
+// iterator is left
+var errStopIteration = errorrepo.NewError("DB000043")
+
+// Rows query database records returning an iterator. Each iteration gets
+// a new result, leaving the loop closes the query.
+//
+//	for result, err := range id.Rows(query) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (id RegDbID) Rows(query *Query) iter.Seq2[*Result, error] {
+	return id.RowsContext(context.Background(), query)
+}
+
+// RowsContext query database records returning an iterator, the query is
+// canceled if the context is done
+func (id RegDbID) RowsContext(ctx context.Context, query *Query) iter.Seq2[*Result, error] {
+	return func(yield func(*Result, error) bool) {
+		stopped := false
+		_, err := id.QueryContext(ctx, query, func(search *Query, result *Result) error {
+			if !yield(result.Clone(), nil) {
+				stopped = true
+				return errStopIteration
+			}
+			return nil
+		})
+		if err != nil && !stopped {
+			log.Log.Debugf("%s: iterator query error: %v", id, err)
+			yield(nil, err)
+		}
+	}
+}
+
+// Clone copy of the result. The row values and the data structure are
+// copied, so that the copy is not changed reading the next record.
+func (result *Result) Clone() *Result {
+	if result == nil {
+		return nil
+	}
+	newResult := *result
+	if result.Rows != nil {
+		newResult.Rows = slices.Clone(result.Rows)
+		for i, r := range newResult.Rows {
+			if b, ok := r.([]byte); ok {
+				newResult.Rows[i] = slices.Clone(b)
+			}
+		}
+	}
+	if result.Data != nil {
+		newResult.Data = copyValue(reflect.ValueOf(result.Data)).Interface()
+	}
+	return &newResult
+}
+
+// copyValue copy value including all referenced structures and slices
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		nv := reflect.New(v.Type().Elem())
+		nv.Elem().Set(copyValue(v.Elem()))
+		return nv
+	case reflect.Struct:
+		nv := reflect.New(v.Type()).Elem()
+		nv.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if nv.Field(i).CanSet() {
+				nv.Field(i).Set(copyValue(v.Field(i)))
+			}
+		}
+		return nv
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		nv := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			nv.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return nv
+	}
+	return v
+}
@@ -0,0 +1,56 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cloneSub struct {
+	Value string
+}
+
+type cloneData struct {
+	Name    string
+	Created time.Time
+	Data    []byte
+	Sub     *cloneSub
+}
+
+func TestResultClone(t *testing.T) {
+	InitLog(t)
+
+	now := time.Now()
+	data := &cloneData{Name: "abc", Created: now, Data: []byte{1, 2},
+		Sub: &cloneSub{Value: "sub"}}
+	result := &Result{Counter: 1, Fields: []string{"Name"},
+		Rows: []any{"abc", []byte{3, 4}}, Data: data}
+	c := result.Clone()
+	assert.Equal(t, result, c)
+
+	data.Name = "changed"
+	data.Data[0] = 9
+	data.Sub.Value = "changed"
+	result.Rows[0] = "changed"
+	result.Rows[1].([]byte)[0] = 9
+	cd := c.Data.(*cloneData)
+	assert.Equal(t, "abc", cd.Name)
+	assert.Equal(t, []byte{1, 2}, cd.Data)
+	assert.Equal(t, "sub", cd.Sub.Value)
+	assert.True(t, now.Equal(cd.Created))
+	assert.Equal(t, []any{"abc", []byte{3, 4}}, c.Rows)
+
+	assert.Nil(t, (*Result)(nil).Clone())
+}
@@ -0,0 +1,384 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/log"
+)
+
+// Page pagination of a query. The first page is read without token, the
+// next pages with the NextToken of the result of the previous page. With
+// Keyset the next page continues after the Sort key of the last row,
+// otherwise after the offset of the last row. Adabas queries without sort
+// fields continue after the ISN of the last record, keyset pages of the
+// SQL drivers need sort fields.
+type Page struct {
+	Size   uint64
+	Token  string
+	Keyset bool
+	Total  bool
+}
+
+// pageToken content of the continuation token
+type pageToken struct {
+	Offset uint64
+	Keys   []any
+}
+
+// tokenData encoded content of the continuation token
+type tokenData struct {
+	Offset uint64     `json:"o,omitempty"`
+	Keys   []tokenKey `json:"k,omitempty"`
+}
+
+// tokenKey key value of the token, times and byte slices are marked with
+// their type to be decoded as time.Time and []byte again
+type tokenKey struct {
+	Type  string `json:"t,omitempty"`
+	Value any    `json:"v"`
+}
+
+const (
+	tokenTime  = "time"
+	tokenBytes = "bytes"
+)
+
+// IsnOrderer database driver reading the records of a query without sort
+// fields in ISN order. Keyset pages of other drivers need sort fields.
+type IsnOrderer interface {
+	IsnOrder() bool
+}
+
+// Counter database driver counting the rows of a query itself, other
+// drivers count with a SELECT COUNT(*) statement
+type Counter interface {
+	CountContext(ctx context.Context, search *Query) (int64, error)
+}
+
+// AndPredicate combine two predicates, nil predicates are left out
+func AndPredicate(p1, p2 Predicate) Predicate {
+	switch {
+	case p1 == nil:
+		return p2
+	case p2 == nil:
+		return p1
+	}
+	return And(p1, p2)
+}
+
+// KeysetPredicate predicate selecting the rows after the After key values of
+// the Sort fields. The sort fields should be unique and not NULL.
+func (q *Query) KeysetPredicate() (Predicate, error) {
+	if len(q.After) == 0 {
+		return nil, nil
+	}
+	if len(q.Sort) == 0 || len(q.Sort) != len(q.After) {
+		return nil, errorrepo.NewError("DB000054", len(q.Sort), len(q.After))
+	}
+	alternatives := make([]Predicate, 0, len(q.Sort))
+	for i, s := range q.Sort {
+		terms := make([]Predicate, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, Eq(q.Sort[j].Field, q.After[j]))
+		}
+		if s.Descending {
+			terms = append(terms, Lt(s.Field, q.After[i]))
+		} else {
+			terms = append(terms, Gt(s.Field, q.After[i]))
+		}
+		if len(terms) == 1 {
+			alternatives = append(alternatives, terms[0])
+		} else {
+			alternatives = append(alternatives, And(terms...))
+		}
+	}
+	if len(alternatives) == 1 {
+		return alternatives[0], nil
+	}
+	return Or(alternatives...), nil
+}
+
+// CountQuery copy of the query counting all rows, order, limit, offset,
+// keyset and page are removed
+func (q *Query) CountQuery() *Query {
+	cq := *q
+	cq.Order, cq.Sort, cq.After, cq.Page = nil, nil, nil, nil
+	cq.Limit, cq.LimitRows, cq.Offset = "", 0, 0
+	return &cq
+}
+
+// CountStatement SELECT COUNT(*) statement counting all rows of the query
+// with `$n` placeholders and the referenced parameters
+func (q *Query) CountStatement() (string, []any, error) {
+	cq := q.CountQuery()
+	if cq.TableName == "" {
+		return "", nil, errorrepo.NewError("DB000016")
+	}
+	if cq.Descriptor || len(cq.Group) > 0 {
+		selectCmd, parameters, err := cq.selectStatement()
+		if err != nil {
+			return "", nil, err
+		}
+		return "SELECT COUNT(*) FROM (" + selectCmd + ") cnt", parameters, nil
+	}
+	from, err := cq.fromClause(cq.tableAlias())
+	if err != nil {
+		return "", nil, err
+	}
+	search, parameters, err := cq.whereCondition()
+	if err != nil {
+		return "", nil, err
+	}
+	countCmd := "SELECT COUNT(*)" + from
+	if search != "" {
+		countCmd += " WHERE " + search
+	}
+	return countCmd, parameters, nil
+}
+
+// queryPage query one page of the query. One more row than the page size
+// is read to check if a next page exists.
+func queryPage(ctx context.Context, driver Database, query *Query, f ResultFunction) (*Result, error) {
+	page := query.Page
+	token, err := decodePageToken(page.Token)
+	if err != nil {
+		return nil, err
+	}
+	pq := *query
+	pq.Page = nil
+	pq.Limit = ""
+	pq.LimitRows = 0
+	if page.Size > 0 {
+		pq.LimitRows = page.Size + 1
+	}
+	if page.Keyset {
+		pq.After = token.Keys
+		if len(pq.Sort) == 0 {
+			isn, ok := driver.(IsnOrderer)
+			if !ok || !isn.IsnOrder() {
+				return nil, errorrepo.NewError("DB000078", query.TableName)
+			}
+			if len(pq.After) == 0 {
+				// read in ISN order starting after ISN 0
+				pq.After = []any{uint64(0)}
+			}
+		}
+	} else if page.Token != "" {
+		pq.Offset = token.Offset
+	}
+	count := uint64(0)
+	more := false
+	var keys []any
+	result, err := driver.QueryContext(ctx, &pq, func(search *Query, result *Result) error {
+		if page.Size > 0 && count == page.Size {
+			more = true
+			return nil
+		}
+		count++
+		if page.Keyset {
+			var err error
+			keys, err = result.keyValues(search)
+			if err != nil {
+				return err
+			}
+		}
+		return f(search, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = &Result{}
+	}
+	if more {
+		next := &pageToken{Offset: pq.Offset + count}
+		if page.Keyset {
+			next = &pageToken{Keys: keys}
+		}
+		result.NextToken, err = next.encode()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if page.Total {
+		result.Total, err = countRows(ctx, driver, &pq)
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Log.Debugf("Page with %d rows, next token %s", count, result.NextToken)
+	return result, nil
+}
+
+// countRows count all rows of the query
+func countRows(ctx context.Context, driver Database, query *Query) (int64, error) {
+	if counter, ok := driver.(Counter); ok {
+		return counter.CountContext(ctx, query)
+	}
+	countCmd, parameters, err := query.CountStatement()
+	if err != nil {
+		return 0, err
+	}
+	total := int64(0)
+	err = driver.BatchSelectFctContext(ctx, &Query{Driver: query.Driver, Search: countCmd,
+		Parameters: parameters}, func(search *Query, result *Result) error {
+		if len(result.Rows) == 0 {
+			return errorrepo.NewError("DB000055")
+		}
+		v := reflect.Indirect(reflect.ValueOf(result.Rows[0]))
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			total = v.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			total = int64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			total = int64(v.Float())
+		case reflect.String:
+			total, err = strconv.ParseInt(v.String(), 10, 64)
+		case reflect.Slice:
+			total, err = strconv.ParseInt(string(v.Bytes()), 10, 64)
+		default:
+			return errorrepo.NewError("DB000055")
+		}
+		return err
+	})
+	return total, err
+}
+
+// keyValues values of the sort fields in the result row. Adabas rows
+// without sort fields provide the ISN as key.
+func (result *Result) keyValues(search *Query) ([]any, error) {
+	if len(search.Sort) == 0 {
+		if result.Isn == 0 {
+			return nil, errorrepo.NewError("DB000054", 0, 1)
+		}
+		return []any{result.Isn}, nil
+	}
+	keys := make([]any, 0, len(search.Sort))
+	for _, s := range search.Sort {
+		v, ok := result.fieldValue(s.Field)
+		if !ok {
+			return nil, errorrepo.NewError("DB000039", s.Field, search.TableName)
+		}
+		keys = append(keys, v)
+	}
+	return keys, nil
+}
+
+// fieldValue value of the field in the result row or data structure, the
+// table alias of the field is not checked
+func (result *Result) fieldValue(field string) (any, bool) {
+	if i := strings.LastIndexByte(field, '.'); i >= 0 {
+		field = field[i+1:]
+	}
+	for i, f := range result.Fields {
+		if i < len(result.Rows) && strings.EqualFold(f, field) {
+			v := reflect.ValueOf(result.Rows[i])
+			if v.Kind() == reflect.Pointer {
+				if v.IsNil() {
+					return nil, true
+				}
+				return v.Elem().Interface(), true
+			}
+			return result.Rows[i], true
+		}
+	}
+	if result.Data != nil {
+		v := reflect.Indirect(reflect.ValueOf(result.Data))
+		if v.Kind() == reflect.Struct {
+			fv := v.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, field) })
+			if fv.IsValid() {
+				return fv.Interface(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (token *pageToken) encode() (string, error) {
+	td := &tokenData{Offset: token.Offset, Keys: make([]tokenKey, len(token.Keys))}
+	for i, k := range token.Keys {
+		switch v := k.(type) {
+		case time.Time:
+			td.Keys[i] = tokenKey{Type: tokenTime, Value: v.Format(time.RFC3339Nano)}
+		case []byte:
+			td.Keys[i] = tokenKey{Type: tokenBytes, Value: base64.RawURLEncoding.EncodeToString(v)}
+		default:
+			td.Keys[i] = tokenKey{Value: k}
+		}
+	}
+	data, err := json.Marshal(td)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodePageToken decode the continuation token, numbers are returned as
+// int64 or float64, times as time.Time and byte slices as []byte
+func decodePageToken(token string) (*pageToken, error) {
+	pt := &pageToken{}
+	if token == "" {
+		return pt, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errorrepo.NewError("DB000056", token)
+	}
+	td := &tokenData{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(td); err != nil {
+		return nil, errorrepo.NewError("DB000056", token)
+	}
+	pt.Offset = td.Offset
+	for _, k := range td.Keys {
+		v, err := k.decode()
+		if err != nil {
+			return nil, errorrepo.NewError("DB000056", token)
+		}
+		pt.Keys = append(pt.Keys, v)
+	}
+	return pt, nil
+}
+
+// decode value of the token key with its type
+func (key *tokenKey) decode() (any, error) {
+	switch v := key.Value.(type) {
+	case json.Number:
+		if iv, err := v.Int64(); err == nil {
+			return iv, nil
+		}
+		return v.Float64()
+	case string:
+		switch key.Type {
+		case tokenTime:
+			return time.Parse(time.RFC3339Nano, v)
+		case tokenBytes:
+			return base64.RawURLEncoding.DecodeString(v)
+		}
+	}
+	if key.Type != "" {
+		return nil, errorrepo.NewError("DB000056", key.Type)
+	}
+	return key.Value, nil
+}
@@ -0,0 +1,78 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeysetSelect(t *testing.T) {
+	InitLog(t)
+
+	q := &Query{Driver: PostgresType, TableName: "ABC", Fields: []string{"ID"},
+		Sort: []SortField{Asc("ID")}, After: []any{"B"}, LimitRows: 3}
+	selectCmd, args, err := q.SelectParameters()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT ID FROM ABC tn WHERE ID > $1 ORDER BY ID ASC LIMIT 3", selectCmd)
+	assert.Equal(t, []any{"B"}, args)
+
+	q = &Query{Driver: MysqlType, TableName: "ABC", Fields: []string{"ID"}, Search: "Age > ?",
+		Parameters: []any{18}, Sort: []SortField{Desc("Name"), Asc("ID")}, After: []any{"Anna", 5}}
+	selectCmd, args, err = q.SelectParameters()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT ID FROM ABC tn WHERE (Age > ?) AND (Name < ? OR (Name = ? AND ID > ?)) ORDER BY Name DESC,ID ASC", selectCmd)
+	assert.Equal(t, []any{18, "Anna", "Anna", 5}, args)
+
+	q.After = []any{"Anna"}
+	_, _, err = q.SelectParameters()
+	assert.Error(t, err)
+}
+
+func TestCountStatement(t *testing.T) {
+	InitLog(t)
+
+	q := &Query{Driver: PostgresType, TableName: "ABC", Fields: []string{"ID"}, Search: "Age > ?",
+		Parameters: []any{18}, Where: Eq("Name", "Anna"), Sort: []SortField{Asc("ID")},
+		After: []any{3}, LimitRows: 10, Offset: 20}
+	countCmd, args, err := q.CountStatement()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT COUNT(*) FROM ABC tn WHERE (Age > ?) AND Name = $2", countCmd)
+	assert.Equal(t, []any{18, "Anna"}, args)
+
+	q = &Query{Driver: PostgresType, TableName: "ABC", Fields: []string{"Name"}, Descriptor: true}
+	countCmd, _, err = q.CountStatement()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT DISTINCT Name FROM ABC tn) cnt", countCmd)
+}
+
+func TestPageToken(t *testing.T) {
+	InitLog(t)
+
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 8, time.UTC)
+	token, err := (&pageToken{Keys: []any{"abc", 12, 1.5, ts, []byte{0, 1, 255}, nil}}).encode()
+	assert.NoError(t, err)
+	pt, err := decodePageToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"abc", int64(12), 1.5, ts, []byte{0, 1, 255}, nil}, pt.Keys)
+
+	token, err = (&pageToken{Offset: 40}).encode()
+	assert.NoError(t, err)
+	pt, err = decodePageToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(40), pt.Offset)
+
+	_, err = decodePageToken("no*token")
+	assert.Error(t, err)
+}
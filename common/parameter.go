@@ -0,0 +1,143 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/log"
+)
+
+// BindParameters rewrite the placeholders of the statement to the
+// placeholder syntax of the driver and return the parameters in the order
+// of the placeholders. The statement may use `?` for the next parameter,
+// `$n` for the n-th parameter or `:name` for a parameter created with
+// sql.Named. Placeholders inside quotes are not changed. Without
+// parameters the statement is returned unchanged. The `?` and `$n`
+// placeholders only count parameters not created with sql.Named.
+func BindParameters(driver ReferenceType, statement string, parameters []any) (string, []any, error) {
+	return BindParametersOffset(driver, statement, parameters, 0)
+}
+
+// BindParametersOffset rewrite the placeholders of the statement part like
+// BindParameters, the placeholders of the driver are numbered after the
+// offset of the placeholders preceding the part
+func BindParametersOffset(driver ReferenceType, statement string, parameters []any, offset int) (string, []any, error) {
+	if len(parameters) == 0 {
+		return statement, nil, nil
+	}
+	named := make(map[string]any)
+	positional := make([]any, 0, len(parameters))
+	for _, p := range parameters {
+		if n, ok := p.(sql.NamedArg); ok {
+			named[strings.ToLower(n.Name)] = n.Value
+			continue
+		}
+		positional = append(positional, p)
+	}
+	var buffer strings.Builder
+	args := make([]any, 0, len(parameters))
+	next := 0
+	bind := func(value any) {
+		args = append(args, value)
+		switch driver {
+		case PostgresType:
+			buffer.WriteString("$" + strconv.Itoa(offset+len(args)))
+		case OracleType:
+			buffer.WriteString(":" + strconv.Itoa(offset+len(args)))
+		default:
+			buffer.WriteString("?")
+		}
+	}
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(statement[i+1:], c)
+			if end < 0 {
+				buffer.WriteString(statement[i:])
+				i = len(statement)
+				continue
+			}
+			buffer.WriteString(statement[i : i+end+2])
+			i += end + 1
+		case c == '?':
+			if next >= len(positional) {
+				return "", nil, errorrepo.NewError("DB000050", "?"+strconv.Itoa(next+1))
+			}
+			bind(positional[next])
+			next++
+		case (c == '$' || c == ':') && i+1 < len(statement) && isDigit(statement[i+1]):
+			end := i + 1
+			for end < len(statement) && isDigit(statement[end]) {
+				end++
+			}
+			n, _ := strconv.Atoi(statement[i+1 : end])
+			if n < 1 || n > len(positional) {
+				return "", nil, errorrepo.NewError("DB000050", statement[i:end])
+			}
+			bind(positional[n-1])
+			i = end - 1
+		case c == ':' && i+1 < len(statement) && isNameStart(statement[i+1]) &&
+			(i == 0 || statement[i-1] != ':'):
+			end := i + 1
+			for end < len(statement) && (isNameStart(statement[end]) || isDigit(statement[end])) {
+				end++
+			}
+			value, ok := named[strings.ToLower(statement[i+1:end])]
+			if !ok {
+				return "", nil, errorrepo.NewError("DB000050", statement[i:end])
+			}
+			bind(value)
+			i = end - 1
+		case c == ':' && i+1 < len(statement) && statement[i+1] == ':':
+			// PostgreSQL type cast
+			buffer.WriteString("::")
+			i++
+		default:
+			buffer.WriteByte(c)
+		}
+	}
+	log.Log.Debugf("Bind parameters: %s -> %s", statement, buffer.String())
+	return buffer.String(), args, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// SelectParameters generate the SELECT statement with the placeholders of
+// the search and the Where predicate bound to the query parameters
+func (q *Query) SelectParameters() (string, []any, error) {
+	selectCmd, parameters, err := q.selectStatement()
+	if err != nil {
+		return "", nil, err
+	}
+	return BindParameters(q.Driver, selectCmd, parameters)
+}
+
+// SearchParameters condition combining the search and the Where predicate
+// with the placeholders bound to the query parameters
+func (q *Query) SearchParameters() (string, []any, error) {
+	search, parameters, err := q.whereCondition()
+	if err != nil {
+		return "", nil, err
+	}
+	return BindParameters(q.Driver, search, parameters)
+}
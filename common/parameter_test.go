@@ -0,0 +1,72 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindParameters(t *testing.T) {
+	InitLog(t)
+
+	injection := "x' OR '1'='1"
+	tests := []struct {
+		driver     ReferenceType
+		statement  string
+		parameters []any
+		expected   string
+		args       []any
+	}{
+		{PostgresType, "name = ? AND id > ?", []any{injection, 1},
+			"name = $1 AND id > $2", []any{injection, 1}},
+		{MysqlType, "name = ? AND id > ?", []any{injection, 1},
+			"name = ? AND id > ?", []any{injection, 1}},
+		{OracleType, "name = ? AND id > ?", []any{injection, 1},
+			"name = :1 AND id > :2", []any{injection, 1}},
+		{MysqlType, "a = $2 OR b = $1 OR c = $2", []any{"one", "two"},
+			"a = ? OR b = ? OR c = ?", []any{"two", "one", "two"}},
+		{PostgresType, "name = :name AND x::text = :value", []any{sql.Named("name", "abc"), sql.Named("Value", 2)},
+			"name = $1 AND x::text = $2", []any{"abc", 2}},
+		{OracleType, "name = :name AND other = ?", []any{sql.Named("name", "abc"), "def"},
+			"name = :1 AND other = :2", []any{"abc", "def"}},
+		{PostgresType, "name = '?' AND t = 'a:b' AND \"x?\" = ?", []any{1},
+			"name = '?' AND t = 'a:b' AND \"x?\" = $1", []any{1}},
+		{PostgresType, "name = ?", nil, "name = ?", nil},
+	}
+	for _, test := range tests {
+		statement, args, err := BindParameters(test.driver, test.statement, test.parameters)
+		if assert.NoError(t, err, test.statement) {
+			assert.Equal(t, test.expected, statement, test.statement)
+			assert.Equal(t, test.args, args, test.statement)
+			assert.NotContains(t, statement, injection)
+		}
+	}
+
+	for _, statement := range []string{"a = ? AND b = ?", "a = $3", "a = :unknown"} {
+		_, _, err := BindParameters(PostgresType, statement, []any{1})
+		assert.Error(t, err, statement)
+	}
+}
+
+func TestSelectParameters(t *testing.T) {
+	InitLog(t)
+
+	q := &Query{Driver: PostgresType, TableName: "ABC", Fields: []string{"Name"},
+		Search: "Name = ? AND Age > ?", Parameters: []any{"O'Brien", 20}}
+	selectCmd, args, err := q.SelectParameters()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT Name FROM ABC tn WHERE Name = $1 AND Age > $2", selectCmd)
+	assert.Equal(t, []any{"O'Brien", 20}, args)
+}
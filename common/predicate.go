@@ -0,0 +1,332 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+)
+
+// Operator comparison operator of a predicate
+type Operator string
+
+const (
+	// OpEq equal
+	OpEq Operator = "="
+	// OpNe not equal
+	OpNe Operator = "<>"
+	// OpLt less than
+	OpLt Operator = "<"
+	// OpLe less or equal
+	OpLe Operator = "<="
+	// OpGt greater than
+	OpGt Operator = ">"
+	// OpGe greater or equal
+	OpGe Operator = ">="
+)
+
+// Predicate typed search condition of a query. The predicate is rendered by
+// each database driver into its own search syntax.
+type Predicate interface {
+	predicate()
+}
+
+// ComparePredicate compare field with value
+type ComparePredicate struct {
+	Field    string
+	Operator Operator
+	Value    any
+}
+
+// InPredicate field value is one of the values
+type InPredicate struct {
+	Field  string
+	Values []any
+}
+
+// BetweenPredicate field value is in the inclusive range
+type BetweenPredicate struct {
+	Field string
+	Low   any
+	High  any
+}
+
+// LikePredicate field value matches the SQL pattern using `%` and `_`
+type LikePredicate struct {
+	Field   string
+	Pattern string
+}
+
+// NullPredicate field value is NULL or, if Not is set, is not NULL
+type NullPredicate struct {
+	Field string
+	Not   bool
+}
+
+// LogicalPredicate all (AND) or, if Or is set, any predicate is true
+type LogicalPredicate struct {
+	Or         bool
+	Predicates []Predicate
+}
+
+// NotPredicate negation of a predicate
+type NotPredicate struct {
+	Predicate Predicate
+}
+
+func (*ComparePredicate) predicate() {}
+func (*InPredicate) predicate()      {}
+func (*BetweenPredicate) predicate() {}
+func (*LikePredicate) predicate()    {}
+func (*NullPredicate) predicate()    {}
+func (*LogicalPredicate) predicate() {}
+func (*NotPredicate) predicate()     {}
+
+// Eq field is equal to value
+func Eq(field string, value any) Predicate {
+	return &ComparePredicate{Field: field, Operator: OpEq, Value: value}
+}
+
+// Ne field is not equal to value
+func Ne(field string, value any) Predicate {
+	return &ComparePredicate{Field: field, Operator: OpNe, Value: value}
+}
+
+// Lt field is less than value
+func Lt(field string, value any) Predicate {
+	return &ComparePredicate{Field: field, Operator: OpLt, Value: value}
+}
+
+// Le field is less or equal to value
+func Le(field string, value any) Predicate {
+	return &ComparePredicate{Field: field, Operator: OpLe, Value: value}
+}
+
+// Gt field is greater than value
+func Gt(field string, value any) Predicate {
+	return &ComparePredicate{Field: field, Operator: OpGt, Value: value}
+}
+
+// Ge field is greater or equal to value
+func Ge(field string, value any) Predicate {
+	return &ComparePredicate{Field: field, Operator: OpGe, Value: value}
+}
+
+// In field is one of the values
+func In(field string, values ...any) Predicate {
+	return &InPredicate{Field: field, Values: values}
+}
+
+// Between field is between low and high, both inclusive
+func Between(field string, low, high any) Predicate {
+	return &BetweenPredicate{Field: field, Low: low, High: high}
+}
+
+// Like field matches the pattern
+func Like(field string, pattern string) Predicate {
+	return &LikePredicate{Field: field, Pattern: pattern}
+}
+
+// IsNull field is NULL
+func IsNull(field string) Predicate {
+	return &NullPredicate{Field: field}
+}
+
+// IsNotNull field is not NULL
+func IsNotNull(field string) Predicate {
+	return &NullPredicate{Field: field, Not: true}
+}
+
+// And all predicates are true
+func And(predicates ...Predicate) Predicate {
+	return &LogicalPredicate{Predicates: predicates}
+}
+
+// Or any of the predicates is true
+func Or(predicates ...Predicate) Predicate {
+	return &LogicalPredicate{Or: true, Predicates: predicates}
+}
+
+// Not predicate is false
+func Not(predicate Predicate) Predicate {
+	return &NotPredicate{Predicate: predicate}
+}
+
+// NullsOrder position of NULL values in the sort order
+type NullsOrder byte
+
+const (
+	// NullsDefault database default position of NULL values
+	NullsDefault NullsOrder = iota
+	// NullsFirst NULL values sorted before all other values
+	NullsFirst
+	// NullsLast NULL values sorted after all other values
+	NullsLast
+)
+
+// SortField sort order of one field
+type SortField struct {
+	Field      string
+	Descending bool
+	Nulls      NullsOrder
+}
+
+// Asc sort field ascending
+func Asc(field string) SortField {
+	return SortField{Field: field}
+}
+
+// Desc sort field descending
+func Desc(field string) SortField {
+	return SortField{Field: field, Descending: true}
+}
+
+// WithNulls sort field with the position of NULL values
+func (s SortField) WithNulls(nulls NullsOrder) SortField {
+	s.Nulls = nulls
+	return s
+}
+
+var fieldNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// ValidateField check that the field name of a predicate or sort field is
+// a plain, optionally table qualified, identifier
+func ValidateField(field string) error {
+	if !fieldNameRegexp.MatchString(field) {
+		return errorrepo.NewError("DB000052", field)
+	}
+	return nil
+}
+
+// sqlRenderer render predicates to SQL using `$n` placeholders which are
+// bound to the driver syntax by BindParameters
+type sqlRenderer struct {
+	buffer strings.Builder
+	args   []any
+	offset int
+}
+
+// RenderPredicate render the predicate to a SQL condition. The values are
+// referenced with `$n` placeholders counted after the given number of
+// positional parameters and are returned in placeholder order.
+func RenderPredicate(predicate Predicate, positional int) (string, []any, error) {
+	r := &sqlRenderer{offset: positional}
+	err := r.render(predicate)
+	if err != nil {
+		return "", nil, err
+	}
+	return r.buffer.String(), r.args, nil
+}
+
+func (r *sqlRenderer) value(v any) {
+	r.args = append(r.args, v)
+	r.buffer.WriteString("$" + strconv.Itoa(r.offset+len(r.args)))
+}
+
+func (r *sqlRenderer) field(field string) error {
+	if err := ValidateField(field); err != nil {
+		return err
+	}
+	r.buffer.WriteString(field)
+	return nil
+}
+
+func (r *sqlRenderer) render(predicate Predicate) error {
+	switch p := predicate.(type) {
+	case *ComparePredicate:
+		if err := r.field(p.Field); err != nil {
+			return err
+		}
+		switch p.Operator {
+		case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe:
+		default:
+			return errorrepo.NewError("DB000051", "operator "+string(p.Operator), "SQL")
+		}
+		r.buffer.WriteString(" " + string(p.Operator) + " ")
+		r.value(p.Value)
+	case *InPredicate:
+		if len(p.Values) == 0 {
+			// empty IN list is false
+			r.buffer.WriteString("1 = 0")
+			return nil
+		}
+		if err := r.field(p.Field); err != nil {
+			return err
+		}
+		r.buffer.WriteString(" IN (")
+		for i, v := range p.Values {
+			if i > 0 {
+				r.buffer.WriteString(",")
+			}
+			r.value(v)
+		}
+		r.buffer.WriteString(")")
+	case *BetweenPredicate:
+		if err := r.field(p.Field); err != nil {
+			return err
+		}
+		r.buffer.WriteString(" BETWEEN ")
+		r.value(p.Low)
+		r.buffer.WriteString(" AND ")
+		r.value(p.High)
+	case *LikePredicate:
+		if err := r.field(p.Field); err != nil {
+			return err
+		}
+		r.buffer.WriteString(" LIKE ")
+		r.value(p.Pattern)
+	case *NullPredicate:
+		if err := r.field(p.Field); err != nil {
+			return err
+		}
+		if p.Not {
+			r.buffer.WriteString(" IS NOT NULL")
+		} else {
+			r.buffer.WriteString(" IS NULL")
+		}
+	case *LogicalPredicate:
+		if len(p.Predicates) == 0 {
+			if p.Or {
+				r.buffer.WriteString("1 = 0")
+			} else {
+				r.buffer.WriteString("1 = 1")
+			}
+			return nil
+		}
+		op := " AND "
+		if p.Or {
+			op = " OR "
+		}
+		r.buffer.WriteString("(")
+		for i, sub := range p.Predicates {
+			if i > 0 {
+				r.buffer.WriteString(op)
+			}
+			if err := r.render(sub); err != nil {
+				return err
+			}
+		}
+		r.buffer.WriteString(")")
+	case *NotPredicate:
+		r.buffer.WriteString("NOT (")
+		if err := r.render(p.Predicate); err != nil {
+			return err
+		}
+		r.buffer.WriteString(")")
+	default:
+		return errorrepo.NewError("DB000051", "nil", "SQL")
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderPredicate(t *testing.T) {
+	InitLog(t)
+
+	where, args, err := RenderPredicate(And(Eq("Name", "Anna"), Or(In("Counter", 1, 2), IsNull("Counter")),
+		Not(Like("City", "B%")), Between("Age", 10, 20)), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "(Name = $2 AND (Counter IN ($3,$4) OR Counter IS NULL) AND NOT (City LIKE $5) AND Age BETWEEN $6 AND $7)", where)
+	assert.Equal(t, []any{"Anna", 1, 2, "B%", 10, 20}, args)
+
+	where, args, err = RenderPredicate(Or(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "1 = 0", where)
+	assert.Empty(t, args)
+
+	for _, field := range []string{"", "Name = 1 OR 1", "a;b", "1abc"} {
+		_, _, err = RenderPredicate(Eq(field, 1), 0)
+		assert.Error(t, err, field)
+	}
+	assert.NoError(t, ValidateField("tn.Name"))
+}
+
+func TestSelectPredicate(t *testing.T) {
+	InitLog(t)
+
+	tests := []struct {
+		driver   ReferenceType
+		expected string
+	}{
+		{PostgresType, "SELECT ID FROM ABC tn WHERE (Age > $1) AND (Name = $2 OR Name IS NULL) ORDER BY ID ASC,Name DESC NULLS LAST LIMIT 10 OFFSET 20"},
+		{MysqlType, "SELECT ID FROM ABC tn WHERE (Age > ?) AND (Name = ? OR Name IS NULL) ORDER BY ID ASC,Name IS NULL ASC,Name DESC LIMIT 10 OFFSET 20"},
+		{OracleType, "SELECT ID FROM ABC tn WHERE (Age > :1) AND (Name = :2 OR Name IS NULL) ORDER BY ID ASC,Name DESC NULLS LAST OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+		{SqliteType, "SELECT ID FROM ABC tn WHERE (Age > ?) AND (Name = ? OR Name IS NULL) ORDER BY ID ASC,Name DESC NULLS LAST LIMIT 10 OFFSET 20"},
+	}
+	for _, test := range tests {
+		q := &Query{Driver: test.driver, TableName: "ABC", Fields: []string{"ID"},
+			Search: "Age > ?", Parameters: []any{18}, Where: Or(Eq("Name", "Anna"), IsNull("Name")),
+			Order: []string{"ID"}, Sort: []SortField{Desc("Name").WithNulls(NullsLast)},
+			LimitRows: 10, Offset: 20}
+		selectCmd, args, err := q.SelectParameters()
+		if assert.NoError(t, err, test.driver) {
+			assert.Equal(t, test.expected, selectCmd)
+			assert.Equal(t, []any{18, "Anna"}, args)
+		}
+	}
+
+	q := &Query{Driver: SqliteType, TableName: "ABC", Fields: []string{"ID"}, Offset: 5}
+	selectCmd, err := q.Select()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT ID FROM ABC tn LIMIT -1 OFFSET 5", selectCmd)
+
+	q = &Query{Driver: MysqlType, TableName: "ABC", Fields: []string{"ID"},
+		Sort: []SortField{Asc("ID").WithNulls(NullsFirst)}}
+	selectCmd, err = q.Select()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT ID FROM ABC tn ORDER BY ID IS NULL DESC,ID ASC", selectCmd)
+
+	q.Sort = []SortField{Asc("ID DESC; DROP TABLE ABC")}
+	_, err = q.Select()
+	assert.Error(t, err)
+}
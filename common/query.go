@@ -23,34 +23,76 @@ import (
 	"github.com/tknie/log"
 )
 
+// JoinType type of a table join
+type JoinType byte
+
+const (
+	// InnerJoin rows with matching rows in the joined table only
+	InnerJoin JoinType = iota
+	// LeftJoin all rows, the columns of the joined table are NULL if no
+	// row matches
+	LeftJoin
+)
+
+// JoinTable table joined to the query using the join condition. Struct
+// fields tagged with `join=<alias>` get the columns of the joined table.
+type JoinTable struct {
+	Type      JoinType
+	TableName string
+	Alias     string
+	On        string
+}
+
 type Query struct {
 	Driver       ReferenceType
 	TableName    string
+	Alias        string
 	Search       string
 	Join         string
+	Joins        []JoinTable
 	Fields       []string
 	Order        []string
 	Group        []string
 	Parameters   []any
 	Limit        string
+	Where        Predicate
+	Sort         []SortField
+	LimitRows    uint64
+	Offset       uint64
+	After        []any
+	Page         *Page
 	Blocksize    int32
 	Descriptor   bool
 	DataStruct   any
 	TypeInfo     any
 	FctParameter any
+	// IncludeDeleted query the records soft deleted using the deleted
+	// tagged field of the data structure too
+	IncludeDeleted bool
 }
 
 type sqlInterface interface {
 	Value() (driver.Value, error)
 }
 
+// Select generate the SELECT statement of the query. The values of the
+// Where predicate are referenced by `$n` placeholders, use
+// SelectParameters to bind them in the driver syntax.
 func (q *Query) Select() (string, error) {
+	selectCmd, _, err := q.selectStatement()
+	return selectCmd, err
+}
+
+// selectStatement generate the SELECT statement and the parameters
+// referenced by the statement
+func (q *Query) selectStatement() (string, []any, error) {
 	log.Log.Debugf("Query select with type %s", q.Driver)
+	alias := q.tableAlias()
 	var selectCmd bytes.Buffer
 	switch {
 	case q.TableName == "":
 		log.Log.Debugf("Table name missing")
-		return "", errorrepo.NewError("DB000016")
+		return "", nil, errorrepo.NewError("DB000016")
 	case q.DataStruct != nil:
 		selectCmd.WriteString("SELECT ")
 		if q.Descriptor {
@@ -58,8 +100,11 @@ func (q *Query) Select() (string, error) {
 		}
 		ti := CreateInterface(q.DataStruct, q.Fields)
 		q.TypeInfo = ti
-		selectCmd.WriteString(ti.CreateQueryFields())
-		selectCmd.WriteString(" FROM " + q.TableName + " tn")
+		if len(q.Joins) > 0 || q.Join != "" {
+			selectCmd.WriteString(ti.createQueryFields(alias))
+		} else {
+			selectCmd.WriteString(ti.CreateQueryFields())
+		}
 	default:
 		selectCmd.WriteString("SELECT ")
 		if q.Descriptor {
@@ -75,13 +120,18 @@ func (q *Query) Select() (string, error) {
 				selectCmd.WriteString(s)
 			}
 		}
-		selectCmd.WriteString(" FROM " + q.TableName + " tn")
 	}
-	if q.Search != "" {
-		selectCmd.WriteString(" WHERE " + q.Search)
+	from, err := q.fromClause(alias)
+	if err != nil {
+		return "", nil, err
 	}
-	if q.Join != "" {
-		selectCmd.WriteString(" LIKE " + q.Join)
+	selectCmd.WriteString(from)
+	search, parameters, err := q.whereCondition()
+	if err != nil {
+		return "", nil, err
+	}
+	if search != "" {
+		selectCmd.WriteString(" WHERE " + search)
 	}
 	if len(q.Group) > 0 {
 		selectCmd.WriteString(" GROUP BY ")
@@ -92,7 +142,7 @@ func (q *Query) Select() (string, error) {
 			selectCmd.WriteString(s)
 		}
 	}
-	if len(q.Order) > 0 {
+	if len(q.Order) > 0 || len(q.Sort) > 0 {
 		selectCmd.WriteString(" ORDER BY ")
 		for x, s := range q.Order {
 			if x > 0 {
@@ -106,7 +156,7 @@ func (q *Query) Select() (string, error) {
 				x = strings.ToUpper(entry[1])
 			default:
 				log.Log.Debugf("Split order incorect")
-				return "", errorrepo.NewError("DB000017")
+				return "", nil, errorrepo.NewError("DB000017")
 			}
 			log.Log.Debugf("Order by: " + x)
 			switch x {
@@ -116,6 +166,16 @@ func (q *Query) Select() (string, error) {
 				selectCmd.WriteString(entry[0] + " ASC")
 			}
 		}
+		for x, s := range q.Sort {
+			if x > 0 || len(q.Order) > 0 {
+				selectCmd.WriteString(",")
+			}
+			sortCmd, err := s.orderBy(q.Driver)
+			if err != nil {
+				return "", nil, err
+			}
+			selectCmd.WriteString(sortCmd)
+		}
 	}
 	sqlCmd := selectCmd.String()
 	if q.Limit != "" {
@@ -126,9 +186,170 @@ func (q *Query) Select() (string, error) {
 		default:
 			sqlCmd += fmt.Sprintf(" LIMIT %s", q.Limit)
 		}
+	} else {
+		sqlCmd += q.limitOffset()
+	}
+	log.Log.Debugf("Final select: %s", sqlCmd)
+	return sqlCmd, parameters, nil
+}
+
+// tableAlias alias of the query table, default is `tn`
+func (q *Query) tableAlias() string {
+	if q.Alias == "" {
+		return "tn"
+	}
+	return q.Alias
+}
+
+// fromClause FROM clause of the table and the joined tables
+func (q *Query) fromClause(alias string) (string, error) {
+	var from strings.Builder
+	from.WriteString(" FROM " + q.TableName + " " + alias)
+	for _, join := range q.Joins {
+		joinCmd, err := join.join()
+		if err != nil {
+			return "", err
+		}
+		from.WriteString(joinCmd)
+	}
+	if q.Join != "" {
+		from.WriteString(" " + q.Join)
+	}
+	return from.String(), nil
+}
+
+// join JOIN clause of the joined table
+func (j *JoinTable) join() (string, error) {
+	if j.TableName == "" {
+		return "", errorrepo.NewError("DB000016")
+	}
+	alias := j.Alias
+	if alias == "" {
+		alias = j.TableName
+	}
+	if err := ValidateField(alias); err != nil {
+		return "", err
+	}
+	if j.On == "" {
+		return "", errorrepo.NewError("DB000053", j.TableName)
+	}
+	joinType := " INNER JOIN "
+	if j.Type == LeftJoin {
+		joinType = " LEFT JOIN "
+	}
+	return joinType + j.TableName + " " + alias + " ON " + j.On, nil
+}
+
+// whereCondition condition of the WHERE clause combining the search and
+// the Where predicate, the parameters of the predicate are appended to
+// the query parameters
+func (q *Query) whereCondition() (string, []any, error) {
+	where := AndPredicate(q.Where, q.notDeleted())
+	if len(q.After) > 0 {
+		keyset, err := q.KeysetPredicate()
+		if err != nil {
+			return "", nil, err
+		}
+		where = AndPredicate(where, keyset)
+	}
+	if where == nil {
+		return q.Search, q.Parameters, nil
+	}
+	positional := 0
+	for _, p := range q.Parameters {
+		if _, ok := p.(sql.NamedArg); !ok {
+			positional++
+		}
+	}
+	condition, args, err := RenderPredicate(where, positional)
+	if err != nil {
+		return "", nil, err
+	}
+	parameters := append(append(make([]any, 0, len(q.Parameters)+len(args)), q.Parameters...), args...)
+	if q.Search == "" {
+		return condition, parameters, nil
+	}
+	return "(" + q.Search + ") AND " + condition, parameters, nil
+}
+
+// notDeleted predicate excluding the records soft deleted using the deleted
+// tagged field of the data structure
+func (q *Query) notDeleted() Predicate {
+	if q.IncludeDeleted || q.DataStruct == nil {
+		return nil
+	}
+	deleted := CreateInterface(q.DataStruct, nil).RowNames["#deleted"]
+	if len(deleted) == 0 {
+		return nil
+	}
+	if len(q.Joins) > 0 || q.Join != "" {
+		return IsNull(q.tableAlias() + "." + deleted[0])
+	}
+	return IsNull(deleted[0])
+}
+
+// orderBy ORDER BY entry of the sort field, MySQL has no NULLS FIRST or
+// NULLS LAST and sorts by the NULL check first
+func (s SortField) orderBy(driver ReferenceType) (string, error) {
+	if err := ValidateField(s.Field); err != nil {
+		return "", err
+	}
+	direction := " ASC"
+	if s.Descending {
+		direction = " DESC"
+	}
+	switch {
+	case s.Nulls == NullsDefault:
+		return s.Field + direction, nil
+	case driver == MysqlType && s.Nulls == NullsFirst:
+		return s.Field + " IS NULL DESC," + s.Field + direction, nil
+	case driver == MysqlType:
+		return s.Field + " IS NULL ASC," + s.Field + direction, nil
+	case s.Nulls == NullsFirst:
+		return s.Field + direction + " NULLS FIRST", nil
+	default:
+		return s.Field + direction + " NULLS LAST", nil
+	}
+}
+
+// limitOffset LIMIT and OFFSET clause of the numeric limit and offset
+func (q *Query) limitOffset() string {
+	if q.LimitRows == 0 && q.Offset == 0 {
+		return ""
+	}
+	switch q.Driver {
+	case OracleType:
+		clause := ""
+		if q.Offset > 0 {
+			clause = fmt.Sprintf(" OFFSET %d ROWS", q.Offset)
+		}
+		if q.LimitRows > 0 {
+			clause += fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", q.LimitRows)
+		}
+		return clause
+	case MysqlType, SqliteType:
+		// MySQL and SQLite need a LIMIT for an OFFSET
+		limit := fmt.Sprintf(" LIMIT %d", q.LimitRows)
+		if q.LimitRows == 0 {
+			limit = " LIMIT 18446744073709551615"
+			if q.Driver == SqliteType {
+				limit = " LIMIT -1"
+			}
+		}
+		if q.Offset > 0 {
+			limit += fmt.Sprintf(" OFFSET %d", q.Offset)
+		}
+		return limit
+	default:
+		clause := ""
+		if q.LimitRows > 0 {
+			clause = fmt.Sprintf(" LIMIT %d", q.LimitRows)
+		}
+		if q.Offset > 0 {
+			clause += fmt.Sprintf(" OFFSET %d", q.Offset)
+		}
+		return clause
 	}
-	log.Log.Debugf("Final select: %s", selectCmd.String())
-	return sqlCmd, nil
 }
 
 func (search *Query) ParseRows(rows *sql.Rows, f ResultFunction) (result *Result, err error) {
@@ -61,3 +61,44 @@ func TestQuery(t *testing.T) {
 	assert.Equal(t, "SELECT * FROM (SELECT field1,field2 FROM ABC tn WHERE id='10' ORDER BY aaa ASC,bbb ASC,dddd DESC) WHERE rownum < 10", selectCmd)
 
 }
+
+type joinAuthor struct {
+	ID   string
+	Name string
+}
+
+type joinBook struct {
+	ID        string
+	Title     string
+	Author    *joinAuthor `flynn:"Author:join=authors"`
+	Publisher joinAuthor  `flynn:"Publisher:join=pub"`
+}
+
+func TestQueryJoin(t *testing.T) {
+	InitLog(t)
+
+	q := &Query{Driver: PostgresType, TableName: "Books", Alias: "b",
+		Fields: []string{"b.Title", "authors.Name"},
+		Joins: []JoinTable{{TableName: "Authors", Alias: "authors", On: "authors.ID = b.AuthorID"},
+			{Type: LeftJoin, TableName: "Publishers", Alias: "pub", On: "pub.ID = b.PublisherID"}},
+		Where: Eq("authors.Name", "Kafka")}
+	selectCmd, args, err := q.SelectParameters()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT b.Title,authors.Name FROM Books b INNER JOIN Authors authors ON authors.ID = b.AuthorID "+
+		"LEFT JOIN Publishers pub ON pub.ID = b.PublisherID WHERE authors.Name = $1", selectCmd)
+	assert.Equal(t, []any{"Kafka"}, args)
+
+	q = &Query{Driver: MysqlType, TableName: "Books", DataStruct: &joinBook{}, Fields: []string{"*"},
+		Joins: []JoinTable{{TableName: "Authors", Alias: "authors", On: "authors.ID = tn.AuthorID"},
+			{Type: LeftJoin, TableName: "Publishers", Alias: "pub", On: "pub.ID = tn.PublisherID"}}}
+	selectCmd, err = q.Select()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT tn.ID,tn.Title,authors.ID,authors.Name,pub.ID,pub.Name FROM Books tn "+
+		"INNER JOIN Authors authors ON authors.ID = tn.AuthorID LEFT JOIN Publishers pub ON pub.ID = tn.PublisherID", selectCmd)
+	ti := q.TypeInfo.(*typeInterface)
+	assert.Equal(t, []string{"ID", "Title"}, ti.RowFields)
+
+	q.Joins = []JoinTable{{TableName: "Authors"}}
+	_, err = q.Select()
+	assert.Error(t, err)
+}
@@ -58,9 +58,6 @@ func TestReferenceURL(t *testing.T) {
 	assert.Equal(t, "test123", p)
 	ref.SetType("mysql")
 	assert.Equal(t, &Reference{Driver: MysqlType, Host: "host", Port: 123, User: "admin", Database: "datab"}, ref)
-	ref, _, err = NewReference("adatcp://adahost:123")
-	assert.NoError(t, err)
-	assert.Equal(t, &Reference{Driver: AdabasType, Host: "adahost", Port: 123, Database: "4"}, ref)
 	ref, p, err = NewReference("postgres://<user>:<password>@lion:5432/bitgarten")
 	assert.NoError(t, err)
 	assert.Equal(t, &Reference{Driver: PostgresType, Host: "lion", Port: 5432, Database: "bitgarten",
@@ -12,6 +12,7 @@
 package common
 
 import (
+	"context"
 	"sync"
 
 	"github.com/tknie/errorrepo"
@@ -35,3 +36,26 @@ func searchDataDriver(id RegDbID) (Database, error) {
 	log.Log.Debugf("DataDriver id not found")
 	return nil, errorrepo.NewError("DB000002", id)
 }
+
+// searchDataDriverContext search data driver and check that the context is
+// not already done
+func searchDataDriverContext(ctx context.Context, id RegDbID) (Database, error) {
+	if ctx == nil {
+		return nil, errorrepo.NewError("DB000023")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, context.Cause(ctx)
+	}
+	return searchDataDriver(id)
+}
+
+// ContextError return the cause of the context if the context is done,
+// otherwise the error is returned unchanged. The returned error can be
+// checked with errors.Is(err, context.Canceled) or
+// errors.Is(err, context.DeadlineExceeded).
+func ContextError(ctx context.Context, err error) error {
+	if err == nil || ctx == nil || ctx.Err() == nil {
+		return err
+	}
+	return context.Cause(ctx)
+}
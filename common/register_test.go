@@ -0,0 +1,56 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextError(t *testing.T) {
+	InitLog(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	driverErr := fmt.Errorf("driver error")
+	assert.Equal(t, driverErr, ContextError(ctx, driverErr))
+	assert.NoError(t, ContextError(ctx, nil))
+	cancel()
+	assert.NoError(t, ContextError(ctx, nil))
+	assert.True(t, errors.Is(ContextError(ctx, driverErr), context.Canceled))
+
+	cause := fmt.Errorf("client disconnected")
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(cause)
+	assert.Equal(t, cause, ContextError(ctx, driverErr))
+
+	id := RegDbID(1)
+	_, err := id.QueryContext(ctx, &Query{TableName: "ABC"}, nil)
+	assert.Equal(t, cause, err)
+	_, err = id.InsertContext(ctx, "ABC", &Entries{})
+	assert.Equal(t, cause, err)
+	err = id.PingContext(ctx)
+	assert.Equal(t, cause, err)
+	assert.Equal(t, cause, id.CreateTableContext(ctx, "ABC", &Entries{}))
+	_, err = id.CreateTableIfNotExistsContext(ctx, "ABC", &Entries{})
+	assert.Equal(t, cause, err)
+	assert.Equal(t, cause, id.DeleteTableContext(ctx, "ABC"))
+	_, err = id.GetTableColumnContext(ctx, "ABC")
+	assert.Equal(t, cause, err)
+	_, err = id.TablesContext(ctx)
+	assert.Equal(t, cause, err)
+	_, err = id.OpenContext(ctx)
+	assert.Equal(t, cause, err)
+}
@@ -0,0 +1,238 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tknie/errorrepo"
+)
+
+// modifier database driver or transaction updating and deleting records
+type modifier interface {
+	UpdateContext(ctx context.Context, name string, insert *Entries) ([][]any, int64, error)
+	DeleteContext(ctx context.Context, name string, remove *Entries) (int64, error)
+}
+
+// UpdateStruct update the records of the structures. The records are
+// searched using the key tagged fields of the structure, all other fields
+// are updated.
+func (id RegDbID) UpdateStruct(name string, structs ...any) (int64, error) {
+	return id.UpdateStructContext(context.Background(), name, structs...)
+}
+
+// UpdateStructContext update the records of the structures, the update is
+// canceled and rolled back if the context is done
+func (id RegDbID) UpdateStructContext(ctx context.Context, name string, structs ...any) (int64, error) {
+	driver, err := searchDataDriverContext(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := updateStruct(ctx, driver, name, structs)
+	return rowsAffected, ContextError(ctx, err)
+}
+
+// DeleteStruct delete the records of the structures searched using the key
+// tagged fields of the structure
+func (id RegDbID) DeleteStruct(name string, structs ...any) (int64, error) {
+	return id.DeleteStructContext(context.Background(), name, structs...)
+}
+
+// DeleteStructContext delete the records of the structures, the delete is
+// canceled and rolled back if the context is done
+func (id RegDbID) DeleteStructContext(ctx context.Context, name string, structs ...any) (int64, error) {
+	driver, err := searchDataDriverContext(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := deleteStruct(ctx, driver, name, structs)
+	return rowsAffected, ContextError(ctx, err)
+}
+
+// UpdateStruct update the records of the structures in the transaction
+func (tx *Tx) UpdateStruct(name string, structs ...any) (int64, error) {
+	return tx.UpdateStructContext(tx.ctx, name, structs...)
+}
+
+// UpdateStructContext update the records of the structures in the
+// transaction using context
+func (tx *Tx) UpdateStructContext(ctx context.Context, name string, structs ...any) (int64, error) {
+	if err := tx.check(ctx); err != nil {
+		return 0, err
+	}
+	rowsAffected, err := updateStruct(ctx, tx.transaction, name, structs)
+	return rowsAffected, ContextError(ctx, err)
+}
+
+// DeleteStruct delete the records of the structures in the transaction
+func (tx *Tx) DeleteStruct(name string, structs ...any) (int64, error) {
+	return tx.DeleteStructContext(tx.ctx, name, structs...)
+}
+
+// DeleteStructContext delete the records of the structures in the
+// transaction using context
+func (tx *Tx) DeleteStructContext(ctx context.Context, name string, structs ...any) (int64, error) {
+	if err := tx.check(ctx); err != nil {
+		return 0, err
+	}
+	rowsAffected, err := deleteStruct(ctx, tx.transaction, name, structs)
+	return rowsAffected, ContextError(ctx, err)
+}
+
+// updateStruct update the structures using the batch update if the driver
+// provides it
+func updateStruct(ctx context.Context, driver modifier, name string, structs []any) (int64, error) {
+	if len(structs) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	for _, s := range structs {
+		setTimestamp(s, now, UpdatedTag)
+	}
+	update, err := StructUpdate(name, structs...)
+	if err != nil {
+		return 0, err
+	}
+	var rowsAffected int64
+	if batch, ok := driver.(BatchModifier); ok {
+		var counts []int64
+		counts, err = batch.BatchUpdateContext(ctx, name, update)
+		rowsAffected = sumRows(counts)
+	} else {
+		_, rowsAffected, err = driver.UpdateContext(ctx, name, update)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if update.Version != "" {
+		for _, s := range structs {
+			incrementVersion(s, update.Version)
+		}
+	}
+	return rowsAffected, nil
+}
+
+// deleteStruct delete the structures using the batch delete if the driver
+// provides it
+func deleteStruct(ctx context.Context, driver modifier, name string, structs []any) (int64, error) {
+	if len(structs) == 0 {
+		return 0, nil
+	}
+	remove, err := StructDelete(name, structs...)
+	if err != nil {
+		return 0, err
+	}
+	remove.DataStruct = structs[0]
+	if batch, ok := driver.(BatchModifier); ok {
+		rowsAffected, err := batchDeleteEntries(ctx, batch, name, remove)
+		return sumRows(rowsAffected), err
+	}
+	return deleteEntries(ctx, driver, name, remove)
+}
+
+// deleteEntries delete the records or update the deleted tagged field of
+// the data structure
+func deleteEntries(ctx context.Context, driver modifier, name string, remove *Entries) (int64, error) {
+	update, err := remove.softDelete(name)
+	if err != nil {
+		return 0, err
+	}
+	if update != nil {
+		_, rowsAffected, err := driver.UpdateContext(ctx, name, update)
+		return rowsAffected, err
+	}
+	return driver.DeleteContext(ctx, name, remove)
+}
+
+func sumRows(rowsAffected []int64) int64 {
+	sum := int64(0)
+	for _, r := range rowsAffected {
+		sum += r
+	}
+	return sum
+}
+
+// StructUpdate update entries of the structures. The fields of the
+// structure are updated, the key tagged fields are the conditions named
+// in Update and are appended after the updated fields. A structure
+// without key tagged fields uses the ISN tagged field as field `ISN`. The
+// version tagged field is the Version of the entries. The created and
+// deleted tagged fields are not updated.
+func StructUpdate(name string, structs ...any) (*Entries, error) {
+	keyFields, keys, err := structKeys(name, structs[0])
+	if err != nil {
+		return nil, err
+	}
+	dynamic := CreateInterface(structs[0], nil)
+	fields := make([]string, 0, len(dynamic.RowFields))
+	skip := append(append(slices.Clone(keyFields), dynamic.RowNames["#created"]...), dynamic.RowNames["#deleted"]...)
+	for _, f := range dynamic.RowFields {
+		if !slices.ContainsFunc(skip, func(k string) bool { return strings.EqualFold(k, f) }) {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, errorrepo.NewError("DB000062", name)
+	}
+	update := &Entries{Fields: append(slices.Clone(fields), keys...), Update: keys,
+		Values: make([][]any, 0, len(structs))}
+	if version := dynamic.RowNames["#version"]; len(version) > 0 {
+		update.Version = version[0]
+	}
+	for _, s := range structs {
+		v, err := CreateInterface(s, fields).CreateValues(s)
+		if err != nil {
+			return nil, err
+		}
+		kv, err := CreateInterface(s, keyFields).CreateValues(s)
+		if err != nil {
+			return nil, err
+		}
+		update.Values = append(update.Values, append(v, kv...))
+	}
+	return update, nil
+}
+
+// StructDelete delete entries of the structures using the key tagged
+// fields or the ISN tagged field as field `ISN`
+func StructDelete(name string, structs ...any) (*Entries, error) {
+	keyFields, keys, err := structKeys(name, structs[0])
+	if err != nil {
+		return nil, err
+	}
+	remove := &Entries{Fields: keys, Values: make([][]any, 0, len(structs))}
+	for _, s := range structs {
+		kv, err := CreateInterface(s, keyFields).CreateValues(s)
+		if err != nil {
+			return nil, err
+		}
+		remove.Values = append(remove.Values, kv)
+	}
+	return remove, nil
+}
+
+// structKeys key tagged fields of the structure and the field names used
+// in the condition
+func structKeys(name string, s any) ([]string, []string, error) {
+	dynamic := CreateInterface(s, nil)
+	if keys := dynamic.RowNames["#key"]; len(keys) > 0 {
+		return keys, keys, nil
+	}
+	if isn := dynamic.RowNames["#index"]; len(isn) > 0 {
+		return isn, []string{"ISN"}, nil
+	}
+	return nil, nil, errorrepo.NewError("DB000063", fmt.Sprintf("%T", s), name)
+}
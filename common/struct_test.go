@@ -0,0 +1,71 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type structOrder struct {
+	Shop   string `flynn:"Shop:key"`
+	Number int    `flynn:"Number:key"`
+	Amount int64
+	Note   string
+}
+
+type structEmployee struct {
+	Isn  uint64 `flynn:"Isn:isn"`
+	Name string `flynn:"AE"`
+}
+
+type structNoKey struct {
+	Name string
+}
+
+func TestStructUpdate(t *testing.T) {
+	InitLog(t)
+
+	update, err := StructUpdate("Orders", &structOrder{Shop: "A", Number: 1, Amount: 10, Note: "x"},
+		&structOrder{Shop: "B", Number: 2, Amount: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Amount", "Note", "Shop", "Number"}, update.Fields)
+	assert.Equal(t, []string{"Shop", "Number"}, update.Update)
+	assert.Equal(t, [][]any{{int64(10), "x", "A", 1}, {int64(20), "", "B", 2}}, update.Values)
+
+	update, err = StructUpdate("EMPLOYEES", &structEmployee{Isn: 12, Name: "SMITH"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AE", "ISN"}, update.Fields)
+	assert.Equal(t, []string{"ISN"}, update.Update)
+	assert.Equal(t, [][]any{{"SMITH", uint64(12)}}, update.Values)
+
+	_, err = StructUpdate("Names", &structNoKey{Name: "A"})
+	assert.Error(t, err)
+}
+
+func TestStructDelete(t *testing.T) {
+	InitLog(t)
+
+	remove, err := StructDelete("Orders", &structOrder{Shop: "A", Number: 1, Amount: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Shop", "Number"}, remove.Fields)
+	assert.Equal(t, [][]any{{"A", 1}}, remove.Values)
+
+	remove, err = StructDelete("EMPLOYEES", structEmployee{Isn: 12})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ISN"}, remove.Fields)
+	assert.Equal(t, [][]any{{uint64(12)}}, remove.Values)
+
+	_, err = StructDelete("Names", &structNoKey{})
+	assert.Error(t, err)
+}
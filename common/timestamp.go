@@ -0,0 +1,127 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tknie/errorrepo"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// timestampField field of the data structure tagged with the created,
+// updated or deleted tag
+func (entries *Entries) timestampField(tag string) string {
+	if entries.DataStruct == nil {
+		return ""
+	}
+	if field := CreateInterface(entries.DataStruct, nil).RowNames[tag]; len(field) > 0 {
+		return field[0]
+	}
+	return ""
+}
+
+// CreatedField field of the data structure tagged with `:created`, it is
+// not changed by an update
+func (entries *Entries) CreatedField() string {
+	return entries.timestampField("#created")
+}
+
+// insertTimestamps set the created and updated tagged fields of the
+// inserted structures to the current time
+func (entries *Entries) insertTimestamps() {
+	if entries.DataStruct == nil {
+		return
+	}
+	now := time.Now()
+	for _, v := range entries.Values {
+		if len(v) > 0 {
+			setTimestamp(v[0], now, CreatedTag, UpdatedTag)
+		}
+	}
+}
+
+// updateTimestamps set the updated tagged field of the updated structures
+// to the current time. The field is added to the updated fields.
+func (entries *Entries) updateTimestamps() {
+	updated := entries.timestampField("#updated")
+	if updated == "" {
+		return
+	}
+	if len(entries.Fields) > 0 && !slices.Contains(entries.Fields, "*") &&
+		!slices.ContainsFunc(entries.Fields, func(f string) bool { return strings.EqualFold(f, updated) }) {
+		entries.Fields = append(entries.Fields, updated)
+	}
+	now := time.Now()
+	for _, v := range entries.Values {
+		if len(v) > 0 {
+			setTimestamp(v[0], now, UpdatedTag)
+		}
+	}
+}
+
+// setTimestamp set the time or time pointer fields of the structure pointer
+// with one of the tags
+func setTimestamp(s any, now time.Time, tags ...TagInfo) {
+	value := reflect.ValueOf(s)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return
+	}
+	value = value.Elem()
+	for i := 0; i < value.NumField(); i++ {
+		_, tagInfo := TagInfoParse(value.Type().Field(i).Tag.Get(TagName))
+		field := value.Field(i)
+		if !slices.Contains(tags, tagInfo) || !field.CanSet() {
+			continue
+		}
+		switch {
+		case field.Type() == timeType:
+			field.Set(reflect.ValueOf(now))
+		case field.Type() == reflect.PointerTo(timeType):
+			t := now
+			field.Set(reflect.ValueOf(&t))
+		}
+	}
+}
+
+// softDelete update entries setting the deleted tagged field of the data
+// structure to the current time instead of deleting the records. Records
+// already deleted are not changed. Returns nil if the records are deleted.
+func (entries *Entries) softDelete(name string) (*Entries, error) {
+	deleted := entries.timestampField("#deleted")
+	if deleted == "" {
+		return nil, nil
+	}
+	update := &Entries{Fields: append([]string{deleted}, entries.Fields...),
+		Update:     []string{deleted + "=NULL"},
+		Criteria:   entries.Criteria,
+		Parameters: entries.Parameters,
+		Values:     make([][]any, 0, len(entries.Values))}
+	for _, f := range entries.Fields {
+		if strings.HasPrefix(f, "%") {
+			return nil, errorrepo.NewError("DB000066", name, f[1:])
+		}
+		update.Update = append(update.Update, f)
+	}
+	now := time.Now()
+	for _, v := range entries.Values {
+		update.Values = append(update.Values, append([]any{now}, v...))
+	}
+	if len(update.Values) == 0 {
+		update.Values = [][]any{{now}}
+	}
+	return update, nil
+}
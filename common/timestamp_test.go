@@ -0,0 +1,81 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timestampRecord struct {
+	ID      string `flynn:"ID:key"`
+	Name    string
+	Created time.Time  `flynn:"created:created"`
+	Updated time.Time  `flynn:"updated:updated"`
+	Deleted *time.Time `flynn:"deleted:deleted"`
+}
+
+func TestTimestamp(t *testing.T) {
+	InitLog(t)
+
+	for tag, tagInfo := range map[string]TagInfo{"created": CreatedTag, "updated": UpdatedTag, "deleted": DeletedTag} {
+		name, info := TagInfoParse("X:" + tag)
+		assert.Equal(t, "X", name)
+		assert.Equal(t, tagInfo, info)
+	}
+
+	record := &timestampRecord{ID: "A"}
+	entries := &Entries{DataStruct: record, Fields: []string{"*"}, Values: [][]any{{record}}}
+	entries.insertTimestamps()
+	assert.False(t, record.Created.IsZero())
+	assert.Equal(t, record.Created, record.Updated)
+	assert.Nil(t, record.Deleted)
+	fields, values, err := entries.InsertValues()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ID", "Name", "created", "updated", "deleted"}, fields)
+	assert.Nil(t, values[0][4])
+
+	created := record.Created
+	entries = &Entries{DataStruct: record, Fields: []string{"Name"}, Update: []string{"ID"}, Values: [][]any{{record}}}
+	entries.updateTimestamps()
+	assert.Equal(t, []string{"Name", "updated"}, entries.Fields)
+	assert.Equal(t, created, record.Created)
+	assert.True(t, record.Updated.After(created) || record.Updated.Equal(created))
+
+	update, err := StructUpdate("Records", record)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Name", "updated", "ID"}, update.Fields)
+
+	remove := &Entries{DataStruct: &timestampRecord{}, Fields: []string{"ID"}, Values: [][]any{{"A"}, {"B"}}}
+	update, err = remove.softDelete("Records")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"deleted", "ID"}, update.Fields)
+	assert.Equal(t, []string{"deleted=NULL", "ID"}, update.Update)
+	assert.Len(t, update.Values, 2)
+	assert.Equal(t, "B", update.Values[1][1])
+	update, err = (&Entries{Fields: []string{"ID"}, Values: [][]any{{"A"}}}).softDelete("Records")
+	assert.NoError(t, err)
+	assert.Nil(t, update)
+	_, err = (&Entries{DataStruct: &timestampRecord{}, Fields: []string{"%Name"}, Values: [][]any{{"A%"}}}).softDelete("Records")
+	assert.Error(t, err)
+
+	q := &Query{TableName: "Records", DataStruct: &timestampRecord{}, Fields: []string{"ID", "Name"}}
+	selectCmd, err := q.Select()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT ID,Name FROM Records tn WHERE deleted IS NULL", selectCmd)
+	q.IncludeDeleted = true
+	selectCmd, err = q.Select()
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT ID,Name FROM Records tn", selectCmd)
+}
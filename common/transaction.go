@@ -0,0 +1,344 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/log"
+)
+
+// DefaultRetries number of retries of WithTransaction if the transaction
+// fails because of serialization failures or deadlocks
+var DefaultRetries = 3
+
+// TxOptions options used to begin a transaction. Retries is the number
+// of retries used by WithTransaction, zero uses DefaultRetries and a
+// negative value disables retries.
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	Retries   int
+}
+
+// SqlOptions transaction options used by database/sql, nil options
+// use the default
+func (opts *TxOptions) SqlOptions() *sql.TxOptions {
+	if opts == nil {
+		return nil
+	}
+	return &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+}
+
+// Transaction transaction of a database driver. All operations are done
+// inside the transaction until Commit or Rollback is called.
+type Transaction interface {
+	QueryContext(ctx context.Context, search *Query, f ResultFunction) (*Result, error)
+	InsertContext(ctx context.Context, name string, insert *Entries) ([][]any, error)
+	UpdateContext(ctx context.Context, name string, insert *Entries) ([][]any, int64, error)
+	DeleteContext(ctx context.Context, name string, remove *Entries) (int64, error)
+	StreamContext(ctx context.Context, search *Query, sf StreamFunction) error
+	BatchContext(ctx context.Context, batch string) error
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// TxDatabase database driver able to provide transaction handles
+type TxDatabase interface {
+	BeginTx(ctx context.Context, opts *TxOptions) (Transaction, error)
+}
+
+// SavepointTransaction transaction able to use savepoints, used by
+// nested calls of WithTransaction
+type SavepointTransaction interface {
+	Savepoint(ctx context.Context, name string) error
+	RollbackTo(ctx context.Context, name string) error
+	Release(ctx context.Context, name string) error
+}
+
+// Retryable transaction or database able to detect errors solved by
+// retrying the transaction, like serialization failures or deadlocks
+type Retryable interface {
+	Retryable(err error) bool
+}
+
+// txContextKey context key of the transaction handle of a registry id
+type txContextKey struct {
+	id RegDbID
+}
+
+// Tx transaction handle. Each handle uses its own connection, so that
+// multiple transactions of one registry id are independent of each
+// other. The handle itself must not be used concurrently.
+type Tx struct {
+	id          RegDbID
+	ctx         context.Context
+	transaction Transaction
+	finished    atomic.Bool
+	savepoints  int
+}
+
+// Begin begin a new transaction handle using the given context and
+// options. The options may be nil to use the database defaults.
+func (id RegDbID) Begin(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	driver, err := searchDataDriverContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	txDriver, ok := driver.(TxDatabase)
+	if !ok {
+		log.Log.Debugf("%s: transaction handle not supported", id)
+		return nil, errorrepo.NewError("DB065535")
+	}
+	transaction, err := txDriver.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	log.Log.Debugf("%s: transaction handle started", id)
+	tx := &Tx{id: id, transaction: transaction}
+	tx.ctx = context.WithValue(ctx, txContextKey{id}, tx)
+	return tx, nil
+}
+
+// ID registry id the transaction belongs to
+func (tx *Tx) ID() RegDbID {
+	return tx.id
+}
+
+// Context context of the transaction. WithTransaction calls using this
+// context are nested into the transaction.
+func (tx *Tx) Context() context.Context {
+	return tx.ctx
+}
+
+// check check that the transaction is not finished
+func (tx *Tx) check(ctx context.Context) error {
+	if tx.finished.Load() {
+		return errorrepo.NewError("DB000046")
+	}
+	if ctx == nil {
+		return errorrepo.NewError("DB000023")
+	}
+	return nil
+}
+
+// Query query database records in the transaction
+func (tx *Tx) Query(search *Query, f ResultFunction) (*Result, error) {
+	return tx.QueryContext(tx.ctx, search, f)
+}
+
+// QueryContext query database records in the transaction using context
+func (tx *Tx) QueryContext(ctx context.Context, search *Query, f ResultFunction) (*Result, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	result, err := tx.transaction.QueryContext(ctx, search, f)
+	return result, ContextError(ctx, err)
+}
+
+// Insert insert records in the transaction
+func (tx *Tx) Insert(name string, insert *Entries) ([][]any, error) {
+	return tx.InsertContext(tx.ctx, name, insert)
+}
+
+// InsertContext insert records in the transaction using context
+func (tx *Tx) InsertContext(ctx context.Context, name string, insert *Entries) ([][]any, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	insert.initVersion()
+	insert.insertTimestamps()
+	returning, err := tx.transaction.InsertContext(ctx, name, insert)
+	return returning, ContextError(ctx, err)
+}
+
+// Update update records in the transaction
+func (tx *Tx) Update(name string, insert *Entries) ([][]any, int64, error) {
+	return tx.UpdateContext(tx.ctx, name, insert)
+}
+
+// UpdateContext update records in the transaction using context
+func (tx *Tx) UpdateContext(ctx context.Context, name string, insert *Entries) ([][]any, int64, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, -1, err
+	}
+	insert.updateTimestamps()
+	returning, rowsAffected, err := tx.transaction.UpdateContext(ctx, name, insert)
+	if err == nil {
+		insert.incrementVersion()
+	}
+	return returning, rowsAffected, ContextError(ctx, err)
+}
+
+// Delete delete records in the transaction
+func (tx *Tx) Delete(name string, remove *Entries) (int64, error) {
+	return tx.DeleteContext(tx.ctx, name, remove)
+}
+
+// DeleteContext delete records in the transaction using context
+func (tx *Tx) DeleteContext(ctx context.Context, name string, remove *Entries) (int64, error) {
+	if err := tx.check(ctx); err != nil {
+		return -1, err
+	}
+	rowsAffected, err := deleteEntries(ctx, tx.transaction, name, remove)
+	return rowsAffected, ContextError(ctx, err)
+}
+
+// Stream streaming data from a field in the transaction
+func (tx *Tx) Stream(search *Query, sf StreamFunction) error {
+	return tx.StreamContext(tx.ctx, search, sf)
+}
+
+// StreamContext streaming data from a field in the transaction using context
+func (tx *Tx) StreamContext(ctx context.Context, search *Query, sf StreamFunction) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	return ContextError(ctx, tx.transaction.StreamContext(ctx, search, sf))
+}
+
+// Batch batch SQL query in the transaction
+func (tx *Tx) Batch(batch string) error {
+	return tx.BatchContext(tx.ctx, batch)
+}
+
+// BatchContext batch SQL query in the transaction using context
+func (tx *Tx) BatchContext(ctx context.Context, batch string) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+	return ContextError(ctx, tx.transaction.BatchContext(ctx, batch))
+}
+
+// Commit commit the transaction and release the connection
+func (tx *Tx) Commit() error {
+	if !tx.finished.CompareAndSwap(false, true) {
+		return errorrepo.NewError("DB000046")
+	}
+	log.Log.Debugf("%s: commit transaction handle", tx.id)
+	return tx.transaction.Commit(tx.ctx)
+}
+
+// Rollback rollback the transaction and release the connection. The
+// rollback of a finished transaction only returns an error, so
+// `defer tx.Rollback()` can be used after begin.
+func (tx *Tx) Rollback() error {
+	if !tx.finished.CompareAndSwap(false, true) {
+		return errorrepo.NewError("DB000046")
+	}
+	log.Log.Debugf("%s: rollback transaction handle", tx.id)
+	// rollback even if the context of the transaction is already done
+	return tx.transaction.Rollback(context.WithoutCancel(tx.ctx))
+}
+
+// WithTransaction call the function inside a transaction. The transaction
+// is committed if the function returns nil and rolled back if the function
+// returns an error or panics. If the context contains a transaction of the
+// registry id, the call is nested using a savepoint.
+func (id RegDbID) WithTransaction(ctx context.Context, fn func(tx *Tx) error) error {
+	return id.WithTransactionOptions(ctx, nil, fn)
+}
+
+// WithTransactionOptions call the function inside a transaction using the
+// transaction options. The whole function is called again if the
+// transaction fails because of serialization failures or deadlocks.
+func (id RegDbID) WithTransactionOptions(ctx context.Context, opts *TxOptions, fn func(tx *Tx) error) error {
+	if ctx == nil {
+		return errorrepo.NewError("DB000023")
+	}
+	if tx, ok := ctx.Value(txContextKey{id}).(*Tx); ok && !tx.finished.Load() {
+		return tx.WithTransaction(fn)
+	}
+	retries := DefaultRetries
+	if opts != nil && opts.Retries != 0 {
+		retries = max(opts.Retries, 0)
+	}
+	for attempt := 0; ; attempt++ {
+		retry, err := id.runTransaction(ctx, opts, fn)
+		if err == nil || !retry || attempt >= retries || ctx.Err() != nil {
+			return err
+		}
+		log.Log.Debugf("%s: retry transaction %d: %v", id, attempt+1, err)
+	}
+}
+
+// runTransaction run the function in a new transaction, returns if the
+// error allows a retry of the transaction
+func (id RegDbID) runTransaction(ctx context.Context, opts *TxOptions, fn func(tx *Tx) error) (bool, error) {
+	tx, err := id.Begin(ctx, opts)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+	err = fn(tx)
+	if err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.Log.Debugf("%s: rollback transaction error: %v", id, rerr)
+		}
+		return tx.retryable(err), err
+	}
+	err = tx.Commit()
+	return tx.retryable(err), err
+}
+
+// retryable check if the error allows a retry of the transaction
+func (tx *Tx) retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	r, ok := tx.transaction.(Retryable)
+	return ok && r.Retryable(err)
+}
+
+// WithTransaction call the function nested in the transaction. Changes of
+// the function are rolled back to a savepoint if the function returns an
+// error or panics. Without savepoint support of the database the function
+// is called inside the transaction and the error is returned only.
+func (tx *Tx) WithTransaction(fn func(tx *Tx) error) error {
+	if err := tx.check(tx.ctx); err != nil {
+		return err
+	}
+	sp, ok := tx.transaction.(SavepointTransaction)
+	if !ok {
+		log.Log.Debugf("%s: savepoints not supported, nest without savepoint", tx.id)
+		return fn(tx)
+	}
+	tx.savepoints++
+	name := fmt.Sprintf("flynn_sp%d", tx.savepoints)
+	err := sp.Savepoint(tx.ctx, name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			sp.RollbackTo(tx.ctx, name)
+			panic(p)
+		}
+	}()
+	err = fn(tx)
+	if err != nil {
+		if rerr := sp.RollbackTo(tx.ctx, name); rerr != nil {
+			log.Log.Debugf("%s: rollback to savepoint %s error: %v", tx.id, name, rerr)
+		}
+		return err
+	}
+	return sp.Release(tx.ctx, name)
+}
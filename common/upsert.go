@@ -0,0 +1,109 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/log"
+)
+
+// Upserter database driver able to insert records or update the existing
+// record with the same conflict key in one statement
+type Upserter interface {
+	UpsertContext(ctx context.Context, name string, upsert *Entries) ([][]any, error)
+}
+
+// Upsert insert records into table or update them if a record with the
+// same conflict key exists. Inserted records get version 1, the update of
+// an existing record increments its version. The created and updated
+// fields are set like by an insert, the update keeps the created field of
+// the existing record.
+func (id RegDbID) Upsert(name string, upsert *Entries) ([][]any, error) {
+	return id.UpsertContext(context.Background(), name, upsert)
+}
+
+// UpsertContext insert or update records in table, the upsert is canceled
+// and rolled back if the context is done
+func (id RegDbID) UpsertContext(ctx context.Context, name string, upsert *Entries) ([][]any, error) {
+	driver, err := searchDataDriverContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	upserter, ok := driver.(Upserter)
+	if !ok {
+		log.Log.Debugf("%s: upsert not supported", id)
+		return nil, errorrepo.NewError("DB065535")
+	}
+	upsert.initVersion()
+	upsert.insertTimestamps()
+	returning, err := upserter.UpsertContext(ctx, name, upsert)
+	return returning, ContextError(ctx, err)
+}
+
+// Upsert insert or update records in the transaction
+func (tx *Tx) Upsert(name string, upsert *Entries) ([][]any, error) {
+	return tx.UpsertContext(tx.ctx, name, upsert)
+}
+
+// UpsertContext insert or update records in the transaction using context
+func (tx *Tx) UpsertContext(ctx context.Context, name string, upsert *Entries) ([][]any, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	upserter, ok := tx.transaction.(Upserter)
+	if !ok {
+		return nil, errorrepo.NewError("DB065535")
+	}
+	upsert.initVersion()
+	upsert.insertTimestamps()
+	returning, err := upserter.UpsertContext(ctx, name, upsert)
+	return returning, ContextError(ctx, err)
+}
+
+// UpsertValues fields, values and conflict key fields of the upsert. The
+// conflict key are the field names in Update or, if Update contains no
+// field names, the key tagged fields of the data structure. The values are
+// created like the values of an insert.
+func (entries *Entries) UpsertValues(name string) (fields []string, values [][]any, keys []string, err error) {
+	for _, u := range entries.Update {
+		if !strings.ContainsAny(u, "=<>") {
+			keys = append(keys, u)
+		}
+	}
+	fields, values, dynamic, err := entries.insertValues()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(keys) == 0 && dynamic != nil {
+		keys = dynamic.RowNames["#key"]
+	}
+	if len(keys) == 0 {
+		return nil, nil, nil, errorrepo.NewError("DB000057", name)
+	}
+	for i, k := range keys {
+		found := false
+		for _, f := range fields {
+			if strings.EqualFold(f, k) {
+				keys[i] = f
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, nil, errorrepo.NewError("DB000058", k, name)
+		}
+	}
+	return fields, values, keys, nil
+}
@@ -0,0 +1,116 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+)
+
+// ErrStaleVersion can be used to check for a StaleVersionError using
+// errors.Is
+var ErrStaleVersion = &StaleVersionError{}
+
+// StaleVersionError the update found no record with the expected version,
+// the record was changed or deleted by another update
+type StaleVersionError struct {
+	TableName string
+}
+
+// Error error message of the stale version error
+func (e *StaleVersionError) Error() string {
+	return errorrepo.NewError("DB000065", e.TableName).Error()
+}
+
+// Is all stale version errors are equal in errors.Is
+func (e *StaleVersionError) Is(target error) bool {
+	_, ok := target.(*StaleVersionError)
+	return ok
+}
+
+// VersionField field used for optimistic locking. It is the Version field
+// or the field of the data structure tagged with `:version`.
+func (entries *Entries) VersionField() string {
+	if entries.Version != "" || entries.DataStruct == nil {
+		return entries.Version
+	}
+	if version := CreateInterface(entries.DataStruct, nil).RowNames["#version"]; len(version) > 0 {
+		return version[0]
+	}
+	return ""
+}
+
+// initVersion set the version of inserted structures without version to 1
+func (entries *Entries) initVersion() {
+	version := entries.VersionField()
+	if version == "" || entries.DataStruct == nil {
+		return
+	}
+	for _, v := range entries.Values {
+		if len(v) > 0 {
+			if field, ok := versionValue(v[0], version); ok && field.IsZero() {
+				field.SetInt(1)
+			}
+		}
+	}
+}
+
+// incrementVersion increment the version of updated structures like the
+// update incremented the version of the records
+func (entries *Entries) incrementVersion() {
+	version := entries.VersionField()
+	if version == "" || entries.DataStruct == nil {
+		return
+	}
+	for _, v := range entries.Values {
+		if len(v) > 0 {
+			incrementVersion(v[0], version)
+		}
+	}
+}
+
+func incrementVersion(s any, version string) {
+	if field, ok := versionValue(s, version); ok {
+		field.SetInt(field.Int() + 1)
+	}
+}
+
+// versionValue settable integer version field of the structure pointer
+func versionValue(s any, version string) (reflect.Value, bool) {
+	value := reflect.ValueOf(s)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	value = value.Elem()
+	for i := 0; i < value.NumField(); i++ {
+		sf := value.Type().Field(i)
+		name, tagInfo := TagInfoParse(sf.Tag.Get(TagName))
+		if tagInfo != VersionTag {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		field := value.Field(i)
+		if !strings.EqualFold(name, version) || !field.CanSet() {
+			return reflect.Value{}, false
+		}
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return field, true
+		}
+		return reflect.Value{}, false
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,60 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type versionRecord struct {
+	ID      string `flynn:"ID:key"`
+	Name    string
+	Version int64 `flynn:"version:version"`
+}
+
+func TestVersion(t *testing.T) {
+	InitLog(t)
+
+	name, tagInfo := TagInfoParse("version:version")
+	assert.Equal(t, "version", name)
+	assert.Equal(t, VersionTag, tagInfo)
+
+	entries := &Entries{DataStruct: &versionRecord{}, Fields: []string{"*"},
+		Values: [][]any{{&versionRecord{ID: "A"}}, {&versionRecord{ID: "B", Version: 3}}}}
+	assert.Equal(t, "version", entries.VersionField())
+	fields, _, err := entries.InsertValues()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ID", "Name", "version"}, fields)
+
+	entries.initVersion()
+	assert.Equal(t, int64(1), entries.Values[0][0].(*versionRecord).Version)
+	assert.Equal(t, int64(3), entries.Values[1][0].(*versionRecord).Version)
+	entries.incrementVersion()
+	assert.Equal(t, int64(2), entries.Values[0][0].(*versionRecord).Version)
+	assert.Equal(t, int64(4), entries.Values[1][0].(*versionRecord).Version)
+
+	assert.Equal(t, "", (&Entries{DataStruct: &structOrder{}}).VersionField())
+	assert.Equal(t, "Counter", (&Entries{Version: "Counter"}).VersionField())
+
+	update, err := StructUpdate("Records", &versionRecord{ID: "A", Version: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "version", update.Version)
+	assert.Equal(t, []string{"Name", "version", "ID"}, update.Fields)
+
+	err = fmt.Errorf("update failed: %w", &StaleVersionError{TableName: "Records"})
+	assert.True(t, errors.Is(err, ErrStaleVersion))
+	assert.False(t, errors.Is(err, ErrNotFound))
+}
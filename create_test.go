@@ -136,22 +136,26 @@ func (s *SubField) ParseData(sub []byte) error {
 	return nil
 }
 
+// getTestTargets list of database targets used by the tests. The targets
+// can be restricted with the TEST_TARGETS environment variable, like
+// TEST_TARGETS=sqlite to test without any external database service.
 func getTestTargets(t *testing.T) (targets []*target) {
-	url, err := mysqlTarget(t)
-	if !assert.NoError(t, err) {
-		return nil
-	}
-	targets = append(targets, &target{"mysql", url})
-	url, err = postgresTarget(t)
-	if !assert.NoError(t, err) {
-		return nil
-	}
-	targets = append(targets, &target{"postgres", url})
-	url, err = adabasTarget(t)
-	if !assert.NoError(t, err) {
-		return nil
+	targetFct := []struct {
+		layer string
+		fct   func(t *testing.T) (string, error)
+	}{{"sqlite", sqliteTarget}, {"mysql", mysqlTarget},
+		{"postgres", postgresTarget}, {"adabas", adabasTarget}}
+	selected := os.Getenv("TEST_TARGETS")
+	for _, tf := range targetFct {
+		if selected != "" && !slices.Contains(strings.Split(selected, ","), tf.layer) {
+			continue
+		}
+		url, err := tf.fct(t)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		targets = append(targets, &target{tf.layer, url})
 	}
-	targets = append(targets, &target{"adabas", url})
 	return
 }
 
@@ -383,7 +387,11 @@ func createStruct(t *testing.T, target *target) error {
 	assert.NoError(t, err, "on "+target.layer)
 	assert.True(t, found, "on "+target.layer)
 
-	err = id.Batch("TRUNCATE " + testCreationTableStruct)
+	truncateCmd := "TRUNCATE "
+	if target.layer == "sqlite" {
+		truncateCmd = "DELETE FROM "
+	}
+	err = id.Batch(truncateCmd + testCreationTableStruct)
 	if !assert.NoError(t, err) {
 		return err
 	}
@@ -636,8 +644,9 @@ func TestCreateAndAdapt(t *testing.T) {
 
 func testAdapt(t *testing.T, target *target, columns []*common.Column) {
 	defer testWg.Done()
-	// Problem that ADABAS does not support adaption and mysql has a cachning problem
-	if target.layer == "adabas" || target.layer == "mysql" {
+	// Problem that ADABAS does not support adaption and mysql has a cachning problem,
+	// the new SQLite test database does not contain the table, see TestSqliteAdapt
+	if target.layer == "adabas" || target.layer == "mysql" || target.layer == "sqlite" {
 		return
 	}
 	fmt.Println("Working at string creation on target " + target.layer)
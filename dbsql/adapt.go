@@ -0,0 +1,532 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package dbsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+// SchemaDB database driver providing the database/sql layer and URL used
+// to read and change the table definitions
+type SchemaDB interface {
+	ID() common.RegDbID
+	Reference() (string, string)
+	ByteArrayAvailable() bool
+}
+
+// tableColumn column definition of the structure or of the table
+type tableColumn struct {
+	name       string
+	definition string
+	typeText   string
+	dataType   string
+	length     int
+	digits     int
+	// nullable is nil if the definition does not specify the nullability
+	nullable *bool
+	// defaultValue is nil if the definition does not specify a default
+	defaultValue *string
+	generated    bool
+}
+
+// columnDiff differences of the column in the table and in the structure
+type columnDiff struct {
+	typeChanged    bool
+	nullChanged    bool
+	defaultChanged bool
+}
+
+// AdaptTable adapt the table to the structure or columns. Only the missing
+// columns are added, all other changes of the plan are skipped and need to
+// be applied using ApplyAdaptPlan.
+func AdaptTable(ctx context.Context, db SchemaDB, driver common.ReferenceType, name string, col any) error {
+	log.Log.Debugf("%s: Adapt SQL table", db.ID())
+	plan, err := PlanAdaptTable(ctx, db, driver, name, col)
+	if err != nil {
+		return err
+	}
+	statements := make([]string, 0)
+	for _, c := range plan.Changes {
+		if c.Type != common.AddColumn {
+			log.Log.Debugf("Skip change %s of column %s: %v", c.Type, c.Column, c.Reasons)
+			continue
+		}
+		statements = append(statements, c.Statements...)
+	}
+	layer, url := db.Reference()
+	dbOpen, err := sql.Open(layer, url)
+	if err != nil {
+		return err
+	}
+	defer dbOpen.Close()
+	for _, s := range statements {
+		log.Log.Debugf("Adapt cmd %s", s)
+		_, err = dbOpen.ExecContext(ctx, s)
+		if err != nil {
+			log.Log.Errorf("Error returned by SQL: %v", err)
+			return err
+		}
+	}
+	log.Log.Debugf("Table adapted")
+	return nil
+}
+
+// PlanAdaptTable plan the changes adapting the table to the structure or
+// columns. Added, dropped and retyped columns, length changes and, if the
+// structure defines them, nullability and defaults are detected.
+func PlanAdaptTable(ctx context.Context, db SchemaDB, driver common.ReferenceType, name string, col any) (*common.AdaptPlan, error) {
+	desired, err := definedColumns(db.ByteArrayAvailable(), col)
+	if err != nil {
+		return nil, err
+	}
+	layer, url := db.Reference()
+	dbOpen, err := sql.Open(layer, url)
+	if err != nil {
+		return nil, err
+	}
+	defer dbOpen.Close()
+	current, err := readColumns(ctx, dbOpen, driver, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(current) == 0 {
+		return nil, errorrepo.NewError("DB000074", name)
+	}
+	return adaptPlan(driver, name, desired, current), nil
+}
+
+// adaptPlan compare the columns of the structure and of the table
+func adaptPlan(driver common.ReferenceType, name string, desired, current []*tableColumn) *common.AdaptPlan {
+	plan := &common.AdaptPlan{TableName: name, Changes: make([]*common.AdaptChange, 0)}
+	find := func(columns []*tableColumn, name string) *tableColumn {
+		for _, c := range columns {
+			if strings.EqualFold(c.name, name) {
+				return c
+			}
+		}
+		return nil
+	}
+	for _, d := range desired {
+		c := find(current, d.name)
+		if c == nil {
+			add := "ALTER TABLE " + name + " ADD COLUMN " + d.definition
+			if driver == common.OracleType {
+				add = "ALTER TABLE " + name + " ADD " + d.definition
+			}
+			plan.Changes = append(plan.Changes, &common.AdaptChange{Type: common.AddColumn, Column: d.name,
+				To: d.definition, Statements: []string{add}})
+			continue
+		}
+		change, diff := compareColumn(driver, c, d)
+		if change == nil {
+			continue
+		}
+		statements, ok := alterStatements(driver, name, c, d, diff)
+		if !ok {
+			log.Log.Debugf("Change %v of column %s not supported by %s", change.Reasons, d.name, driver)
+			change.Unsupported = true
+		}
+		change.Statements = statements
+		plan.Changes = append(plan.Changes, change)
+	}
+	for _, c := range current {
+		if find(desired, c.name) == nil {
+			plan.Changes = append(plan.Changes, &common.AdaptChange{Type: common.DropColumn, Column: c.name,
+				From: c.definition, Destructive: true,
+				Statements: []string{"ALTER TABLE " + name + " DROP COLUMN " + c.name}})
+		}
+	}
+	return plan
+}
+
+// compareColumn change altering the column of the table to the column of
+// the structure, nil if they are equal
+func compareColumn(driver common.ReferenceType, current, desired *tableColumn) (*common.AdaptChange, columnDiff) {
+	diff := columnDiff{}
+	change := &common.AdaptChange{Type: common.AlterColumn, Column: desired.name,
+		From: current.definition, To: desired.definition}
+	currentType, desiredType := current.dataType, desired.dataType
+	if driver == common.OracleType {
+		// Oracle stores all integer types as NUMBER
+		currentType = strings.Replace(currentType, "BIGINT", "INTEGER", 1)
+		desiredType = strings.Replace(desiredType, "BIGINT", "INTEGER", 1)
+	}
+	switch {
+	case currentType != desiredType:
+		change.Reasons = append(change.Reasons, "type "+current.typeText+" -> "+desired.typeText)
+		change.Destructive = !widenedType(currentType, desiredType)
+	case desiredType == "DECIMAL" && current.length > 0 && desired.length > 0 &&
+		(current.length != desired.length || current.digits != desired.digits):
+		change.Reasons = append(change.Reasons, "precision "+current.typeText+" -> "+desired.typeText)
+		change.Destructive = desired.length-desired.digits < current.length-current.digits ||
+			desired.digits < current.digits
+	case current.length > 0 && desired.length > 0 && current.length != desired.length:
+		change.Reasons = append(change.Reasons, "length "+strconv.Itoa(current.length)+" -> "+strconv.Itoa(desired.length))
+		change.Destructive = desired.length < current.length
+	}
+	diff.typeChanged = len(change.Reasons) > 0
+	if desired.nullable != nil && *desired.nullable != *current.nullable {
+		diff.nullChanged = true
+		if *desired.nullable {
+			change.Reasons = append(change.Reasons, "NULL allowed")
+		} else {
+			change.Reasons = append(change.Reasons, "NOT NULL")
+			change.Destructive = true
+		}
+	}
+	if desired.defaultValue != nil && !desired.generated &&
+		(current.defaultValue == nil || normalizeDefault(*current.defaultValue) != normalizeDefault(*desired.defaultValue)) {
+		diff.defaultChanged = true
+		change.Reasons = append(change.Reasons, "default "+*desired.defaultValue)
+	}
+	if len(change.Reasons) == 0 {
+		return nil, diff
+	}
+	return change, diff
+}
+
+// widenedType check if the type change keeps all values
+func widenedType(from, to string) bool {
+	switch from + ">" + to {
+	case "INTEGER>BIGINT", "INTEGER>DECIMAL", "BIGINT>DECIMAL", "CHAR>VARCHAR", "CHAR>TEXT", "VARCHAR>TEXT":
+		return true
+	}
+	return false
+}
+
+// alterStatements statements altering the column in the SQL dialect of
+// the driver, false if the driver cannot alter columns
+func alterStatements(driver common.ReferenceType, name string, current, desired *tableColumn,
+	diff columnDiff) ([]string, bool) {
+	switch driver {
+	case common.PostgresType:
+		parts := make([]string, 0)
+		if diff.typeChanged {
+			parts = append(parts, "ALTER COLUMN "+desired.name+" TYPE "+desired.typeText)
+		}
+		if diff.nullChanged {
+			if *desired.nullable {
+				parts = append(parts, "ALTER COLUMN "+desired.name+" DROP NOT NULL")
+			} else {
+				parts = append(parts, "ALTER COLUMN "+desired.name+" SET NOT NULL")
+			}
+		}
+		if diff.defaultChanged {
+			parts = append(parts, "ALTER COLUMN "+desired.name+" SET DEFAULT "+*desired.defaultValue)
+		}
+		return []string{"ALTER TABLE " + name + " " + strings.Join(parts, ", ")}, true
+	case common.MysqlType:
+		// MODIFY redefines the whole column
+		column := desired.name + " " + desired.typeText
+		if (desired.nullable != nil && !*desired.nullable) || (desired.nullable == nil && !*current.nullable) {
+			column += " NOT NULL"
+		}
+		switch {
+		case desired.defaultValue != nil:
+			column += " DEFAULT " + *desired.defaultValue
+		case current.defaultValue != nil:
+			column += " DEFAULT " + *current.defaultValue
+		}
+		return []string{"ALTER TABLE " + name + " MODIFY COLUMN " + column}, true
+	case common.OracleType:
+		column := desired.name
+		if diff.typeChanged {
+			column += " " + desired.typeText
+		}
+		if diff.defaultChanged {
+			column += " DEFAULT " + *desired.defaultValue
+		}
+		if diff.nullChanged {
+			if *desired.nullable {
+				column += " NULL"
+			} else {
+				column += " NOT NULL"
+			}
+		}
+		return []string{"ALTER TABLE " + name + " MODIFY (" + column + ")"}, true
+	}
+	return nil, false
+}
+
+// normalizeDefault default expression without casts, parentheses and
+// quotes to compare the defaults of the table and of the structure
+func normalizeDefault(value string) string {
+	value = strings.TrimSpace(value)
+	if index := strings.Index(value, "::"); index > 0 {
+		value = value[:index]
+	}
+	for len(value) > 1 && value[0] == '(' && value[len(value)-1] == ')' {
+		value = strings.TrimSpace(value[1 : len(value)-1])
+	}
+	return strings.ToUpper(strings.Trim(value, "'"))
+}
+
+// definedColumns column definitions of the structure or columns
+func definedColumns(baAvailable bool, col any) ([]*tableColumn, error) {
+	var definitions []string
+	switch columns := col.(type) {
+	case []*common.Column:
+		for _, c := range columns {
+			var buffer bytes.Buffer
+			CreateTableByColumn(&buffer, baAvailable, c)
+			definitions = append(definitions, buffer.String())
+		}
+	default:
+		s, err := SqlDataType(baAvailable, col, nil)
+		if err != nil {
+			return nil, err
+		}
+		definitions = splitDefinitions(s)
+	}
+	desired := make([]*tableColumn, 0, len(definitions))
+	for _, d := range definitions {
+		desired = append(desired, parseDefinition(d))
+	}
+	return desired, nil
+}
+
+// splitDefinitions split the column definitions at the commas outside of
+// parentheses
+func splitDefinitions(s string) []string {
+	definitions := make([]string, 0)
+	depth := 0
+	start := 0
+	add := func(end int) {
+		if d := strings.TrimSpace(s[start:end]); d != "" {
+			definitions = append(definitions, d)
+		}
+	}
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				add(i)
+				start = i + 1
+			}
+		}
+	}
+	add(len(s))
+	return definitions
+}
+
+// parseDefinition parse a column definition like `Name VARCHAR(10) NOT NULL`
+func parseDefinition(definition string) *tableColumn {
+	name, rest, _ := strings.Cut(strings.TrimSpace(definition), " ")
+	rest = strings.TrimSpace(rest)
+	end := strings.IndexByte(rest, ' ')
+	if open := strings.IndexByte(rest, '('); open >= 0 && (end < 0 || open < end) {
+		end = strings.IndexByte(rest, ')') + 1
+	}
+	if end < 0 {
+		end = len(rest)
+	}
+	column := parseType(rest[:end])
+	column.name = name
+	column.definition = definition
+	tail := rest[end:]
+	constraints := " " + strings.ToUpper(tail) + " "
+	switch {
+	case strings.Contains(constraints, " NOT NULL ") || strings.Contains(constraints, " PRIMARY KEY "):
+		column.nullable = new(bool)
+	case strings.Contains(constraints, " NULL "):
+		column.nullable = new(bool)
+		*column.nullable = true
+	}
+	if index := strings.Index(constraints, " DEFAULT "); index >= 0 && index+8 <= len(tail) {
+		value := defaultExpression(tail[index+8:])
+		column.defaultValue = &value
+	}
+	column.generated = column.dataType == "SERIAL" || strings.Contains(constraints, "IDENTITY") ||
+		strings.Contains(constraints, "AUTO_INCREMENT")
+	if column.dataType == "SERIAL" {
+		column.dataType = "INTEGER"
+	}
+	return column
+}
+
+// defaultExpression expression after DEFAULT up to the next constraint
+func defaultExpression(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return s
+	}
+	switch s[0] {
+	case '\'':
+		if end := strings.IndexByte(s[1:], '\''); end >= 0 {
+			return s[:end+2]
+		}
+	case '(':
+		depth := 0
+		for i, c := range s {
+			switch c {
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return s[:i+1]
+				}
+			}
+		}
+	}
+	value, _, _ := strings.Cut(s, " ")
+	return value
+}
+
+// parseType parse the type like `VARCHAR(255)` or `DECIMAL(10,5)`
+func parseType(typeText string) *tableColumn {
+	column := &tableColumn{typeText: typeText}
+	typeName, args, _ := strings.Cut(typeText, "(")
+	if args != "" {
+		args = strings.TrimSuffix(strings.TrimSpace(args), ")")
+		length, digits, _ := strings.Cut(args, ",")
+		column.length, _ = strconv.Atoi(strings.TrimSpace(length))
+		column.digits, _ = strconv.Atoi(strings.TrimSpace(digits))
+	}
+	column.dataType = canonicalType(typeName)
+	if column.dataType != "DECIMAL" && column.dataType != "VARCHAR" &&
+		column.dataType != "CHAR" && column.dataType != "BINARY" {
+		column.length = 0
+		column.digits = 0
+	}
+	return column
+}
+
+// canonicalType type name used to compare the types of different SQL
+// dialects
+func canonicalType(typeName string) string {
+	typeName = strings.ToUpper(strings.TrimSpace(typeName))
+	if index := strings.IndexByte(typeName, '('); index > 0 {
+		typeName = typeName[:index]
+	}
+	switch {
+	case strings.HasPrefix(typeName, "TIMESTAMP"), typeName == "DATETIME":
+		return "TIMESTAMP"
+	}
+	switch typeName {
+	case "VARCHAR", "CHARACTER VARYING", "VARCHAR2", "NVARCHAR2", "NVARCHAR":
+		return "VARCHAR"
+	case "CHAR", "CHARACTER", "BPCHAR", "NCHAR":
+		return "CHAR"
+	case "INTEGER", "INT", "INT4", "SMALLINT", "INT2", "MEDIUMINT":
+		return "INTEGER"
+	case "BIGINT", "INT8", "BIGSERIAL":
+		return "BIGINT"
+	case "NUMERIC", "DECIMAL", "NUMBER":
+		return "DECIMAL"
+	case "BOOL", "BOOLEAN", "TINYINT":
+		return "BOOLEAN"
+	case "TEXT", "CLOB", "LONGTEXT", "MEDIUMTEXT":
+		return "TEXT"
+	case "BYTEA", "BLOB", "LONGBLOB", "MEDIUMBLOB":
+		return "BLOB"
+	case "BINARY", "VARBINARY", "RAW":
+		return "BINARY"
+	case "REAL", "FLOAT", "DOUBLE", "DOUBLE PRECISION", "FLOAT4", "FLOAT8":
+		return "FLOAT"
+	}
+	return typeName
+}
+
+// columnQuery query of the column definitions of the table, the table
+// name is compared case-insensitive
+func columnQuery(driver common.ReferenceType) (string, error) {
+	switch driver {
+	case common.PostgresType:
+		return `SELECT column_name, data_type, character_maximum_length, numeric_precision, numeric_scale,
+			is_nullable, column_default FROM information_schema.columns
+			WHERE table_schema = current_schema() AND lower(table_name) = lower($1) ORDER BY ordinal_position`, nil
+	case common.MysqlType:
+		return `SELECT column_name, data_type, character_maximum_length, numeric_precision, numeric_scale,
+			is_nullable, column_default FROM information_schema.columns
+			WHERE table_schema = DATABASE() AND LOWER(table_name) = LOWER(?) ORDER BY ordinal_position`, nil
+	case common.OracleType:
+		return `SELECT column_name, data_type, char_length, data_precision, data_scale,
+			CASE nullable WHEN 'Y' THEN 'YES' ELSE 'NO' END, data_default FROM all_tab_columns
+			WHERE owner = SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') AND UPPER(table_name) = UPPER(:1)
+			ORDER BY column_id`, nil
+	case common.SqliteType:
+		return `SELECT name, type, NULL, NULL, NULL, CASE "notnull" WHEN 0 THEN 'YES' ELSE 'NO' END,
+			dflt_value FROM pragma_table_info(?) ORDER BY cid`, nil
+	}
+	return "", errorrepo.NewError("DB065535")
+}
+
+// readColumns column definitions of the table in the database
+func readColumns(ctx context.Context, db *sql.DB, driver common.ReferenceType, name string) ([]*tableColumn, error) {
+	query, err := columnQuery(driver)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := make([]*tableColumn, 0)
+	for rows.Next() {
+		var columnName, dataType, nullable string
+		var length, precision, scale sql.NullInt64
+		var defaultValue sql.NullString
+		err = rows.Scan(&columnName, &dataType, &length, &precision, &scale, &nullable, &defaultValue)
+		if err != nil {
+			return nil, err
+		}
+		column := parseType(dataType)
+		column.name = columnName
+		switch {
+		case column.dataType == "DECIMAL" && driver == common.OracleType && !precision.Valid && scale.Int64 == 0:
+			column.dataType = "INTEGER"
+		case column.dataType == "DECIMAL" && precision.Valid:
+			column.length, column.digits = int(precision.Int64), int(scale.Int64)
+		case length.Valid && column.length == 0:
+			column.length = int(length.Int64)
+		}
+		column.typeText = column.dataType
+		if column.length > 0 {
+			column.typeText += "(" + strconv.Itoa(column.length)
+			if column.dataType == "DECIMAL" {
+				column.typeText += "," + strconv.Itoa(column.digits)
+			}
+			column.typeText += ")"
+		}
+		column.nullable = new(bool)
+		*column.nullable = strings.EqualFold(nullable, "YES")
+		column.definition = columnName + " " + column.typeText
+		if !*column.nullable {
+			column.definition += " NOT NULL"
+		}
+		if defaultValue.Valid && strings.TrimSpace(defaultValue.String) != "" {
+			value := strings.TrimSpace(defaultValue.String)
+			column.defaultValue = &value
+			column.definition += " DEFAULT " + value
+		}
+		columns = append(columns, column)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	log.Log.Debugf("Table %s has %d columns", name, len(columns))
+	return columns, nil
+}
@@ -0,0 +1,135 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package dbsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+)
+
+type adaptStruct struct {
+	ID      uint64 `flynn:"ID:IDENTITY(1, 1)"`
+	Name    string `flynn:"Name:NOT NULL DEFAULT 'none':100"`
+	Amount  float64
+	Counter int64
+	Street  string
+}
+
+func liveColumns(definitions ...string) []*tableColumn {
+	columns := make([]*tableColumn, 0, len(definitions))
+	for _, d := range definitions {
+		c := parseDefinition(d)
+		if c.nullable == nil {
+			c.nullable = new(bool)
+			*c.nullable = true
+		}
+		columns = append(columns, c)
+	}
+	return columns
+}
+
+func TestAdaptDefinitions(t *testing.T) {
+	InitLog(t)
+
+	assert.Equal(t, []string{"A VARCHAR(10)", "B DECIMAL(10,5)", "ID NUMERIC(20,0) IDENTITY(1, 1)"},
+		splitDefinitions("A VARCHAR(10), B DECIMAL(10,5),, ID NUMERIC(20,0) IDENTITY(1, 1)"))
+
+	c := parseDefinition("Name VARCHAR(100) NOT NULL DEFAULT 'a b'")
+	assert.Equal(t, "Name", c.name)
+	assert.Equal(t, "VARCHAR", c.dataType)
+	assert.Equal(t, 100, c.length)
+	assert.False(t, *c.nullable)
+	assert.Equal(t, "'a b'", *c.defaultValue)
+	c = parseDefinition("Deleted TIMESTAMP NULL")
+	assert.Equal(t, "TIMESTAMP", c.dataType)
+	assert.True(t, *c.nullable)
+	assert.Nil(t, c.defaultValue)
+	c = parseDefinition("Amount DECIMAL(10,5)")
+	assert.Equal(t, []int{10, 5}, []int{c.length, c.digits})
+	assert.Nil(t, c.nullable)
+	c = parseDefinition("ID SERIAL UNIQUE")
+	assert.Equal(t, "INTEGER", c.dataType)
+	assert.True(t, c.generated)
+
+	assert.Equal(t, "VARCHAR", canonicalType("character varying"))
+	assert.Equal(t, "TIMESTAMP", canonicalType("timestamp without time zone"))
+	assert.Equal(t, "TIMESTAMP", canonicalType("TIMESTAMP(6)"))
+	assert.Equal(t, "DECIMAL", canonicalType("NUMBER"))
+	assert.Equal(t, "NONE", normalizeDefault("'none'::character varying"))
+	assert.Equal(t, "5", normalizeDefault("((5))"))
+
+	desired, err := definedColumns(false, &adaptStruct{})
+	assert.NoError(t, err)
+	assert.Len(t, desired, 5)
+	desired, err = definedColumns(false, []*common.Column{{Name: "Name", DataType: common.Alpha, Length: 20}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Name VARCHAR(20)", desired[0].definition)
+}
+
+func TestAdaptPlan(t *testing.T) {
+	InitLog(t)
+
+	desired, err := definedColumns(false, &adaptStruct{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	current := liveColumns("ID NUMERIC(20,0) NOT NULL", "Name VARCHAR(50)", "Amount DECIMAL(12,5)",
+		"Counter INTEGER", "Old VARCHAR(10)")
+
+	plan := adaptPlan(common.PostgresType, "Records", desired, current)
+	if !assert.Len(t, plan.Changes, 4) {
+		return
+	}
+	assert.Equal(t, common.AlterColumn, plan.Changes[0].Type)
+	assert.Equal(t, "Name", plan.Changes[0].Column)
+	assert.Equal(t, []string{"length 50 -> 100", "NOT NULL", "default 'none'"}, plan.Changes[0].Reasons)
+	assert.True(t, plan.Changes[0].Destructive)
+	assert.Equal(t, []string{"ALTER TABLE Records ALTER COLUMN Name TYPE VARCHAR(100), " +
+		"ALTER COLUMN Name SET NOT NULL, ALTER COLUMN Name SET DEFAULT 'none'"}, plan.Changes[0].Statements)
+	assert.Equal(t, "Amount", plan.Changes[1].Column)
+	assert.True(t, plan.Changes[1].Destructive)
+	assert.Equal(t, "Street", plan.Changes[2].Column)
+	assert.Equal(t, common.AddColumn, plan.Changes[2].Type)
+	assert.False(t, plan.Changes[2].Destructive)
+	assert.Equal(t, common.DropColumn, plan.Changes[3].Type)
+	assert.True(t, plan.Destructive())
+	assert.Equal(t, []string{"ALTER TABLE Records ADD COLUMN Street VARCHAR(255)"}, plan.Statements(false))
+	assert.Contains(t, plan.SQL(), "ALTER TABLE Records DROP COLUMN Old;\n")
+
+	plan = adaptPlan(common.MysqlType, "Records", desired[1:2], current[1:2])
+	assert.Equal(t, []string{"ALTER TABLE Records MODIFY COLUMN Name VARCHAR(100) NOT NULL DEFAULT 'none'"},
+		plan.Changes[0].Statements)
+	plan = adaptPlan(common.OracleType, "Records", desired[1:2], current[1:2])
+	assert.Equal(t, []string{"ALTER TABLE Records MODIFY (Name VARCHAR(100) DEFAULT 'none' NOT NULL)"},
+		plan.Changes[0].Statements)
+
+	// SQLite cannot alter columns, the change is only reported
+	plan = adaptPlan(common.SqliteType, "Records", desired[1:3], current[1:2])
+	if assert.Len(t, plan.Changes, 2) {
+		assert.True(t, plan.Changes[0].Unsupported)
+		assert.Empty(t, plan.Changes[0].Statements)
+		assert.False(t, plan.Destructive())
+		assert.Equal(t, []string{"ALTER TABLE Records ADD COLUMN Amount DECIMAL(10,5)"}, plan.Statements(true))
+	}
+
+	// widened and equal columns
+	plan = adaptPlan(common.PostgresType, "Records", liveColumns("Counter BIGINT", "Name VARCHAR(10) NULL"),
+		liveColumns("Counter INTEGER", "Name VARCHAR(10)"))
+	if assert.Len(t, plan.Changes, 1) {
+		assert.False(t, plan.Changes[0].Destructive)
+		assert.Equal(t, []string{"ALTER TABLE Records ALTER COLUMN Counter TYPE BIGINT"}, plan.Changes[0].Statements)
+	}
+	plan = adaptPlan(common.OracleType, "Records", liveColumns("Counter BIGINT"), liveColumns("Counter INTEGER"))
+	assert.Empty(t, plan.Changes)
+}
@@ -39,80 +39,49 @@ type DBsql interface {
 	IsTransaction() bool
 }
 
+// CreateTable create the table out of the structure or columns including
+// the primary key, unique and foreign key constraints and the indexes
 func CreateTable(dbsql DBsql, name string, col any) error {
+	return CreateTableContext(context.Background(), dbsql, name, col)
+}
+
+// CreateTableContext create the table using context
+func CreateTableContext(ctx context.Context, dbsql DBsql, name string, col any) error {
 	log.Log.Debugf("%s: Create SQL table", dbsql.ID())
-	layer, url := dbsql.Reference()
-	db, err := sql.Open(layer, url)
-	if err != nil {
-		return err
+	driver := common.NoType
+	if named, ok := dbsql.(interface{ DriverName() string }); ok {
+		driver = common.ParseTypeName(named.DriverName())
 	}
-	defer db.Close()
-	createCmd := `CREATE TABLE ` + name + ` (`
-	switch columns := col.(type) {
-	case []*common.Column:
-		createCmd += CreateTableByColumns(dbsql.ByteArrayAvailable(), columns)
-	default:
-		c, err := CreateTableByStruct(dbsql.ByteArrayAvailable(), col)
-		if err != nil {
-			log.Log.Errorf("Error parsing structure: %v", err)
-			return err
-		}
-		createCmd += c
-	}
-	createCmd += ")"
-	log.Log.Debugf("Create cmd %s", createCmd)
-	_, err = db.Query(createCmd)
+	statements, err := CreateTableStatements(driver, dbsql.ByteArrayAvailable(), name, col)
 	if err != nil {
-		log.Log.Errorf("Error returned by SQL: %v", err)
+		log.Log.Errorf("Error parsing structure: %v", err)
 		return err
 	}
-	log.Log.Debugf("Table created, waiting ....")
-	//time.Sleep(60 * time.Second)
-	log.Log.Debugf("Table created")
-	return nil
-}
-
-// AdaptTable adapt table to new struct
-func AdaptTable(dbsql DBsql, name string, col any) error {
-
-	log.Log.Debugf("%s: Adapt SQL table", dbsql.ID())
 	layer, url := dbsql.Reference()
 	db, err := sql.Open(layer, url)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
-
-	columnCurrent, err := dbsql.ID().GetTableColumn(name)
-	if err != nil {
-		return err
-	}
-	if columns, ok := col.([]*common.Column); ok {
-		adaptCmd := `ADAPT TABLE ` + name + ` ADD `
-		adaptCmd += CreateTableByColumns(dbsql.ByteArrayAvailable(), columns)
-		fmt.Println(adaptCmd)
-		return nil
-	}
-	log.Log.Debugf("Got columns: %v", columnCurrent)
-	columStruct, err := SqlDataType(true, col, columnCurrent)
-	if err != nil {
-		return err
-	}
-	for _, f := range strings.Split(columStruct, ",") {
-
-		adaptCmd := `ALTER TABLE ` + name + ` ADD ` + f
-		log.Log.Debugf("Create cmd %s", adaptCmd)
-		_, err = db.Query(adaptCmd)
+	for _, createCmd := range statements {
+		log.Log.Debugf("Create cmd %s", createCmd)
+		_, err = db.ExecContext(ctx, createCmd)
 		if err != nil {
 			log.Log.Errorf("Error returned by SQL: %v", err)
 			return err
 		}
 	}
-	log.Log.Debugf("Table adapted")
+	log.Log.Debugf("Table created")
 	return nil
 }
 
+// DeleteTable drop the table
 func DeleteTable(dbsql DBsql, name string) error {
+	return DeleteTableContext(context.Background(), dbsql, name)
+}
+
+// DeleteTableContext drop the table using context
+func DeleteTableContext(ctx context.Context, dbsql DBsql, name string) error {
 	layer, url := dbsql.Reference()
 	db, err := sql.Open(layer, url)
 	if err != nil {
@@ -120,7 +89,7 @@ func DeleteTable(dbsql DBsql, name string) error {
 	}
 	defer db.Close()
 
-	_, err = db.Query("DROP TABLE " + name)
+	_, err = db.ExecContext(ctx, "DROP TABLE "+name)
 	if err != nil {
 		log.Log.Debugf("Drop table error: %v", err)
 		return err
@@ -158,6 +127,9 @@ func CreateTableByColumn(buffer *bytes.Buffer, baAvailable bool, c *common.Colum
 			buffer.WriteString(c.DataType.SqlType())
 		}
 	}
+	if c.Default != "" {
+		buffer.WriteString(" DEFAULT " + c.Default)
+	}
 }
 
 func CreateTableByMaps(baAvailable bool, columns map[string]interface{}) string {
@@ -186,6 +158,11 @@ func CreateTableByStruct(baAvailable bool, columns any) (string, error) {
 }
 
 func Batch(dbsql DBsql, batch string) error {
+	return BatchContext(context.Background(), dbsql, batch)
+}
+
+// BatchContext batch SQL using context
+func BatchContext(ctx context.Context, dbsql DBsql, batch string) error {
 	layer, url := dbsql.Reference()
 	db, err := sql.Open(layer, url)
 	if err != nil {
@@ -193,7 +170,7 @@ func Batch(dbsql DBsql, batch string) error {
 	}
 	defer db.Close()
 	// Query batch SQL
-	rows, err := db.Query(batch)
+	rows, err := db.QueryContext(ctx, batch)
 	if err != nil {
 		return err
 	}
@@ -207,6 +184,11 @@ func Batch(dbsql DBsql, batch string) error {
 
 // BatchSelect batch SQL query in table with values returned
 func BatchSelect(dbsql DBsql, batch string) ([][]interface{}, error) {
+	return BatchSelectContext(context.Background(), dbsql, batch)
+}
+
+// BatchSelectContext batch SQL query in table with values returned using context
+func BatchSelectContext(ctx context.Context, dbsql DBsql, batch string) ([][]interface{}, error) {
 	layer, url := dbsql.Reference()
 	db, err := sql.Open(layer, url)
 	if err != nil {
@@ -214,7 +196,7 @@ func BatchSelect(dbsql DBsql, batch string) ([][]interface{}, error) {
 	}
 	defer db.Close()
 	// Query batch SQL
-	rows, err := db.Query(batch)
+	rows, err := db.QueryContext(ctx, batch)
 	if err != nil {
 		return nil, err
 	}
@@ -241,6 +223,11 @@ func BatchSelect(dbsql DBsql, batch string) ([][]interface{}, error) {
 
 // BatchSelectFct batch SQL query in table with fct called
 func BatchSelectFct(dbsql DBsql, batch *common.Query, fct common.ResultFunction) error {
+	return BatchSelectFctContext(context.Background(), dbsql, batch, fct)
+}
+
+// BatchSelectFctContext batch SQL query in table with fct called using context
+func BatchSelectFctContext(ctx context.Context, dbsql DBsql, batch *common.Query, fct common.ResultFunction) error {
 	layer, url := dbsql.Reference()
 	log.Log.Debugf("Connect url: %s", url)
 	db, err := sql.Open(layer, url)
@@ -249,7 +236,11 @@ func BatchSelectFct(dbsql DBsql, batch *common.Query, fct common.ResultFunction)
 	}
 	defer db.Close()
 	// Query batch SQL
-	rows, err := db.Query(batch.Search)
+	selectCmd, args, err := common.BindParameters(batch.Driver, batch.Search, batch.Parameters)
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, selectCmd, args...)
 	if err != nil {
 		return err
 	}
@@ -339,7 +330,11 @@ func sqlDataTypeStructField(baAvailable bool, field reflect.StructField,
 			return "", nil
 		}
 		if x.Name() == "Time" {
-			return sfi.name + " TIMESTAMP " + sfi.additional, nil
+			if sfi.kind == "deleted" {
+				// soft deleted timestamp is NULL until the record is deleted
+				return sfi.name + " TIMESTAMP NULL", nil
+			}
+			return sfi.name + " TIMESTAMP " + strings.TrimLeft(sfi.additional, " "), nil
 		}
 		if tagValue, ok := field.Tag.Lookup(common.TagName); ok {
 			log.Log.Debugf("Found tag %s for %s", tagValue, field.Name)
@@ -395,7 +390,10 @@ func sqlDataTypeStructFieldDataType(baAvailable bool, sf reflect.StructField) (s
 	log.Log.Debugf("dbsql name %s and kind %s (%s) (sfi kind=%s)",
 		sfi.name, t.Kind(), t.Name(), sfi.kind)
 	if t.PkgPath() == "time" && t.Name() == "Time" {
-		return sfi.name + " TIMESTAMP", nil
+		if sfi.kind == "deleted" {
+			return sfi.name + " TIMESTAMP NULL", nil
+		}
+		return sfi.name + " TIMESTAMP" + sfi.additional, nil
 	}
 	switch t.Kind() {
 	case reflect.String:
@@ -474,26 +472,39 @@ type structFieldInfo struct {
 	kind       string
 	length     int
 	skip       bool
+	primaryKey bool
+	unique     []string
+	index      []string
+	references string
 }
 
-// evaluateName evaluate name of type given (extract tags and info)
+// evaluateName evaluate name of type given (extract tags and info). The
+// tag parts after the length are constraint options, see evaluateConstraints.
 func evaluateName(sf reflect.StructField, tsf reflect.Type) *structFieldInfo {
 	sfi := &structFieldInfo{name: sf.Name, skip: false}
 	log.Log.Debugf("Found name " + sfi.name)
 	if tagName, ok := sf.Tag.Lookup(common.TagName); ok {
-		tagField := strings.Split(tagName, ":")
+		tagField := common.SplitTag(tagName)
 		if tagField[0] != "" {
 			sfi.name = tagField[0]
 		}
 		if len(tagField) > 1 {
-			if tagField[1] == "ignore" {
+			if _, join := common.TagJoinAlias(tagName); tagField[1] == "ignore" || join {
 				sfi.skip = true
 				return sfi
 			}
-			sfi.additional = " " + tagField[1]
+			switch _, tagInfo := common.TagInfoParse(tagName); tagInfo {
+			case common.NormalTag:
+				sfi.additional = " " + tagField[1]
+			case common.KeyTag, common.IndexTag:
+				sfi.primaryKey = true
+			}
 			sfi.kind = tagField[1]
 		}
 		log.Log.Debugf("Overwrite to name " + sfi.name)
+		if len(tagField) > 3 {
+			evaluateConstraints(sfi, tagField[3:])
+		}
 		if len(tagField) > 2 && tagField[2] != "" {
 			if tagField[2] == "SERIAL" {
 				sfi.info = sfi.name + " SERIAL UNIQUE"
@@ -508,6 +519,46 @@ func evaluateName(sf reflect.StructField, tsf reflect.Type) *structFieldInfo {
 	return sfi
 }
 
+// evaluateConstraints evaluate the constraint options of the tag like
+// `CustomerID:::notnull:references=Customers(ID):index`. The options are
+//
+//	pk, primarykey     column is part of the primary key
+//	unique[=name]      unique column, columns with the same name are one constraint
+//	index[=name]       index on the column, columns with the same name are one index
+//	notnull            NOT NULL column
+//	default=value      DEFAULT value of the column
+//	references=t(c)    foreign key referencing column c of table t
+//
+// A colon ends the option unless it is quoted like in `default='12:00'`.
+func evaluateConstraints(sfi *structFieldInfo, options []string) {
+	for _, o := range options {
+		option, value, _ := strings.Cut(o, "=")
+		switch strings.ToLower(strings.TrimSpace(option)) {
+		case "":
+		case "pk", "primarykey":
+			sfi.primaryKey = true
+		case "unique":
+			sfi.unique = append(sfi.unique, value)
+		case "index":
+			sfi.index = append(sfi.index, value)
+		case "notnull":
+			sfi.addConstraint("NOT NULL")
+		case "default":
+			sfi.addConstraint("DEFAULT " + value)
+		case "references":
+			sfi.references = value
+		default:
+			log.Log.Debugf("Unknown constraint option %s of %s", o, sfi.name)
+		}
+	}
+}
+
+// addConstraint add the constraint to the column definition separated by
+// one space
+func (sfi *structFieldInfo) addConstraint(constraint string) {
+	sfi.additional = strings.TrimRight(sfi.additional, " ") + " " + constraint
+}
+
 func evaluateSlice(baAvailable bool, sf reflect.StructField, t reflect.Type) (string, error) {
 	tt := t.Elem()
 	if tt.Kind() == reflect.Pointer {
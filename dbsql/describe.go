@@ -0,0 +1,197 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+// indexColumn column of an index of the table
+type indexColumn struct {
+	index   string
+	column  string
+	primary bool
+	unique  bool
+}
+
+// DescribeTable read the column definitions of the table out of the
+// database catalog. The table name is compared case-insensitive.
+func DescribeTable(ctx context.Context, db SchemaDB, driver common.ReferenceType, name string) (*common.TableInfo, error) {
+	log.Log.Debugf("%s: Describe SQL table %s", db.ID(), name)
+	layer, url := db.Reference()
+	dbOpen, err := sql.Open(layer, url)
+	if err != nil {
+		return nil, err
+	}
+	defer dbOpen.Close()
+	current, err := readColumns(ctx, dbOpen, driver, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(current) == 0 {
+		return nil, errorrepo.NewError("DB000074", name)
+	}
+	indexes, err := readIndexes(ctx, dbOpen, driver, name)
+	if err != nil {
+		return nil, err
+	}
+	info := &common.TableInfo{Name: name, Columns: make([]*common.Column, 0, len(current))}
+	for _, c := range current {
+		info.Columns = append(info.Columns, describeColumn(c))
+	}
+	markIndexes(info, indexes)
+	return info, nil
+}
+
+// TableColumnNames lower case column names of the table, the list is
+// empty if the table does not exist
+func TableColumnNames(ctx context.Context, db SchemaDB, driver common.ReferenceType, name string) ([]string, error) {
+	layer, url := db.Reference()
+	dbOpen, err := sql.Open(layer, url)
+	if err != nil {
+		return nil, err
+	}
+	defer dbOpen.Close()
+	current, err := readColumns(ctx, dbOpen, driver, name)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(current))
+	for _, c := range current {
+		names = append(names, strings.ToLower(c.name))
+	}
+	return names, nil
+}
+
+// describeColumn column of the table info out of the column definition
+func describeColumn(c *tableColumn) *common.Column {
+	column := &common.Column{Name: c.name, DataType: columnDataType(c.dataType),
+		Length: uint16(c.length), Digits: uint8(c.digits), Nullable: true}
+	if c.nullable != nil {
+		column.Nullable = *c.nullable
+	}
+	if c.defaultValue != nil {
+		column.Default = *c.defaultValue
+	}
+	return column
+}
+
+// columnDataType data type of the canonical SQL type name
+func columnDataType(dataType string) common.DataType {
+	switch dataType {
+	case "VARCHAR":
+		return common.Alpha
+	case "CHAR":
+		return common.Character
+	case "TEXT":
+		return common.Text
+	case "INTEGER":
+		return common.Integer
+	case "BIGINT":
+		return common.BigInteger
+	case "DECIMAL", "FLOAT":
+		return common.Decimal
+	case "BOOLEAN":
+		return common.Boolean
+	case "TIMESTAMP":
+		return common.CurrentTimestamp
+	case "DATE":
+		return common.Date
+	case "BLOB":
+		return common.BLOB
+	case "BINARY":
+		return common.Bytes
+	case "BIT":
+		return common.Bit
+	}
+	return common.None
+}
+
+// markIndexes set primary key, unique and index membership of the
+// columns. A column is unique if a unique index contains only this
+// column.
+func markIndexes(info *common.TableInfo, indexes []*indexColumn) {
+	count := make(map[string]int)
+	for _, ic := range indexes {
+		count[ic.index]++
+	}
+	for _, ic := range indexes {
+		column := info.Column(ic.column)
+		if column == nil {
+			continue
+		}
+		column.Index = true
+		column.PrimaryKey = column.PrimaryKey || ic.primary
+		column.Unique = column.Unique || (ic.unique && count[ic.index] == 1)
+	}
+}
+
+// indexQuery query of the index columns of the table, the table name is
+// compared case-insensitive
+func indexQuery(driver common.ReferenceType) (string, error) {
+	switch driver {
+	case common.PostgresType:
+		return `SELECT i.indexrelid::regclass::text, a.attname,
+			CASE WHEN i.indisprimary THEN 1 ELSE 0 END, CASE WHEN i.indisunique THEN 1 ELSE 0 END
+			FROM pg_catalog.pg_index i JOIN pg_catalog.pg_class c ON c.oid = i.indrelid
+			JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid AND a.attnum = ANY(i.indkey)
+			WHERE n.nspname = current_schema() AND lower(c.relname) = lower($1)`, nil
+	case common.MysqlType:
+		return `SELECT index_name, column_name, CASE index_name WHEN 'PRIMARY' THEN 1 ELSE 0 END,
+			CASE non_unique WHEN 0 THEN 1 ELSE 0 END FROM information_schema.statistics
+			WHERE table_schema = DATABASE() AND LOWER(table_name) = LOWER(?)`, nil
+	case common.OracleType:
+		return `SELECT ic.index_name, ic.column_name, CASE WHEN c.constraint_type = 'P' THEN 1 ELSE 0 END,
+			CASE i.uniqueness WHEN 'UNIQUE' THEN 1 ELSE 0 END FROM all_ind_columns ic
+			JOIN all_indexes i ON i.owner = ic.index_owner AND i.index_name = ic.index_name
+			LEFT JOIN all_constraints c ON c.owner = i.owner AND c.index_name = i.index_name AND c.constraint_type = 'P'
+			WHERE ic.table_owner = SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') AND UPPER(ic.table_name) = UPPER(:1)`, nil
+	case common.SqliteType:
+		// an INTEGER PRIMARY KEY column is the row id and has no index
+		return `SELECT '#pk', name, 1, 1 FROM pragma_table_info(?1) WHERE pk > 0
+			UNION ALL SELECT il.name, ii.name, CASE il.origin WHEN 'pk' THEN 1 ELSE 0 END, il."unique"
+			FROM pragma_index_list(?1) il, pragma_index_info(il.name) ii`, nil
+	}
+	return "", errorrepo.NewError("DB065535")
+}
+
+// readIndexes index columns of the table in the database
+func readIndexes(ctx context.Context, db *sql.DB, driver common.ReferenceType, name string) ([]*indexColumn, error) {
+	query, err := indexQuery(driver)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	indexes := make([]*indexColumn, 0)
+	for rows.Next() {
+		var primary, unique int
+		ic := &indexColumn{}
+		err = rows.Scan(&ic.index, &ic.column, &primary, &unique)
+		if err != nil {
+			return nil, err
+		}
+		ic.primary, ic.unique = primary != 0, unique != 0
+		indexes = append(indexes, ic)
+	}
+	return indexes, rows.Err()
+}
@@ -0,0 +1,51 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package dbsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+)
+
+func TestDescribeColumns(t *testing.T) {
+	InitLog(t)
+
+	info := &common.TableInfo{Name: "Records"}
+	for _, c := range liveColumns("ID INTEGER NOT NULL", "Name VARCHAR(100) DEFAULT 'none'",
+		"Amount DECIMAL(10,5)", "Created TIMESTAMP", "Tenant BIGINT", "Data BYTEA") {
+		info.Columns = append(info.Columns, describeColumn(c))
+	}
+	assert.Equal(t, &common.Column{Name: "ID", DataType: common.Integer}, info.Columns[0])
+	assert.Equal(t, &common.Column{Name: "Name", DataType: common.Alpha, Length: 100, Nullable: true,
+		Default: "'none'"}, info.Columns[1])
+	assert.Equal(t, []any{common.Decimal, uint16(10), uint8(5)},
+		[]any{info.Columns[2].DataType, info.Columns[2].Length, info.Columns[2].Digits})
+	assert.Equal(t, common.CurrentTimestamp, info.Columns[3].DataType)
+	assert.Equal(t, common.BigInteger, info.Columns[4].DataType)
+	assert.Equal(t, common.BLOB, info.Columns[5].DataType)
+
+	markIndexes(info, []*indexColumn{{index: "pk", column: "id", primary: true, unique: true},
+		{index: "uq_name", column: "NAME", unique: true},
+		{index: "uq_tenant", column: "Tenant", unique: true}, {index: "uq_tenant", column: "Amount", unique: true},
+		{index: "ix_created", column: "Created"}, {index: "ix_unknown", column: "Unknown"}})
+	assert.Equal(t, []string{"ID"}, info.PrimaryKey())
+	assert.True(t, info.Column("id").Unique)
+	assert.True(t, info.Column("name").Unique)
+	assert.False(t, info.Column("Name").PrimaryKey)
+	assert.False(t, info.Column("tenant").Unique)
+	assert.True(t, info.Column("tenant").Index)
+	assert.True(t, info.Column("created").Index)
+	assert.False(t, info.Column("data").Index)
+	assert.Nil(t, info.Column("unknown"))
+}
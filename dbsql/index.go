@@ -0,0 +1,254 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+// foreignKey column referencing a column of another table like
+// `Customers(ID)`
+type foreignKey struct {
+	column    string
+	reference string
+}
+
+// tableConstraints constraints and indexes of the table created out of
+// the struct tags or the columns
+type tableConstraints struct {
+	primaryKey []string
+	unique     []*common.Index
+	indexes    []*common.Index
+	references []*foreignKey
+}
+
+// CreateTableStatements CREATE TABLE statement including the primary key,
+// unique and foreign key constraints followed by the CREATE INDEX
+// statements of the table
+func CreateTableStatements(driver common.ReferenceType, baAvailable bool, name string, col any) ([]string, error) {
+	constraints := &tableConstraints{}
+	var definition string
+	switch columns := col.(type) {
+	case []*common.Column:
+		definition = CreateTableByColumns(baAvailable, columns)
+		constraints.addColumns(columns)
+	case map[string]interface{}:
+		definition = CreateTableByMaps(baAvailable, columns)
+	default:
+		c, err := CreateTableByStruct(baAvailable, col)
+		if err != nil {
+			return nil, err
+		}
+		definition = c
+		t := reflect.TypeOf(col)
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		constraints.addStruct(t)
+	}
+	statements := []string{"CREATE TABLE " + name + " (" + definition + constraints.definition() + ")"}
+	for _, index := range constraints.indexes {
+		if driver == common.OracleType && constraints.indexed(index.Columns) {
+			// Oracle rejects a second index on the same column list
+			log.Log.Debugf("Skip index %s, columns already indexed", index.Name)
+			continue
+		}
+		statements = append(statements, IndexStatement(name, index))
+	}
+	return statements, nil
+}
+
+// addColumns constraints and indexes of the column definitions. Columns
+// already indexed by the primary key or their unique constraint get no
+// additional index.
+func (tc *tableConstraints) addColumns(columns []*common.Column) {
+	for _, c := range columns {
+		if c.PrimaryKey {
+			tc.primaryKey = append(tc.primaryKey, c.Name)
+		}
+		if c.Unique {
+			tc.unique = addIndexColumn(tc.unique, "", c.Name, true)
+		}
+	}
+	for _, c := range columns {
+		if c.Index && !tc.indexed([]string{c.Name}) {
+			tc.indexes = addIndexColumn(tc.indexes, "", c.Name, false)
+		}
+	}
+}
+
+// addStruct constraints and indexes of the struct fields, sub structures
+// are handled like the column definitions in SqlDataType
+func (tc *tableConstraints) addStruct(t reflect.Type) {
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Name == "" || unicode.IsLower([]rune(f.Name)[0]) {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		sfi := evaluateName(f, ft)
+		if sfi.skip {
+			continue
+		}
+		if ft.Kind() == reflect.Struct && !(ft.PkgPath() == "time" && ft.Name() == "Time") {
+			switch _, tagInfo := common.TagInfoParse(f.Tag.Get(common.TagName)); tagInfo {
+			case common.SubTag, common.YAMLTag, common.XMLTag, common.JSONTag:
+			default:
+				tc.addStruct(ft)
+				continue
+			}
+		}
+		tc.addField(sfi)
+	}
+}
+
+// addField constraints and indexes of the field
+func (tc *tableConstraints) addField(sfi *structFieldInfo) {
+	if sfi.primaryKey {
+		tc.primaryKey = append(tc.primaryKey, sfi.name)
+	}
+	for _, u := range sfi.unique {
+		tc.unique = addIndexColumn(tc.unique, u, sfi.name, true)
+	}
+	for _, i := range sfi.index {
+		tc.indexes = addIndexColumn(tc.indexes, i, sfi.name, false)
+	}
+	if sfi.references != "" {
+		tc.references = append(tc.references, &foreignKey{column: sfi.name, reference: sfi.references})
+	}
+}
+
+// addIndexColumn add the column to the index of the same name, indexes
+// without name contain only one column
+func addIndexColumn(indexes []*common.Index, name, column string, unique bool) []*common.Index {
+	if name != "" {
+		for _, index := range indexes {
+			if strings.EqualFold(index.Name, name) {
+				index.Columns = append(index.Columns, column)
+				return indexes
+			}
+		}
+	}
+	return append(indexes, &common.Index{Name: name, Columns: []string{column}, Unique: unique})
+}
+
+// definition table constraints appended to the column definitions
+func (tc *tableConstraints) definition() string {
+	var buffer strings.Builder
+	if len(tc.primaryKey) > 0 {
+		buffer.WriteString(", PRIMARY KEY (" + strings.Join(tc.primaryKey, ", ") + ")")
+	}
+	for _, u := range tc.unique {
+		buffer.WriteString(", ")
+		if u.Name != "" {
+			buffer.WriteString("CONSTRAINT " + u.Name + " ")
+		}
+		buffer.WriteString("UNIQUE (" + strings.Join(u.Columns, ", ") + ")")
+	}
+	for _, r := range tc.references {
+		buffer.WriteString(", FOREIGN KEY (" + r.column + ") REFERENCES " + r.reference)
+	}
+	return buffer.String()
+}
+
+// indexed check if the columns are already indexed by the primary key or
+// a unique constraint
+func (tc *tableConstraints) indexed(columns []string) bool {
+	sameColumns := func(list []string) bool {
+		if len(list) != len(columns) {
+			return false
+		}
+		for i := range list {
+			if !strings.EqualFold(list[i], columns[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if sameColumns(tc.primaryKey) {
+		return true
+	}
+	for _, u := range tc.unique {
+		if sameColumns(u.Columns) {
+			return true
+		}
+	}
+	return false
+}
+
+// IndexStatement CREATE INDEX statement of the table. Indexes without
+// name get the name `<table>_<columns>_idx`.
+func IndexStatement(table string, index *common.Index) string {
+	createCmd := "CREATE INDEX "
+	if index.Unique {
+		createCmd = "CREATE UNIQUE INDEX "
+	}
+	return createCmd + indexName(table, index) + " ON " + table + " (" + strings.Join(index.Columns, ", ") + ")"
+}
+
+// indexName name of the index, generated out of the table and columns if
+// not given
+func indexName(table string, index *common.Index) string {
+	if index.Name != "" {
+		return index.Name
+	}
+	return table + "_" + strings.Join(index.Columns, "_") + "_idx"
+}
+
+// DropIndexStatement DROP INDEX statement in the SQL dialect of the
+// driver, MySQL indexes belong to the table
+func DropIndexStatement(driver common.ReferenceType, table, name string) string {
+	if driver == common.MysqlType {
+		return "DROP INDEX " + name + " ON " + table
+	}
+	return "DROP INDEX " + name
+}
+
+// CreateIndex create the index on the columns of the table
+func CreateIndex(ctx context.Context, db SchemaDB, driver common.ReferenceType, table string, index *common.Index) error {
+	if index == nil || len(index.Columns) == 0 {
+		return errorrepo.NewError("DB000075", table)
+	}
+	return execStatement(ctx, db, IndexStatement(table, index))
+}
+
+// DropIndex drop the index of the table
+func DropIndex(ctx context.Context, db SchemaDB, driver common.ReferenceType, table, name string) error {
+	return execStatement(ctx, db, DropIndexStatement(driver, table, name))
+}
+
+// execStatement execute the DDL statement
+func execStatement(ctx context.Context, db SchemaDB, statement string) error {
+	log.Log.Debugf("%s: Execute %s", db.ID(), statement)
+	layer, url := db.Reference()
+	dbOpen, err := sql.Open(layer, url)
+	if err != nil {
+		return err
+	}
+	defer dbOpen.Close()
+	_, err = dbOpen.ExecContext(ctx, statement)
+	return err
+}
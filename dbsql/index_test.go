@@ -0,0 +1,93 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package dbsql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+)
+
+type orderAddress struct {
+	City string `flynn:"City:::index"`
+	Zip  string `flynn:"Zip::10"`
+}
+
+type orderLine struct {
+	Shop       string `flynn:"Shop:key:10"`
+	Number     int64  `flynn:"Number:key"`
+	CustomerID int64  `flynn:"CustomerID:::notnull:references=Customers(ID):index"`
+	Code       string `flynn:"Code::20:unique"`
+	Tenant     int64  `flynn:":::unique=order_tenant:index=order_tenant_idx"`
+	Item       string `flynn:"Item:::unique=order_tenant:index=order_tenant_idx"`
+	State      string `flynn:"State::1:notnull:default='N'"`
+	Created    time.Time
+	Address    orderAddress
+	Ignored    string `flynn:":ignore"`
+}
+
+func TestCreateTableStatements(t *testing.T) {
+	InitLog(t)
+
+	statements, err := CreateTableStatements(common.PostgresType, true, "Orders", &orderLine{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CREATE TABLE Orders (Shop VARCHAR(10), Number INTEGER, " +
+		"CustomerID INTEGER NOT NULL, Code VARCHAR(20) , Tenant INTEGER , Item VARCHAR(255) , " +
+		"State VARCHAR(1) NOT NULL DEFAULT 'N', Created TIMESTAMP , City VARCHAR(255) , Zip VARCHAR(10) , " +
+		"PRIMARY KEY (Shop, Number), UNIQUE (Code), CONSTRAINT order_tenant UNIQUE (Tenant, Item), " +
+		"FOREIGN KEY (CustomerID) REFERENCES Customers(ID))",
+		"CREATE INDEX Orders_CustomerID_idx ON Orders (CustomerID)",
+		"CREATE INDEX order_tenant_idx ON Orders (Tenant, Item)",
+		"CREATE INDEX Orders_City_idx ON Orders (City)"}, statements)
+
+	// Oracle rejects indexes on columns indexed by a unique constraint
+	statements, err = CreateTableStatements(common.OracleType, true, "Orders", &orderLine{})
+	assert.NoError(t, err)
+	assert.Len(t, statements, 3)
+
+	statements, err = CreateTableStatements(common.MysqlType, true, "Items", []*common.Column{
+		{Name: "ID", DataType: common.Integer, PrimaryKey: true, Index: true},
+		{Name: "Name", DataType: common.Alpha, Length: 10, Unique: true, Default: "'x'"},
+		{Name: "Amount", DataType: common.Integer, Index: true}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CREATE TABLE Items (ID INTEGER, Name VARCHAR(10) DEFAULT 'x', Amount INTEGER, " +
+		"PRIMARY KEY (ID), UNIQUE (Name))", "CREATE INDEX Items_Amount_idx ON Items (Amount)"}, statements)
+
+	// defaults containing colons and options after the default
+	statements, err = CreateTableStatements(common.PostgresType, true, "Hours", &struct {
+		Opens  string    `flynn:"Opens::5:notnull:default='12:00'"`
+		Kind   string    `flynn:"Kind:::default='x:y':notnull"`
+		Closes time.Time `flynn:"Closes:::default=CURRENT_TIMESTAMP:notnull"`
+	}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CREATE TABLE Hours (Opens VARCHAR(5) NOT NULL DEFAULT '12:00', " +
+		"Kind VARCHAR(255) DEFAULT 'x:y' NOT NULL, Closes TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL)"}, statements)
+
+	// primary key, unique constraint and index of the same column
+	statements, err = CreateTableStatements(common.PostgresType, true, "Lines", []*common.Column{
+		{Name: "Shop", DataType: common.Alpha, Length: 10, PrimaryKey: true, Index: true},
+		{Name: "Number", DataType: common.Integer, PrimaryKey: true, Unique: true, Index: true}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"CREATE TABLE Lines (Shop VARCHAR(10), Number INTEGER, " +
+		"PRIMARY KEY (Shop, Number), UNIQUE (Number))", "CREATE INDEX Lines_Shop_idx ON Lines (Shop)"}, statements)
+}
+
+func TestIndexStatement(t *testing.T) {
+	assert.Equal(t, "CREATE UNIQUE INDEX uq_name ON Items (Name, Shop)",
+		IndexStatement("Items", &common.Index{Name: "uq_name", Columns: []string{"Name", "Shop"}, Unique: true}))
+	assert.Equal(t, "CREATE INDEX Items_Name_idx ON Items (Name)",
+		IndexStatement("Items", &common.Index{Columns: []string{"Name"}}))
+	assert.Equal(t, "DROP INDEX uq_name ON Items", DropIndexStatement(common.MysqlType, "Items", "uq_name"))
+	assert.Equal(t, "DROP INDEX uq_name", DropIndexStatement(common.PostgresType, "Items", "uq_name"))
+}
@@ -13,9 +13,13 @@ package dbsql
 
 import (
 	"bytes"
-	"fmt"
+	"context"
+	"database/sql"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/exp/slices"
 
@@ -24,9 +28,16 @@ import (
 	"github.com/tknie/log"
 )
 
-func Insert(dbsql DBsql, name string, insert *common.Entries) ([][]any, error) {
+// Insert insert records, one statement is used for each record
+func Insert(dbsql DBsql, driver common.ReferenceType, name string, insert *common.Entries) ([][]any, error) {
+	return InsertContext(context.Background(), dbsql, driver, name, insert)
+}
+
+// InsertContext insert records using the given context for all statements.
+// The Returning fields of each record are returned.
+func InsertContext(ctx context.Context, dbsql DBsql, driver common.ReferenceType, name string, insert *common.Entries) ([][]any, error) {
 	log.Log.Debugf("%s: Transaction (begin insert): %v", dbsql.ID(), dbsql.IsTransaction())
-	tx, ctx, err := dbsql.StartTransaction()
+	tx, _, err := dbsql.StartTransaction()
 	if err != nil {
 		return nil, err
 	}
@@ -41,22 +52,9 @@ func Insert(dbsql DBsql, name string, insert *common.Entries) ([][]any, error) {
 	insertCmd := "INSERT INTO " + name + " ("
 	values := "("
 
-	indexNeed := dbsql.IndexNeeded()
-	var insertValues [][]any
-	var insertFields []string
-	if insert.DataStruct != nil {
-		dynamic := common.CreateInterface(insert.DataStruct, insert.Fields)
-		insertFields = dynamic.RowFields
-		for _, vi := range insert.Values {
-			v, err := dynamic.CreateValues(vi[0])
-			if err != nil {
-				return nil, err
-			}
-			insertValues = append(insertValues, v)
-		}
-	} else {
-		insertFields = insert.Fields
-		insertValues = insert.Values
+	insertFields, insertValues, err := insert.InsertValues()
+	if err != nil {
+		return nil, err
 	}
 	log.Log.Debugf("Row   fields: %#v", insertFields)
 	log.Log.Debugf("Value fields: %#v", insertValues)
@@ -65,20 +63,26 @@ func Insert(dbsql DBsql, name string, insert *common.Entries) ([][]any, error) {
 			insertCmd += ","
 			values += ","
 		}
-		if indexNeed {
-			insertCmd += `"` + strings.ToLower(field) + `"`
-			values += "$" + strconv.Itoa(i+1)
-		} else {
-			insertCmd += "`" + strings.ToLower(field) + "`"
-			values += "?"
-		}
+		insertCmd += driverField(driver, field)
+		values += driverPlaceholder(driver, i+1)
 	}
 	values += ")"
 	insertCmd += ") VALUES " + values
 	log.Log.Debugf("Insert pre-CMD: %s", insertCmd)
+	returning := make([][]any, 0)
 	for _, v := range insertValues {
 		av := v
 		log.Log.Debugf("Insert values: %d -> %#v", len(av), av)
+		if len(insert.Returning) > 0 {
+			rv, err := insertReturning(ctx, tx, driver, name, insertCmd, insertFields, av, insert)
+			if err != nil {
+				dbsql.EndTransaction(false)
+				log.Log.Debugf("Error insert CMD: %v of %s and cmd %s", err, name, insertCmd)
+				return nil, err
+			}
+			returning = append(returning, rv)
+			continue
+		}
 		res, err := tx.ExecContext(ctx, insertCmd, av...)
 		if err != nil {
 			dbsql.EndTransaction(false)
@@ -107,199 +111,817 @@ func Insert(dbsql DBsql, name string, insert *common.Entries) ([][]any, error) {
 	} else {
 		log.Log.Debugf("Transaction, NO end and close")
 	}
-	return make([][]any, 0), nil
+	return returning, nil
 }
 
-func GenerateUpdate(indexNeeded bool, name string, updateInfo *common.Entries) (string, []int) {
-	insertCmd := "UPDATE " + name + " SET "
+// BulkInsertContext insert records with the bulk insert of the driver if
+// requested or if the number of records reaches common.BulkThreshold,
+// otherwise each record is inserted with its own statement. MySQL and
+// SQLite insert multiple rows with one INSERT, Oracle uses array binding.
+func BulkInsertContext(ctx context.Context, dbsql DBsql, driver common.ReferenceType, name string, insert *common.Entries) ([][]any, error) {
+	insertFields, insertValues, err := insert.InsertValues()
+	if err != nil {
+		return nil, err
+	}
+	bulk, err := insert.UseBulk(name, len(insertValues))
+	if err != nil {
+		return nil, err
+	}
+	if !bulk {
+		return InsertContext(ctx, dbsql, driver, name, insert)
+	}
+	tx, _, err := dbsql.StartTransaction()
+	if err != nil {
+		return nil, err
+	}
+	if !dbsql.IsTransaction() {
+		defer dbsql.Close()
+	}
+	batchSize := bulkBatchSize(driver, len(insertFields))
+	log.Log.Debugf("Bulk insert %d rows in batches of %d", len(insertValues), batchSize)
+	for start := 0; start < len(insertValues); start += batchSize {
+		end := min(start+batchSize, len(insertValues))
+		batches := [][][]any{insertValues[start:end]}
+		if driver == common.OracleType && !arrayBinding(insertValues[start:end], len(insertFields)) {
+			// columns with mixed types cannot be bound as array, each row
+			// of the batch is inserted with its own statement
+			log.Log.Debugf("Bulk insert rows %d-%d row by row", start, end)
+			batches = batches[:0]
+			for r := start; r < end; r++ {
+				batches = append(batches, insertValues[r:r+1])
+			}
+		}
+		for _, rows := range batches {
+			insertCmd, args := GenerateBulkInsert(driver, name, insertFields, rows)
+			res, err := tx.ExecContext(ctx, insertCmd, args...)
+			if err == nil {
+				var l int64
+				l, err = res.RowsAffected()
+				if err == nil && l != int64(len(rows)) {
+					err = errorrepo.NewError("DB000020")
+				}
+			}
+			if err != nil {
+				dbsql.EndTransaction(false)
+				log.Log.Debugf("Error bulk insert CMD: %v of %s and cmd %s", err, name, insertCmd)
+				return nil, err
+			}
+		}
+	}
+	if !dbsql.IsTransaction() {
+		err = dbsql.EndTransaction(true)
+		if err != nil {
+			log.Log.Debugf("Error transaction %v", err)
+			return nil, err
+		}
+	}
+	return make([][]any, 0), nil
+}
 
-	whereFields := make([]int, 0)
-	indexNeed := indexNeeded
-	var insertFields []string
-	if updateInfo.DataStruct != nil {
-		dynamic := common.CreateInterface(updateInfo.DataStruct, updateInfo.Fields)
-		insertFields = dynamic.RowFields
-	} else {
-		insertFields = updateInfo.Fields
+// bulkBatchSize number of rows inserted by one statement, limited by the
+// maximum number of placeholders of the driver
+func bulkBatchSize(driver common.ReferenceType, fields int) int {
+	batchSize := max(common.BulkBatchSize, 1)
+	maxPlaceholders := 0
+	switch driver {
+	case common.MysqlType:
+		maxPlaceholders = 65535
+	case common.SqliteType:
+		maxPlaceholders = 32766
 	}
+	if maxPlaceholders > 0 && fields > 0 {
+		batchSize = max(min(batchSize, maxPlaceholders/fields), 1)
+	}
+	return batchSize
+}
 
-	for i, field := range insertFields {
+// GenerateBulkInsert generate the statement and arguments inserting all
+// rows. Oracle binds one array for each field, the other drivers use one
+// VALUES list for each row.
+func GenerateBulkInsert(driver common.ReferenceType, name string, fields []string, rows [][]any) (string, []any) {
+	var buffer bytes.Buffer
+	buffer.WriteString("INSERT INTO " + name + " (")
+	for i, f := range fields {
 		if i > 0 {
-			insertCmd += ","
+			buffer.WriteString(",")
 		}
-		if indexNeed {
-			insertCmd += `"` + strings.ToLower(field) + `"` + "=$" + strconv.Itoa(i+1)
-		} else {
-			insertCmd += "`" + strings.ToLower(field) + "`" + "=?"
+		buffer.WriteString(driverField(driver, f))
+	}
+	buffer.WriteString(") VALUES ")
+	if driver == common.OracleType {
+		buffer.WriteString("(")
+		args := make([]any, 0, len(fields))
+		for i := range fields {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(driverPlaceholder(driver, i+1))
+			array, _ := columnArray(rows, i)
+			args = append(args, array)
+		}
+		buffer.WriteString(")")
+		return buffer.String(), args
+	}
+	args := make([]any, 0, len(fields)*len(rows))
+	n := 0
+	for r, row := range rows {
+		if r > 0 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("(")
+		for i := range fields {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			n++
+			buffer.WriteString(driverPlaceholder(driver, n))
+			args = append(args, row[i])
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.String(), args
+}
+
+// arrayBinding check if all columns of the rows can be bound as array
+func arrayBinding(rows [][]any, fields int) bool {
+	for i := 0; i < fields; i++ {
+		if _, ok := columnArray(rows, i); !ok {
+			return false
 		}
-		if slices.Contains(updateInfo.Update, field) {
-			whereFields = append(whereFields, i)
+	}
+	return true
+}
+
+// columnArray values of the column of all rows used for array binding. If
+// all values have the same type a typed slice is returned. NULL values are
+// bound with sql.Null* slices, nil byte slices or empty strings, which are
+// NULL in Oracle. If no typed slice can be built, the values are returned
+// and false.
+func columnArray(rows [][]any, column int) (any, bool) {
+	values := make([]any, 0, len(rows))
+	var valueType reflect.Type
+	typed := true
+	null := false
+	for _, row := range rows {
+		v := row[column]
+		values = append(values, v)
+		switch {
+		case v == nil:
+			null = true
+		case valueType == nil:
+			valueType = reflect.TypeOf(v)
+		case valueType != reflect.TypeOf(v):
+			typed = false
 		}
 	}
-	insertCmd += " WHERE "
-	return insertCmd, whereFields
+	switch {
+	case !typed:
+		return values, false
+	case valueType == nil:
+		return make([]string, len(values)), true
+	case null:
+		return nullArray(valueType, values)
+	}
+	array := reflect.MakeSlice(reflect.SliceOf(valueType), 0, len(values))
+	for _, v := range values {
+		array = reflect.Append(array, reflect.ValueOf(v))
+	}
+	return array.Interface(), true
 }
 
+// nullArray typed slice of the values of the type containing NULL values
+func nullArray(valueType reflect.Type, values []any) (any, bool) {
+	switch valueType.Kind() {
+	case reflect.String:
+		array := make([]string, len(values))
+		for i, v := range values {
+			if v != nil {
+				array[i] = reflect.ValueOf(v).String()
+			}
+		}
+		return array, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		array := make([]sql.NullInt64, len(values))
+		for i, v := range values {
+			if v != nil {
+				array[i] = sql.NullInt64{Int64: reflect.ValueOf(v).Convert(reflect.TypeFor[int64]()).Int(), Valid: true}
+			}
+		}
+		return array, true
+	case reflect.Float32, reflect.Float64:
+		array := make([]sql.NullFloat64, len(values))
+		for i, v := range values {
+			if v != nil {
+				array[i] = sql.NullFloat64{Float64: reflect.ValueOf(v).Float(), Valid: true}
+			}
+		}
+		return array, true
+	case reflect.Slice:
+		if valueType.Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		array := make([][]byte, len(values))
+		for i, v := range values {
+			if v != nil {
+				array[i] = reflect.ValueOf(v).Bytes()
+			}
+		}
+		return array, true
+	}
+	if valueType == reflect.TypeFor[time.Time]() {
+		array := make([]sql.NullTime, len(values))
+		for i, v := range values {
+			if v != nil {
+				array[i] = sql.NullTime{Time: v.(time.Time), Valid: true}
+			}
+		}
+		return array, true
+	}
+	return values, false
+}
+
+// GenerateDelete generate the DELETE statement of the value row. All values
+// including the LIKE patterns of fields starting with `%` are bound as
+// parameters.
 func GenerateDelete(indexNeeded bool, name string, valueIndex int, deleteInfo *common.Entries) (string, []any) {
 	deleteCmd := "DELETE FROM " + name + " WHERE "
 
 	values := make([]any, 0)
+	placeholder := func() string {
+		if indexNeeded {
+			return "$" + strconv.Itoa(len(values))
+		}
+		return "?"
+	}
 	for i, field := range deleteInfo.Fields {
 		if i > 0 {
 			deleteCmd += " AND "
 		}
+		values = append(values, deleteInfo.Values[valueIndex][i])
 		if field[0] == '%' {
-			deleteCmd += "(" + field[1:] + " LIKE '" + deleteInfo.Values[0][i].(string) + "')"
+			deleteCmd += "(" + field[1:] + " LIKE " + placeholder() + ")"
 			continue
 		}
-		//deleteCmd += "`" + strings.ToLower(field) + "` IN ("
-		deleteCmd += strings.ToLower(field) + " IN ("
-		//for j := 0; j < len(deleteInfo.Values[0]); j++ {
-		if indexNeeded {
-			deleteCmd += "$" + strconv.Itoa(i+1)
-		} else {
-			deleteCmd += "?"
-		}
-		values = append(values, deleteInfo.Values[0][i])
-		//}
-		deleteCmd += ")"
+		deleteCmd += strings.ToLower(field) + " IN (" + placeholder() + ")"
 	}
 	return deleteCmd, values
 }
 
-func Update(dbsql DBsql, name string, updateInfo *common.Entries) (running [][]any, rowsAffected int64, err error) {
-	tx, ctx, err := dbsql.StartTransaction()
+func Update(dbsql DBsql, driver common.ReferenceType, name string, updateInfo *common.Entries) (running [][]any, rowsAffected int64, err error) {
+	return UpdateContext(context.Background(), dbsql, driver, name, updateInfo)
+}
+
+// UpdateContext update records using the given context for all statements.
+// The key fields named in Update, the values of the comparisons in Update
+// and the Parameters of Criteria are bound as placeholders.
+func UpdateContext(ctx context.Context, dbsql DBsql, driver common.ReferenceType, name string, updateInfo *common.Entries) (running [][]any, rowsAffected int64, err error) {
+	if len(updateInfo.Returning) > 0 {
+		return updateReturning(ctx, dbsql, driver, name, updateInfo)
+	}
+	counts, err := BatchUpdateContext(ctx, dbsql, driver, name, updateInfo)
 	if err != nil {
-		return nil, -1, err
+		return nil, 0, err
+	}
+	for _, c := range counts {
+		rowsAffected += c
+	}
+	log.Log.Debugf("Update done")
+	return nil, rowsAffected, nil
+}
+
+func Delete(dbsql DBsql, driver common.ReferenceType, name string, updateInfo *common.Entries) (rowsAffected int64, err error) {
+	return DeleteContext(context.Background(), dbsql, driver, name, updateInfo)
+}
+
+// DeleteContext delete records using the given context for all statements.
+// The values and the Parameters of Criteria are bound as placeholders.
+func DeleteContext(ctx context.Context, dbsql DBsql, driver common.ReferenceType, name string, updateInfo *common.Entries) (rowsAffected int64, err error) {
+	counts, err := BatchDeleteContext(ctx, dbsql, driver, name, updateInfo)
+	if err != nil {
+		return -1, err
+	}
+	for _, c := range counts {
+		rowsAffected += c
+	}
+	log.Log.Debugf("Delete done")
+	return rowsAffected, nil
+}
+
+// driverField field name quoted like in the insert of the driver
+func driverField(driver common.ReferenceType, field string) string {
+	switch driver {
+	case common.PostgresType:
+		return `"` + strings.ToLower(field) + `"`
+	case common.OracleType:
+		return field
+	}
+	return "`" + strings.ToLower(field) + "`"
+}
+
+// driverPlaceholder placeholder of the n-th value of the driver
+func driverPlaceholder(driver common.ReferenceType, n int) string {
+	switch driver {
+	case common.PostgresType:
+		return "$" + strconv.Itoa(n)
+	case common.OracleType:
+		return ":" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// GenerateUpsert generate the statement inserting a record or updating the
+// record with the same conflict key. PostgreSQL and SQLite use
+// `ON CONFLICT ... DO UPDATE` including the returning fields, MySQL uses
+// `ON DUPLICATE KEY UPDATE` checking all unique keys of the table and Oracle
+// uses `MERGE`. The update of an existing record increments the version
+// field of the entries instead of setting it and keeps the created field.
+func GenerateUpsert(driver common.ReferenceType, name string, fields, keys, returning []string, upsert *common.Entries) string {
+	version := ""
+	created := ""
+	if upsert != nil {
+		if v := VersionIndex(fields, upsert); v >= 0 {
+			version = fields[v]
+		}
+		created = upsert.CreatedField()
+	}
+	updateFields := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if !slices.ContainsFunc(keys, func(k string) bool { return strings.EqualFold(k, f) }) &&
+			!strings.EqualFold(f, created) {
+			updateFields = append(updateFields, f)
+		}
+	}
+	target := name[strings.LastIndexByte(name, '.')+1:]
+	var buffer bytes.Buffer
+	if driver == common.OracleType {
+		buffer.WriteString("MERGE INTO " + name + " t USING (SELECT ")
+		for i, f := range fields {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(driverPlaceholder(driver, i+1) + " " + f)
+		}
+		buffer.WriteString(" FROM dual) s ON (")
+		for i, k := range keys {
+			if i > 0 {
+				buffer.WriteString(" AND ")
+			}
+			buffer.WriteString("t." + k + "=s." + k)
+		}
+		buffer.WriteString(")")
+		if len(updateFields) > 0 {
+			buffer.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+			for i, f := range updateFields {
+				if i > 0 {
+					buffer.WriteString(",")
+				}
+				if f == version {
+					buffer.WriteString("t." + f + "=t." + f + "+1")
+					continue
+				}
+				buffer.WriteString("t." + f + "=s." + f)
+			}
+		}
+		buffer.WriteString(" WHEN NOT MATCHED THEN INSERT (" + strings.Join(fields, ",") + ") VALUES (")
+		for i, f := range fields {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString("s." + f)
+		}
+		buffer.WriteString(")")
+		return buffer.String()
+	}
+	buffer.WriteString("INSERT INTO " + name + " (")
+	for i, f := range fields {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString(driverField(driver, f))
+	}
+	buffer.WriteString(") VALUES (")
+	for i := range fields {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString(driverPlaceholder(driver, i+1))
+	}
+	buffer.WriteString(")")
+	if len(updateFields) == 0 {
+		// update the key to itself, so that the record is returned
+		updateFields = keys[:1]
+	}
+	if driver == common.MysqlType {
+		buffer.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for i, f := range updateFields {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			if f == version {
+				buffer.WriteString(driverField(driver, f) + "=" + driverField(driver, f) + "+1")
+				continue
+			}
+			buffer.WriteString(driverField(driver, f) + "=VALUES(" + driverField(driver, f) + ")")
+		}
+		return buffer.String()
+	}
+	buffer.WriteString(" ON CONFLICT (")
+	for i, k := range keys {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString(driverField(driver, k))
+	}
+	buffer.WriteString(") DO UPDATE SET ")
+	for i, f := range updateFields {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		if f == version {
+			buffer.WriteString(driverField(driver, f) + "=" + target + "." + driverField(driver, f) + "+1")
+			continue
+		}
+		buffer.WriteString(driverField(driver, f) + "=EXCLUDED." + driverField(driver, f))
+	}
+	if len(returning) > 0 {
+		buffer.WriteString(" RETURNING " + strings.Join(returning, ","))
+	}
+	return buffer.String()
+}
+
+// GenerateReturning generate the SELECT statement reading the returning
+// fields of the record with the conflict key, used by drivers without
+// RETURNING clause
+func GenerateReturning(driver common.ReferenceType, name string, keys, returning []string) string {
+	selectCmd := "SELECT " + strings.Join(returning, ",") + " FROM " + name + " WHERE "
+	for i, k := range keys {
+		if i > 0 {
+			selectCmd += " AND "
+		}
+		selectCmd += driverField(driver, k) + "=" + driverPlaceholder(driver, i+1)
+	}
+	return selectCmd
+}
+
+// Upsert insert records or update the records with the same conflict key
+func Upsert(dbsql DBsql, driver common.ReferenceType, name string, upsert *common.Entries) ([][]any, error) {
+	return UpsertContext(context.Background(), dbsql, driver, name, upsert)
+}
+
+// UpsertContext insert records or update the records with the same conflict
+// key using the given context for all statements
+func UpsertContext(ctx context.Context, dbsql DBsql, driver common.ReferenceType, name string, upsert *common.Entries) ([][]any, error) {
+	fields, values, keys, err := upsert.UpsertValues(name)
+	if err != nil {
+		return nil, err
+	}
+	tx, _, err := dbsql.StartTransaction()
+	if err != nil {
+		return nil, err
 	}
-	log.Log.Debugf("Transaction %p", tx)
 	if !dbsql.IsTransaction() {
-		log.Log.Debugf("Is no transaction closing after update")
 		defer dbsql.Close()
 	}
-	insertCmd, whereFields := GenerateUpdate(dbsql.IndexNeeded(), name, updateInfo)
-	log.Log.Debugf("CMD: %s - %s", insertCmd, whereFields)
-	var insertFields []string
-	var insertValues [][]any
-	if updateInfo.DataStruct != nil {
-		dynamic := common.CreateInterface(updateInfo.DataStruct, updateInfo.Fields)
-		insertFields = dynamic.RowFields
-		for _, vi := range updateInfo.Values {
-			v, err := dynamic.CreateValues(vi[0])
-			if err != nil {
-				return nil, -1, err
+	nativeReturning := driver == common.SqliteType
+	upsertCmd := GenerateUpsert(driver, name, fields, keys, nil, upsert)
+	if nativeReturning {
+		upsertCmd = GenerateUpsert(driver, name, fields, keys, upsert.Returning, upsert)
+	}
+	returningCmd := GenerateReturning(driver, name, keys, upsert.Returning)
+	log.Log.Debugf("Upsert CMD: %s", upsertCmd)
+	returning := make([][]any, 0)
+	for _, v := range values {
+		var row *sql.Row
+		switch {
+		case len(upsert.Returning) == 0:
+			_, err = tx.ExecContext(ctx, upsertCmd, v...)
+		case nativeReturning:
+			row = tx.QueryRowContext(ctx, upsertCmd, v...)
+		default:
+			_, err = tx.ExecContext(ctx, upsertCmd, v...)
+			if err == nil {
+				keyValues := make([]any, 0, len(keys))
+				for _, k := range keys {
+					keyValues = append(keyValues, v[slices.Index(fields, k)])
+				}
+				row = tx.QueryRowContext(ctx, returningCmd, keyValues...)
 			}
-			insertValues = append(insertValues, v)
-			log.Log.Debugf("Row   fields: %#v", insertFields)
-			log.Log.Debugf("Value fields: %#v", insertValues)
 		}
-	} else {
-		insertValues = updateInfo.Values
-	}
-	for i, v := range insertValues {
-		whereClause := CreateWhere(i, updateInfo, whereFields)
-		ic := insertCmd + whereClause
-		log.Log.Debugf("Update CMD: %s", ic)
-		log.Log.Debugf("Update values: %d -> %#v", len(v), v)
-		res, err := tx.ExecContext(ctx, ic, v...)
+		if err == nil && row != nil {
+			var rv []any
+			rv, err = ScanReturning(row, upsert)
+			returning = append(returning, rv)
+		}
 		if err != nil {
-			log.Log.Debugf("Update error: %s -> %v", ic, err)
 			dbsql.EndTransaction(false)
-			return nil, 0, err
+			log.Log.Debugf("Error upsert CMD: %v of %s and cmd %s", err, name, upsertCmd)
+			return nil, err
 		}
-		ra, _ := res.RowsAffected()
-		rowsAffected += ra
 	}
-	log.Log.Debugf("Update done")
-
-	log.Log.Debugf("Transaction: %v", dbsql.IsTransaction())
 	if !dbsql.IsTransaction() {
-		log.Log.Debugf("No transaction, end and close")
 		err = dbsql.EndTransaction(true)
 		if err != nil {
 			log.Log.Debugf("Error transaction %v", err)
-			dbsql.Close()
-			return nil, 0, err
+			return nil, err
 		}
-		log.Log.Debugf("Close ...")
-		dbsql.Close()
-	} else {
-		log.Log.Debugf("Transaction, NO end and close")
 	}
+	return returning, nil
+}
 
-	return nil, rowsAffected, nil
+// Argument argument of a batch statement, the value of the row field with
+// the Index or, if Index is negative, the Value
+type Argument struct {
+	Index int
+	Value any
 }
 
-func CreateWhere(valueIndex int, updateInfo *common.Entries, whereFields []int) string {
+// rowValue parameter bound to the value of the row field with the index
+type rowValue int
+
+var comparisonRegexp = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_.]*)\s*(<=|>=|<>|!=|=|<|>)\s*(.*?)\s*$`)
+var placeholderRegexp = regexp.MustCompile(`^(\?|\$[0-9]+|:[A-Za-z_][A-Za-z0-9_]*)$`)
+var numberRegexp = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// GenerateBatchUpdate generate the UPDATE statement used for all value rows
+// of a batch. The Update entries naming a field compare the field with the
+// value of the row, these key fields are not updated. The values of the
+// comparisons in Update and the Parameters of Criteria are bound too. The
+// version field is incremented and must match the version of the row. The
+// arguments of all placeholders are returned.
+func GenerateBatchUpdate(driver common.ReferenceType, name string, fields []string, updateInfo *common.Entries) (string, []Argument, error) {
+	keys := updateKeys(fields, updateInfo.Update)
+	version := VersionIndex(fields, updateInfo)
 	var buffer bytes.Buffer
-	for i, x := range updateInfo.Update {
-		if strings.ContainsAny(x, "=<>") {
-			if i > 0 {
-				buffer.WriteString(" AND ")
+	buffer.WriteString("UPDATE " + name + " SET ")
+	arguments := make([]Argument, 0, len(fields))
+	set := 0
+	for i, f := range fields {
+		if slices.Contains(keys, i) {
+			continue
+		}
+		if set > 0 {
+			buffer.WriteString(",")
+		}
+		set++
+		if i == version {
+			buffer.WriteString(driverField(driver, f) + "=" + driverField(driver, f) + "+1")
+			continue
+		}
+		arguments = append(arguments, Argument{Index: i})
+		buffer.WriteString(driverField(driver, f) + "=" + driverPlaceholder(driver, len(arguments)))
+	}
+	if set == 0 {
+		return "", nil, errorrepo.NewError("DB000062", name)
+	}
+	where, whereArguments, err := updateCondition(driver, name, fields, updateInfo, len(arguments), version)
+	if err != nil {
+		return "", nil, err
+	}
+	buffer.WriteString(" WHERE " + where)
+	return buffer.String(), append(arguments, whereArguments...), nil
+}
+
+// updateKeys indexes of the fields named by the Update entries
+func updateKeys(fields, update []string) []int {
+	keys := make([]int, 0, len(update))
+	for _, u := range update {
+		if strings.ContainsAny(u, "=<>") {
+			continue
+		}
+		if k := slices.IndexFunc(fields, func(f string) bool { return strings.EqualFold(f, u) }); k >= 0 {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// VersionIndex index of the version field of the update in the fields, -1
+// if no version is checked
+func VersionIndex(fields []string, updateInfo *common.Entries) int {
+	version := updateInfo.VersionField()
+	if version == "" {
+		return -1
+	}
+	return slices.IndexFunc(fields, func(f string) bool { return strings.EqualFold(f, version) })
+}
+
+// StaleVersion error of an update of a value row affecting no record, nil
+// if the update checks no version
+func StaleVersion(name string, fields []string, updateInfo *common.Entries) error {
+	if VersionIndex(fields, updateInfo) < 0 {
+		return nil
+	}
+	return &common.StaleVersionError{TableName: name}
+}
+
+// updateCondition condition of the update combining the Update entries and
+// the Criteria, the placeholders are numbered after the offset. The field
+// with the version index must match the version of the row.
+func updateCondition(driver common.ReferenceType, name string, fields []string, updateInfo *common.Entries, offset, version int) (string, []Argument, error) {
+	parameters := append(make([]any, 0, len(updateInfo.Parameters)+len(updateInfo.Update)), updateInfo.Parameters...)
+	positional := 0
+	for _, p := range parameters {
+		if _, ok := p.(sql.NamedArg); !ok {
+			positional++
+		}
+	}
+	bind := func(value any) string {
+		parameters = append(parameters, value)
+		positional++
+		return "$" + strconv.Itoa(positional)
+	}
+	conditions := make([]string, 0, len(updateInfo.Update)+1)
+	for _, u := range updateInfo.Update {
+		if strings.ContainsAny(u, "=<>") {
+			condition, err := comparison(u, bind)
+			if err != nil {
+				return "", nil, err
 			}
-			buffer.WriteString(x)
+			conditions = append(conditions, condition)
+			continue
 		}
+		k := slices.IndexFunc(fields, func(f string) bool { return strings.EqualFold(f, u) })
+		if k < 0 {
+			return "", nil, errorrepo.NewError("DB000077", u, name)
+		}
+		conditions = append(conditions, driverField(driver, fields[k])+"="+bind(rowValue(k)))
 	}
-	for i, s := range whereFields {
-		if buffer.Len() > 0 || i > 0 {
-			buffer.WriteString(" AND ")
+	if updateInfo.Criteria != "" {
+		if len(conditions) > 0 {
+			conditions = append(conditions, "("+updateInfo.Criteria+")")
+		} else {
+			conditions = append(conditions, updateInfo.Criteria)
 		}
-		buffer.WriteString(`"` + strings.ToLower(updateInfo.Fields[s]) + `"`)
-		buffer.WriteRune('=')
-		buffer.WriteString(convertString(updateInfo.Values[valueIndex][s]))
 	}
-	return buffer.String()
+	if len(conditions) == 0 {
+		return "", nil, errorrepo.NewError("DB000042", name)
+	}
+	if version >= 0 {
+		conditions = append(conditions, driverField(driver, fields[version])+"="+bind(rowValue(version)))
+	}
+	where, args, err := common.BindParametersOffset(driver, strings.Join(conditions, " AND "), parameters, offset)
+	if err != nil {
+		return "", nil, err
+	}
+	arguments := make([]Argument, 0, len(args))
+	for _, a := range args {
+		if k, ok := a.(rowValue); ok {
+			arguments = append(arguments, Argument{Index: int(k)})
+			continue
+		}
+		arguments = append(arguments, Argument{Index: -1, Value: a})
+	}
+	return where, arguments, nil
 }
 
-func convertString(convertToString any) string {
-	x := fmt.Sprintf("%v", convertToString)
-	switch convertToString.(type) {
-	case *string, string:
-		return `'` + x + `'`
+// comparison condition of the comparison of a field with a value in
+// Update. Quoted strings are bound as parameter, a placeholder refers to
+// the Parameters of the entries. Numbers and field names stay part of the
+// condition, so `A=B` compares the fields A and B. Other values are
+// rejected.
+func comparison(update string, bind func(value any) string) (string, error) {
+	m := comparisonRegexp.FindStringSubmatch(update)
+	if m == nil || m[3] == "" {
+		return "", errorrepo.NewError("DB000064", update)
+	}
+	field, operator, value := m[1], m[2], m[3]
+	if err := common.ValidateField(field); err != nil {
+		return "", err
 	}
-	return x
+	if operator == "!=" {
+		operator = "<>"
+	}
+	switch {
+	case placeholderRegexp.MatchString(value):
+		return field + operator + value, nil
+	case strings.EqualFold(value, "NULL") && operator == "=":
+		return field + " IS NULL", nil
+	case strings.EqualFold(value, "NULL") && operator == "<>":
+		return field + " IS NOT NULL", nil
+	case len(value) > 1 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0]:
+		q := string(value[0])
+		return field + operator + bind(strings.ReplaceAll(value[1:len(value)-1], q+q, q)), nil
+	}
+	if !numberRegexp.MatchString(value) && common.ValidateField(value) != nil {
+		return "", errorrepo.NewError("DB000064", update)
+	}
+	return field + operator + value, nil
 }
 
-func Delete(dbsql DBsql, name string, updateInfo *common.Entries) (rowsAffected int64, err error) {
-	tx, ctx, err := dbsql.StartTransaction()
+// BatchUpdateArguments arguments of the batch update statement for the
+// value row
+func BatchUpdateArguments(row []any, arguments []Argument) []any {
+	args := make([]any, 0, len(arguments))
+	for _, a := range arguments {
+		if a.Index < 0 {
+			args = append(args, a.Value)
+			continue
+		}
+		args = append(args, row[a.Index])
+	}
+	return args
+}
+
+// GenerateBatchDelete generate the DELETE statement used for all value rows
+// of a batch. Fields starting with `%` are compared using LIKE.
+func GenerateBatchDelete(driver common.ReferenceType, name string, fields []string) (string, error) {
+	if len(fields) == 0 {
+		return "", errorrepo.NewError("DB000061", name)
+	}
+	conditions := make([]string, 0, len(fields))
+	for i, f := range fields {
+		if like, ok := strings.CutPrefix(f, "%"); ok {
+			conditions = append(conditions, "("+driverField(driver, like)+" LIKE "+driverPlaceholder(driver, i+1)+")")
+			continue
+		}
+		conditions = append(conditions, driverField(driver, f)+"="+driverPlaceholder(driver, i+1))
+	}
+	return "DELETE FROM " + name + " WHERE " + strings.Join(conditions, " AND "), nil
+}
+
+// GenerateCriteriaDelete generate the DELETE statement of the Criteria
+// with the Parameters bound to the placeholders
+func GenerateCriteriaDelete(driver common.ReferenceType, name string, remove *common.Entries) (string, []any, error) {
+	return common.BindParameters(driver, "DELETE FROM "+name+" WHERE "+remove.Criteria, remove.Parameters)
+}
+
+// BatchUpdateContext update all value rows using one prepared statement
+// and return the rows affected by each value row
+func BatchUpdateContext(ctx context.Context, dbsql DBsql, driver common.ReferenceType, name string, updateInfo *common.Entries) ([]int64, error) {
+	fields, values, err := updateInfo.InsertValues()
 	if err != nil {
-		return -1, err
+		return nil, err
 	}
-	if !dbsql.IsTransaction() {
-		defer dbsql.Close()
+	updateCmd, arguments, err := GenerateBatchUpdate(driver, name, fields, updateInfo)
+	if err != nil {
+		return nil, err
 	}
+	args := make([][]any, 0, len(values))
+	for _, v := range values {
+		args = append(args, BatchUpdateArguments(v, arguments))
+	}
+	return execPrepared(ctx, dbsql, updateCmd, args, StaleVersion(name, fields, updateInfo))
+}
 
-	if updateInfo.Criteria != "" {
-		deleteCmd := "DELETE FROM " + name + " WHERE " + updateInfo.Criteria
+// BatchDeleteContext delete the records of all value rows using one
+// prepared statement and return the rows affected by each value row. A
+// delete using Criteria returns the rows affected by the criteria.
+func BatchDeleteContext(ctx context.Context, dbsql DBsql, driver common.ReferenceType, name string, remove *common.Entries) ([]int64, error) {
+	if remove.Criteria != "" {
+		deleteCmd, args, err := GenerateCriteriaDelete(driver, name, remove)
+		if err != nil {
+			return nil, err
+		}
+		return execPrepared(ctx, dbsql, deleteCmd, [][]any{args}, nil)
+	}
+	deleteCmd, err := GenerateBatchDelete(driver, name, remove.Fields)
+	if err != nil {
+		return nil, err
+	}
+	return execPrepared(ctx, dbsql, deleteCmd, remove.Values, nil)
+}
 
-		log.Log.Debugf("Delete cmd: %s", deleteCmd)
-		res, err := tx.ExecContext(ctx, deleteCmd)
+// execPrepared execute the prepared statement with the arguments of each
+// row and return the rows affected by each row. The stale error is
+// returned if a row affects no record, nil disables the check.
+func execPrepared(ctx context.Context, dbsql DBsql, statement string, args [][]any, stale error) ([]int64, error) {
+	tx, _, err := dbsql.StartTransaction()
+	if err != nil {
+		return nil, err
+	}
+	if !dbsql.IsTransaction() {
+		defer dbsql.Close()
+	}
+	log.Log.Debugf("Prepare batch CMD: %s", statement)
+	stmt, err := tx.PrepareContext(ctx, statement)
+	if err != nil {
+		dbsql.EndTransaction(false)
+		return nil, err
+	}
+	defer stmt.Close()
+	rowsAffected := make([]int64, 0, len(args))
+	for _, a := range args {
+		res, err := stmt.ExecContext(ctx, a...)
 		if err != nil {
-			log.Log.Debugf("Delete error: %v", err)
+			log.Log.Debugf("Batch error: %s -> %v", statement, err)
 			dbsql.EndTransaction(false)
-			return -1, err
+			return nil, err
 		}
-
 		ra, _ := res.RowsAffected()
-		rowsAffected += ra
-	} else {
-		for i := 0; i < len(updateInfo.Values); i++ {
-			deleteCmd, av := GenerateDelete(dbsql.IndexNeeded(), name, 0, updateInfo)
-			log.Log.Debugf("Delete cmd: %s -> %#v", deleteCmd, av)
-			res, err := tx.ExecContext(ctx, deleteCmd, av...)
-			if err != nil {
-				log.Log.Debugf("Delete error: %v", err)
-				dbsql.EndTransaction(false)
-				return -1, err
-			}
-			ra, _ := res.RowsAffected()
-			rowsAffected += ra
+		if ra == 0 && stale != nil {
+			log.Log.Debugf("Stale version: %s", statement)
+			dbsql.EndTransaction(false)
+			return nil, stale
 		}
+		rowsAffected = append(rowsAffected, ra)
 	}
-	err = dbsql.EndTransaction(true)
-	if err != nil {
-		return -1, err
+	if !dbsql.IsTransaction() {
+		err = dbsql.EndTransaction(true)
+		if err != nil {
+			log.Log.Debugf("Error transaction %v", err)
+			return nil, err
+		}
 	}
-	log.Log.Debugf("Delete done")
-	return
+	return rowsAffected, nil
 }
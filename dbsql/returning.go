@@ -0,0 +1,231 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+// rowScanner single row or current row of a query result
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// returningDestinations destinations of the returning fields and the
+// function creating the returned row after the destinations are filled.
+// With data structure the returned row contains the structure, otherwise
+// the field values as strings.
+func returningDestinations(entries *common.Entries) ([]any, func() ([]any, error), error) {
+	if entries.DataStruct != nil {
+		typeInfo := common.CreateInterface(entries.DataStruct, entries.Returning)
+		vd, err := typeInfo.CreateQueryValues()
+		if err != nil {
+			return nil, nil, err
+		}
+		return vd.ScanValues, func() ([]any, error) {
+			err := vd.ShiftValues()
+			if err != nil {
+				return nil, err
+			}
+			return []any{vd.Copy}, nil
+		}, nil
+	}
+	values := make([]sql.NullString, len(entries.Returning))
+	dest := make([]any, 0, len(values))
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	return dest, func() ([]any, error) {
+		rv := make([]any, 0, len(values))
+		for _, v := range values {
+			if v.Valid {
+				rv = append(rv, v.String)
+			} else {
+				rv = append(rv, nil)
+			}
+		}
+		return rv, nil
+	}, nil
+}
+
+// ScanReturning scan the returning fields of the row. With data structure
+// the returned row contains the structure, otherwise the field values as
+// strings.
+func ScanReturning(row rowScanner, entries *common.Entries) ([]any, error) {
+	dest, finish, err := returningDestinations(entries)
+	if err != nil {
+		return nil, err
+	}
+	err = row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	return finish()
+}
+
+// queryReturning query the returning fields of all rows of the statement
+func queryReturning(ctx context.Context, tx *sql.Tx, statement string, args []any, entries *common.Entries) ([][]any, error) {
+	log.Log.Debugf("Returning CMD: %s", statement)
+	rows, err := tx.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	returning := make([][]any, 0)
+	for rows.Next() {
+		rv, err := ScanReturning(rows, entries)
+		if err != nil {
+			return nil, err
+		}
+		returning = append(returning, rv)
+	}
+	return returning, rows.Err()
+}
+
+// execReturningInto execute the Oracle statement returning the fields into
+// out-bind parameters, only one row can be returned
+func execReturningInto(ctx context.Context, tx *sql.Tx, statement string, args []any, entries *common.Entries) ([]any, int64, error) {
+	dest, finish, err := returningDestinations(entries)
+	if err != nil {
+		return nil, 0, err
+	}
+	outArgs := make([]any, 0, len(args)+len(dest))
+	outArgs = append(outArgs, args...)
+	into := make([]string, 0, len(dest))
+	for _, d := range dest {
+		outArgs = append(outArgs, sql.Out{Dest: d})
+		into = append(into, ":"+strconv.Itoa(len(outArgs)))
+	}
+	statement += " RETURNING " + strings.Join(entries.Returning, ",") + " INTO " + strings.Join(into, ",")
+	log.Log.Debugf("Returning CMD: %s", statement)
+	res, err := tx.ExecContext(ctx, statement, outArgs...)
+	if err != nil {
+		return nil, 0, err
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return nil, 0, nil
+	}
+	rv, err := finish()
+	return rv, rowsAffected, err
+}
+
+// insertReturning insert the record and return the returning fields.
+// MySQL reads the fields after the insert using the first returning field
+// as key, its value is the inserted value or the auto increment value.
+func insertReturning(ctx context.Context, tx *sql.Tx, driver common.ReferenceType, name, insertCmd string,
+	fields []string, values []any, insert *common.Entries) ([]any, error) {
+	switch driver {
+	case common.PostgresType, common.SqliteType:
+		row := tx.QueryRowContext(ctx, insertCmd+" RETURNING "+strings.Join(insert.Returning, ","), values...)
+		return ScanReturning(row, insert)
+	case common.OracleType:
+		rv, _, err := execReturningInto(ctx, tx, insertCmd, values, insert)
+		return rv, err
+	}
+	res, err := tx.ExecContext(ctx, insertCmd, values...)
+	if err != nil {
+		return nil, err
+	}
+	var key any
+	if k := slices.IndexFunc(fields, func(f string) bool { return strings.EqualFold(f, insert.Returning[0]) }); k >= 0 {
+		key = values[k]
+	} else {
+		key, err = res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+	}
+	row := tx.QueryRowContext(ctx, GenerateReturning(driver, name, insert.Returning[:1], insert.Returning), key)
+	return ScanReturning(row, insert)
+}
+
+// updateReturning update the records and return the returning fields of all
+// updated records. MySQL reads the fields after the update using the update
+// condition.
+func updateReturning(ctx context.Context, dbsql DBsql, driver common.ReferenceType, name string, updateInfo *common.Entries) ([][]any, int64, error) {
+	fields, values, err := updateInfo.InsertValues()
+	if err != nil {
+		return nil, -1, err
+	}
+	updateCmd, arguments, err := GenerateBatchUpdate(driver, name, fields, updateInfo)
+	if err != nil {
+		return nil, -1, err
+	}
+	selectCmd := ""
+	var selectArguments []Argument
+	if driver == common.MysqlType {
+		var where string
+		where, selectArguments, err = updateCondition(driver, name, fields, updateInfo, 0, -1)
+		if err != nil {
+			return nil, -1, err
+		}
+		selectCmd = "SELECT " + strings.Join(updateInfo.Returning, ",") + " FROM " + name + " WHERE " + where
+	}
+	tx, _, err := dbsql.StartTransaction()
+	if err != nil {
+		return nil, -1, err
+	}
+	if !dbsql.IsTransaction() {
+		defer dbsql.Close()
+	}
+	stale := StaleVersion(name, fields, updateInfo)
+	returning := make([][]any, 0)
+	rowsAffected := int64(0)
+	for _, v := range values {
+		args := BatchUpdateArguments(v, arguments)
+		var rows [][]any
+		var ra int64
+		switch driver {
+		case common.PostgresType, common.SqliteType:
+			rows, err = queryReturning(ctx, tx, updateCmd+" RETURNING "+strings.Join(updateInfo.Returning, ","), args, updateInfo)
+			ra = int64(len(rows))
+		case common.OracleType:
+			var rv []any
+			rv, ra, err = execReturningInto(ctx, tx, updateCmd, args, updateInfo)
+			if rv != nil {
+				rows = [][]any{rv}
+			}
+		default:
+			var res sql.Result
+			res, err = tx.ExecContext(ctx, updateCmd, args...)
+			if err == nil {
+				ra, _ = res.RowsAffected()
+				rows, err = queryReturning(ctx, tx, selectCmd, BatchUpdateArguments(v, selectArguments), updateInfo)
+			}
+		}
+		if err == nil && ra == 0 && stale != nil {
+			err = stale
+		}
+		rowsAffected += ra
+		if err != nil {
+			log.Log.Debugf("Update error: %s -> %v", updateCmd, err)
+			dbsql.EndTransaction(false)
+			return nil, 0, err
+		}
+		returning = append(returning, rows...)
+	}
+	if !dbsql.IsTransaction() {
+		err = dbsql.EndTransaction(true)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return returning, rowsAffected, nil
+}
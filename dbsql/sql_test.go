@@ -12,6 +12,7 @@
 package dbsql
 
 import (
+	"database/sql"
 	"fmt"
 	"os"
 	"sync"
@@ -73,55 +74,63 @@ func TestSQLUpdate(t *testing.T) {
 	ui := &common.Entries{
 		Fields: []string{"ABC", "BCD", "YYY"},
 		Update: []string{"ABC"},
-		Values: [][]any{{"abc", 123, 233}},
+		Values: [][]any{{"a'bc", 123, 233}},
 	}
-	sqlCmd, rows := GenerateUpdate(true, "ABC", ui)
-	assert.Equal(t, "UPDATE ABC SET \"abc\"=$1,\"bcd\"=$2,\"yyy\"=$3 WHERE ", sqlCmd)
-	assert.Equal(t, []int{0}, rows)
-	wh := CreateWhere(0, ui, rows)
-	assert.Equal(t, "\"abc\"='abc'", wh)
-
-	ui.Update[0] = "BCD"
-	sqlCmd, rows = GenerateUpdate(true, "DFX", ui)
-	assert.Equal(t, "UPDATE DFX SET \"abc\"=$1,\"bcd\"=$2,\"yyy\"=$3 WHERE ", sqlCmd)
-	assert.Equal(t, []int{1}, rows)
-	wh = CreateWhere(0, ui, rows)
-	assert.Equal(t, "\"bcd\"=123", wh)
+	sqlCmd, arguments, err := GenerateBatchUpdate(common.PostgresType, "ABC", ui.Fields, ui)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE ABC SET \"bcd\"=$1,\"yyy\"=$2 WHERE \"abc\"=$3", sqlCmd)
+	assert.Equal(t, []any{123, 233, "a'bc"}, BatchUpdateArguments(ui.Values[0], arguments))
 
 	ui.Update[0] = "BCXD=hugo"
-	sqlCmd, rows = GenerateUpdate(true, "Table1", ui)
-	assert.Equal(t, "UPDATE Table1 SET \"abc\"=$1,\"bcd\"=$2,\"yyy\"=$3 WHERE ", sqlCmd)
-	assert.Equal(t, []int{}, rows)
-	wh = CreateWhere(0, ui, rows)
-	assert.Equal(t, "BCXD=hugo", wh)
-
-	ui.Update[0] = "DDD=emil"
-	ui.Update = append(ui.Update, "YYY")
-	sqlCmd, rows = GenerateUpdate(true, "Table2", ui)
-	assert.Equal(t, "UPDATE Table2 SET \"abc\"=$1,\"bcd\"=$2,\"yyy\"=$3 WHERE ", sqlCmd)
-	assert.Equal(t, []int{2}, rows)
-	wh = CreateWhere(0, ui, rows)
-	assert.Equal(t, "DDD=emil AND \"yyy\"=233", wh)
-
-	ui.Update[0] = "YYY=emil"
-	ui.Update = append(ui.Update, "ABC")
-	ui.Update = append(ui.Update, "WWW=abc")
-	sqlCmd, rows = GenerateUpdate(true, "Table3", ui)
-	assert.Equal(t, "UPDATE Table3 SET \"abc\"=$1,\"bcd\"=$2,\"yyy\"=$3 WHERE ", sqlCmd)
-	assert.Equal(t, []int{0, 2}, rows)
-	wh = CreateWhere(0, ui, rows)
-	assert.Equal(t, "YYY=emil AND WWW=abc AND \"abc\"='abc' AND \"yyy\"=233", wh)
+	sqlCmd, arguments, err = GenerateBatchUpdate(common.PostgresType, "Table1", ui.Fields, ui)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE Table1 SET \"abc\"=$1,\"bcd\"=$2,\"yyy\"=$3 WHERE BCXD=hugo", sqlCmd)
+	assert.Equal(t, []any{"a'bc", 123, 233}, BatchUpdateArguments(ui.Values[0], arguments))
+
+	ui.Update = []string{"YYY='emil''s'", "ABC", "WWW <> 1.5", "ZZZ=NULL"}
+	sqlCmd, arguments, err = GenerateBatchUpdate(common.MysqlType, "Table3", ui.Fields, ui)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE Table3 SET `bcd`=?,`yyy`=? WHERE YYY=? AND `abc`=? AND WWW<>1.5 AND ZZZ IS NULL", sqlCmd)
+	assert.Equal(t, []any{123, 233, "emil's", "a'bc"}, BatchUpdateArguments(ui.Values[0], arguments))
 
 	ui.Fields = []string{"AA", "BB", "CC", "DD", "TT"}
-	ui.Values = [][]any{{"XXX", "daslkds", 123, 222, 222, time.Now()}, {"XXX2", "aaa2", 51, 522, 5222, time.Now()}}
-	ui.Update = []string{"YY=otto", "AA", "CC", "TT"}
-	sqlCmd, rows = GenerateUpdate(true, "Table4", ui)
-	assert.Equal(t, "UPDATE Table4 SET \"aa\"=$1,\"bb\"=$2,\"cc\"=$3,\"dd\"=$4,\"tt\"=$5 WHERE ", sqlCmd)
-	assert.Equal(t, []int{0, 2, 4}, rows)
-	wh = CreateWhere(0, ui, rows)
-	assert.Equal(t, "YY=otto AND \"aa\"='XXX' AND \"cc\"=123 AND \"tt\"=222", wh)
-	wh = CreateWhere(1, ui, rows)
-	assert.Equal(t, "YY=otto AND \"aa\"='XXX2' AND \"cc\"=51 AND \"tt\"=5222", wh)
+	now := time.Now()
+	ui.Values = [][]any{{"XXX", "daslkds", 123, 222, now}, {"XXX2", "aaa2", 51, 522, now}}
+	ui.Update = []string{"YY=?", "AA", "CC"}
+	ui.Criteria = "DD > :dd OR TT < ?"
+	ui.Parameters = []any{"otto", sql.Named("dd", 10), now}
+	sqlCmd, arguments, err = GenerateBatchUpdate(common.OracleType, "Table4", ui.Fields, ui)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE Table4 SET BB=:1,DD=:2,TT=:3 WHERE YY=:4 AND AA=:5 AND CC=:6 AND (DD > :7 OR TT < :8)", sqlCmd)
+	assert.Equal(t, []any{"aaa2", 522, now, "otto", "XXX2", 51, 10, now}, BatchUpdateArguments(ui.Values[1], arguments))
+
+	ui.Update = nil
+	ui.Criteria = "DD = 1"
+	ui.Parameters = nil
+	sqlCmd, _, err = GenerateBatchUpdate(common.SqliteType, "Table5", ui.Fields, ui)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE Table5 SET `aa`=?,`bb`=?,`cc`=?,`dd`=?,`tt`=? WHERE DD = 1", sqlCmd)
+
+	ui.Criteria = ""
+	ui.Update = []string{"AA=1; DROP TABLE Table6"}
+	_, _, err = GenerateBatchUpdate(common.SqliteType, "Table6", ui.Fields, ui)
+	assert.ErrorContains(t, err, "DB000064")
+
+	ui.Update = []string{"AA='1; DROP TABLE Table6'"}
+	sqlCmd, arguments, err = GenerateBatchUpdate(common.SqliteType, "Table6", ui.Fields, ui)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE Table6 SET `aa`=?,`bb`=?,`cc`=?,`dd`=?,`tt`=? WHERE AA=?", sqlCmd)
+	assert.Equal(t, "1; DROP TABLE Table6", BatchUpdateArguments(ui.Values[0], arguments)[5])
+
+	ui.Update = []string{"AA=BB", "CC>=-2", "DD<1e3"}
+	sqlCmd, arguments, err = GenerateBatchUpdate(common.SqliteType, "Table6", ui.Fields, ui)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE Table6 SET `aa`=?,`bb`=?,`cc`=?,`dd`=?,`tt`=? WHERE AA=BB AND CC>=-2 AND DD<1e3", sqlCmd)
+	assert.Len(t, arguments, 5)
+
+	ui.Update = []string{"A-B=1"}
+	_, _, err = GenerateBatchUpdate(common.SqliteType, "Table7", ui.Fields, ui)
+	assert.Error(t, err)
 }
 
 func TestSQLDelete(t *testing.T) {
@@ -141,6 +150,141 @@ func TestSQLDelete(t *testing.T) {
 	ui.Fields = []string{"ABC", "BCD", "%YYY"}
 	ui.Values = [][]any{{"abc", 123, "XXX%"}}
 	sqlCmd, rows = GenerateDelete(false, "TABLENAME", 0, ui)
-	assert.Equal(t, "DELETE FROM TABLENAME WHERE abc IN (?) AND bcd IN (?) AND (YYY LIKE 'XXX%')", sqlCmd)
-	assert.Equal(t, []interface{}{"abc", 123}, rows)
+	assert.Equal(t, "DELETE FROM TABLENAME WHERE abc IN (?) AND bcd IN (?) AND (YYY LIKE ?)", sqlCmd)
+	assert.Equal(t, []interface{}{"abc", 123, "XXX%"}, rows)
+
+	ui.Fields = []string{"%YYY", "ABC"}
+	ui.Values = [][]any{{"X'; DROP TABLE X; --", "abc"}}
+	sqlCmd, rows = GenerateDelete(true, "TABLENAME", 0, ui)
+	assert.Equal(t, "DELETE FROM TABLENAME WHERE (YYY LIKE $1) AND abc IN ($2)", sqlCmd)
+	assert.Equal(t, []interface{}{"X'; DROP TABLE X; --", "abc"}, rows)
+}
+
+type upsertRecord struct {
+	ID    string `flynn:"ID:key"`
+	Name  string
+	Count int
+}
+
+type upsertStamped struct {
+	ID      string `flynn:"ID:key"`
+	Name    string
+	Created time.Time `flynn:"Created:created"`
+}
+
+func TestSQLUpsert(t *testing.T) {
+	InitLog(t)
+
+	ui := &common.Entries{DataStruct: &upsertRecord{}, Fields: []string{"*"},
+		Values: [][]any{{&upsertRecord{ID: "A1", Name: "Anna", Count: 3}}}}
+	fields, values, keys, err := ui.UpsertValues("TABLENAME")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"ID", "Name", "Count"}, fields)
+	assert.Equal(t, [][]any{{"A1", "Anna", 3}}, values)
+	assert.Equal(t, []string{"ID"}, keys)
+
+	assert.Equal(t, `INSERT INTO TABLENAME ("id","name","count") VALUES ($1,$2,$3) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name","count"=EXCLUDED."count" RETURNING ID,Count`,
+		GenerateUpsert(common.PostgresType, "TABLENAME", fields, keys, []string{"ID", "Count"}, ui))
+	assert.Equal(t, "INSERT INTO TABLENAME (`id`,`name`,`count`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`count`=VALUES(`count`)",
+		GenerateUpsert(common.MysqlType, "TABLENAME", fields, keys, []string{"ID"}, ui))
+	assert.Equal(t, "MERGE INTO TABLENAME t USING (SELECT :1 ID,:2 Name,:3 Count FROM dual) s ON (t.ID=s.ID) WHEN MATCHED THEN UPDATE SET t.Name=s.Name,t.Count=s.Count WHEN NOT MATCHED THEN INSERT (ID,Name,Count) VALUES (s.ID,s.Name,s.Count)",
+		GenerateUpsert(common.OracleType, "TABLENAME", fields, keys, nil, ui))
+	assert.Equal(t, "INSERT INTO TABLENAME (`id`,`name`) VALUES (?,?) ON CONFLICT (`id`,`name`) DO UPDATE SET `id`=EXCLUDED.`id`",
+		GenerateUpsert(common.SqliteType, "TABLENAME", []string{"ID", "Name"}, []string{"ID", "Name"}, nil, nil))
+	versioned := &common.Entries{Version: "Count"}
+	assert.Equal(t, `INSERT INTO s.TABLENAME ("id","name","count") VALUES ($1,$2,$3) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name","count"=TABLENAME."count"+1`,
+		GenerateUpsert(common.PostgresType, "s.TABLENAME", fields, keys, nil, versioned))
+	assert.Equal(t, "INSERT INTO TABLENAME (`id`,`name`,`count`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`count`=`count`+1",
+		GenerateUpsert(common.MysqlType, "TABLENAME", fields, keys, nil, versioned))
+	assert.Equal(t, "MERGE INTO TABLENAME t USING (SELECT :1 ID,:2 Name,:3 Count FROM dual) s ON (t.ID=s.ID) WHEN MATCHED THEN UPDATE SET t.Name=s.Name,t.Count=t.Count+1 WHEN NOT MATCHED THEN INSERT (ID,Name,Count) VALUES (s.ID,s.Name,s.Count)",
+		GenerateUpsert(common.OracleType, "TABLENAME", fields, keys, nil, versioned))
+	stamped := &common.Entries{DataStruct: &upsertStamped{}}
+	assert.Equal(t, `INSERT INTO TABLENAME ("id","name","created") VALUES ($1,$2,$3) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name"`,
+		GenerateUpsert(common.PostgresType, "TABLENAME", []string{"ID", "Name", "Created"}, keys, nil, stamped))
+	assert.Equal(t, "MERGE INTO TABLENAME t USING (SELECT :1 ID,:2 Created FROM dual) s ON (t.ID=s.ID) WHEN NOT MATCHED THEN INSERT (ID,Created) VALUES (s.ID,s.Created)",
+		GenerateUpsert(common.OracleType, "TABLENAME", []string{"ID", "Created"}, keys, nil, stamped))
+	assert.Equal(t, "SELECT Count FROM TABLENAME WHERE ID=:1",
+		GenerateReturning(common.OracleType, "TABLENAME", keys, []string{"Count"}))
+
+	_, _, _, err = (&common.Entries{Fields: []string{"ABC"}, Values: [][]any{{1}}}).UpsertValues("TABLENAME")
+	assert.Error(t, err)
+	_, _, _, err = (&common.Entries{Fields: []string{"ABC"}, Update: []string{"XYZ"},
+		Values: [][]any{{1}}}).UpsertValues("TABLENAME")
+	assert.Error(t, err)
+}
+
+func TestSQLBulkInsert(t *testing.T) {
+	InitLog(t)
+
+	rows := [][]any{{"A", 1}, {"B", 2}, {"C", nil}}
+	sqlCmd, args := GenerateBulkInsert(common.MysqlType, "TABLENAME", []string{"ID", "Counter"}, rows)
+	assert.Equal(t, "INSERT INTO TABLENAME (`id`,`counter`) VALUES (?,?),(?,?),(?,?)", sqlCmd)
+	assert.Equal(t, []any{"A", 1, "B", 2, "C", nil}, args)
+
+	sqlCmd, args = GenerateBulkInsert(common.OracleType, "TABLENAME", []string{"ID", "Counter"}, rows)
+	assert.Equal(t, "INSERT INTO TABLENAME (ID,Counter) VALUES (:1,:2)", sqlCmd)
+	assert.Equal(t, []any{[]string{"A", "B", "C"}, []sql.NullInt64{{Int64: 1, Valid: true},
+		{Int64: 2, Valid: true}, {}}}, args)
+
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	nullRows := [][]any{{nil, 1.5, ts, []byte{1}, nil}, {"B", nil, nil, nil, nil}}
+	_, args = GenerateBulkInsert(common.OracleType, "TABLENAME", []string{"ID", "F", "T", "B", "N"}, nullRows)
+	assert.Equal(t, []any{[]string{"", "B"}, []sql.NullFloat64{{Float64: 1.5, Valid: true}, {}},
+		[]sql.NullTime{{Time: ts, Valid: true}, {}}, [][]byte{{1}, nil}, []string{"", ""}}, args)
+	assert.True(t, arrayBinding(nullRows, 5))
+	assert.False(t, arrayBinding([][]any{{"A", true}, {"B", nil}}, 2))
+	assert.False(t, arrayBinding([][]any{{"A", 1}, {"B", "2"}}, 2))
+	assert.True(t, arrayBinding([][]any{{"A", true}, {"B", false}}, 2))
+
+	assert.Equal(t, 1000, bulkBatchSize(common.OracleType, 100))
+	assert.Equal(t, 655, bulkBatchSize(common.MysqlType, 100))
+	assert.Equal(t, 1, bulkBatchSize(common.SqliteType, 40000))
+}
+
+func TestSQLBatchUpdate(t *testing.T) {
+	InitLog(t)
+
+	fields := []string{"ID", "Name", "Count"}
+	sqlCmd, arguments, err := GenerateBatchUpdate(common.PostgresType, "TABLENAME", fields,
+		&common.Entries{Update: []string{"id", "Count > 0"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `UPDATE TABLENAME SET "name"=$1,"count"=$2 WHERE "id"=$3 AND Count>0`, sqlCmd)
+	assert.Equal(t, []any{"Anna", 3, "A1"}, BatchUpdateArguments([]any{"A1", "Anna", 3}, arguments))
+
+	sqlCmd, _, err = GenerateBatchUpdate(common.MysqlType, "TABLENAME", fields, &common.Entries{Update: []string{"ID", "Name"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE TABLENAME SET `count`=? WHERE `id`=? AND `name`=?", sqlCmd)
+
+	_, _, err = GenerateBatchUpdate(common.MysqlType, "TABLENAME", fields[:1], &common.Entries{Update: []string{"ID"}})
+	assert.Error(t, err)
+
+	_, _, err = GenerateBatchUpdate(common.MysqlType, "TABLENAME", fields, &common.Entries{})
+	assert.Error(t, err)
+
+	// a misspelled key must not widen the condition
+	_, _, err = GenerateBatchUpdate(common.MysqlType, "TABLENAME", fields, &common.Entries{Update: []string{"ID", "Nmae"}})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "DB000077")
+	}
+
+	sqlCmd, arguments, err = GenerateBatchUpdate(common.PostgresType, "TABLENAME", fields,
+		&common.Entries{Update: []string{"ID"}, Version: "count"})
+	assert.NoError(t, err)
+	assert.Equal(t, `UPDATE TABLENAME SET "name"=$1,"count"="count"+1 WHERE "id"=$2 AND "count"=$3`, sqlCmd)
+	assert.Equal(t, []any{"Anna", "A1", 3}, BatchUpdateArguments([]any{"A1", "Anna", 3}, arguments))
+	assert.ErrorIs(t, StaleVersion("TABLENAME", fields, &common.Entries{Version: "count"}), common.ErrStaleVersion)
+	assert.NoError(t, StaleVersion("TABLENAME", fields, &common.Entries{}))
+
+	sqlCmd, err = GenerateBatchDelete(common.OracleType, "TABLENAME", []string{"ID", "%Name"})
+	assert.NoError(t, err)
+	assert.Equal(t, "DELETE FROM TABLENAME WHERE ID=:1 AND (Name LIKE :2)", sqlCmd)
+	_, err = GenerateBatchDelete(common.OracleType, "TABLENAME", nil)
+	assert.Error(t, err)
+
+	sqlCmd, rows := GenerateDelete(true, "TABLENAME", 1, &common.Entries{Fields: []string{"ID"},
+		Values: [][]any{{"A1"}, {"B2"}}})
+	assert.Equal(t, "DELETE FROM TABLENAME WHERE id IN ($1)", sqlCmd)
+	assert.Equal(t, []any{"B2"}, rows)
 }
@@ -0,0 +1,110 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+// Queryer query interface of the database or the transaction
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// StreamBlocks streaming data from a field block by block. Each block is
+// read using substr at its offset, the length of the field is read with
+// the first block. Without block size the field is read in one block.
+func StreamBlocks(ctx context.Context, db Queryer, driver common.ReferenceType, search *common.Query, sf common.StreamFunction) error {
+	if len(search.Fields) == 0 {
+		return errorrepo.NewError("DB000012")
+	}
+	field := search.Fields[0]
+	where := ""
+	if search.Search != "" {
+		where = " WHERE " + search.Search
+	}
+	offset := int64(1)
+	blocksize := int64(search.Blocksize)
+	if blocksize <= 0 {
+		blocksize = math.MaxInt32
+	}
+	length := int64(-1)
+
+	log.Log.Debugf("Start stream for %s for %s", field, search.TableName)
+	selectCmd := fmt.Sprintf("SELECT substr(%s, %d, %d),length(%s) FROM %s%s",
+		field, offset, blocksize, field, search.TableName, where)
+	for {
+		log.Log.Debugf("Query: %s", selectCmd)
+		stream := &common.Stream{Data: make([]byte, 0)}
+		err := streamBlock(ctx, db, driver, selectCmd, search.Parameters, stream, &length)
+		if err != nil {
+			return err
+		}
+		err = sf(search, stream)
+		if err != nil {
+			log.Log.Errorf("stream function error: %s", err)
+			return err
+		}
+		offset += blocksize
+		if offset > length {
+			break
+		}
+		if offset+blocksize > length {
+			blocksize = length - offset + 1
+		}
+		selectCmd = fmt.Sprintf("SELECT substr(%s, %d, %d) FROM %s%s",
+			field, offset, blocksize, search.TableName, where)
+	}
+	return nil
+}
+
+// streamBlock read one block of the stream, the length of the field is
+// read with the first block only
+func streamBlock(ctx context.Context, db Queryer, driver common.ReferenceType, selectCmd string,
+	parameters []any, stream *common.Stream, length *int64) error {
+	selectCmd, args, err := common.BindParameters(driver, selectCmd, parameters)
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, selectCmd, args...)
+	if err != nil {
+		log.Log.Errorf("Stream query error: %v", err)
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		log.Log.Errorf("rows missing")
+		return errorrepo.NewError("DB000021")
+	}
+	if *length < 0 {
+		var fieldLength sql.NullInt64
+		err = rows.Scan(&stream.Data, &fieldLength)
+		*length = fieldLength.Int64
+	} else {
+		err = rows.Scan(&stream.Data)
+	}
+	if err != nil {
+		log.Log.Errorf("rows scan error: %s", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package dbsql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+// txDBsql database used inside a transaction handle. The insert, update
+// and delete functions use the transaction and don't end it.
+type txDBsql struct {
+	DBsql
+	tx  *sql.Tx
+	ctx context.Context
+}
+
+// Transaction transaction handle of database/sql drivers using its own
+// database connection
+type Transaction struct {
+	dbsql  *txDBsql
+	db     *sql.DB
+	driver common.ReferenceType
+}
+
+// BeginTx begin transaction handle with a new database connection
+func BeginTx(ctx context.Context, dbsql DBsql, driver common.ReferenceType, opts *common.TxOptions) (common.Transaction, error) {
+	layer, url := dbsql.Reference()
+	db, err := sql.Open(layer, url)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.BeginTx(ctx, opts.SqlOptions())
+	if err != nil {
+		log.Log.Debugf("%s: error begin transaction handle: %v", dbsql.ID(), err)
+		db.Close()
+		return nil, err
+	}
+	log.Log.Debugf("%s: begin transaction handle tx=%p", dbsql.ID(), tx)
+	return &Transaction{dbsql: &txDBsql{dbsql, tx, ctx}, db: db, driver: driver}, nil
+}
+
+// StartTransaction return the transaction of the handle
+func (txdb *txDBsql) StartTransaction() (*sql.Tx, context.Context, error) {
+	return txdb.tx, txdb.ctx, nil
+}
+
+// EndTransaction transaction is ended by the handle only
+func (txdb *txDBsql) EndTransaction(bool) error {
+	return nil
+}
+
+// Close connection is closed by the handle only
+func (txdb *txDBsql) Close() {
+}
+
+// IsTransaction always in transaction
+func (txdb *txDBsql) IsTransaction() bool {
+	return true
+}
+
+// QueryContext query database records in the transaction
+func (transaction *Transaction) QueryContext(ctx context.Context, search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	search.Driver = transaction.driver
+	selectCmd, args, err := search.SelectParameters()
+	if err != nil {
+		return nil, err
+	}
+	log.Log.Debugf("Transaction query: %s", selectCmd)
+	rows, err := transaction.dbsql.tx.QueryContext(ctx, selectCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if search.DataStruct == nil {
+		return search.ParseRows(rows, f)
+	}
+	return search.ParseStruct(rows, f)
+}
+
+// InsertContext insert records in the transaction
+func (transaction *Transaction) InsertContext(ctx context.Context, name string, insert *common.Entries) ([][]any, error) {
+	return BulkInsertContext(ctx, transaction.dbsql, transaction.driver, name, insert)
+}
+
+// UpdateContext update records in the transaction
+func (transaction *Transaction) UpdateContext(ctx context.Context, name string, updateInfo *common.Entries) ([][]any, int64, error) {
+	return UpdateContext(ctx, transaction.dbsql, transaction.driver, name, updateInfo)
+}
+
+// UpsertContext insert or update records in the transaction
+func (transaction *Transaction) UpsertContext(ctx context.Context, name string, upsert *common.Entries) ([][]any, error) {
+	return UpsertContext(ctx, transaction.dbsql, transaction.driver, name, upsert)
+}
+
+// BatchUpdateContext update records of all value rows in the transaction
+// using one prepared statement
+func (transaction *Transaction) BatchUpdateContext(ctx context.Context, name string, update *common.Entries) ([]int64, error) {
+	return BatchUpdateContext(ctx, transaction.dbsql, transaction.driver, name, update)
+}
+
+// BatchDeleteContext delete records of all value rows in the transaction
+// using one prepared statement
+func (transaction *Transaction) BatchDeleteContext(ctx context.Context, name string, remove *common.Entries) ([]int64, error) {
+	return BatchDeleteContext(ctx, transaction.dbsql, transaction.driver, name, remove)
+}
+
+// DeleteContext delete records in the transaction
+func (transaction *Transaction) DeleteContext(ctx context.Context, name string, remove *common.Entries) (int64, error) {
+	return DeleteContext(ctx, transaction.dbsql, transaction.driver, name, remove)
+}
+
+// StreamContext streaming data from a field in the transaction block by
+// block
+func (transaction *Transaction) StreamContext(ctx context.Context, search *common.Query, sf common.StreamFunction) error {
+	log.Log.Debugf("Transaction stream of %s", search.TableName)
+	return StreamBlocks(ctx, transaction.dbsql.tx, transaction.driver, search, sf)
+}
+
+// BatchContext batch SQL statement in the transaction
+func (transaction *Transaction) BatchContext(ctx context.Context, batch string) error {
+	_, err := transaction.dbsql.tx.ExecContext(ctx, batch)
+	return err
+}
+
+// Commit commit the transaction and close the connection
+func (transaction *Transaction) Commit(ctx context.Context) error {
+	defer transaction.db.Close()
+	log.Log.Debugf("%s: commit transaction handle tx=%p", transaction.dbsql.ID(), transaction.dbsql.tx)
+	return transaction.dbsql.tx.Commit()
+}
+
+// Rollback rollback the transaction and close the connection
+func (transaction *Transaction) Rollback(ctx context.Context) error {
+	defer transaction.db.Close()
+	log.Log.Debugf("%s: rollback transaction handle tx=%p", transaction.dbsql.ID(), transaction.dbsql.tx)
+	return transaction.dbsql.tx.Rollback()
+}
+
+// Savepoint set savepoint in the transaction
+func (transaction *Transaction) Savepoint(ctx context.Context, name string) error {
+	return transaction.BatchContext(ctx, "SAVEPOINT "+name)
+}
+
+// RollbackTo rollback the transaction to the savepoint
+func (transaction *Transaction) RollbackTo(ctx context.Context, name string) error {
+	return transaction.BatchContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+}
+
+// Release release the savepoint, Oracle savepoints are released at the
+// end of the transaction only
+func (transaction *Transaction) Release(ctx context.Context, name string) error {
+	if transaction.driver == common.OracleType {
+		return nil
+	}
+	return transaction.BatchContext(ctx, "RELEASE SAVEPOINT "+name)
+}
+
+// Retryable check if the database driver allows a retry of the
+// transaction because of the error
+func (transaction *Transaction) Retryable(err error) bool {
+	if r, ok := transaction.dbsql.DBsql.(common.Retryable); ok {
+		return r.Retryable(err)
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tknie/flynn/common"
+)
+
+// goField field of an existing GO structure
+type goField struct {
+	name     string
+	column   string
+	typeName string
+	length   int
+	// converted is set for sub structures and YAML, XML or JSON fields
+	converted bool
+}
+
+// Check compare the GO structures of the file or package directory with
+// the structures generated out of the database. The structures are found
+// by name, each difference is returned as one drift message.
+func Check(path string, structs []*Struct) ([]string, error) {
+	existing, err := parseStructs(path)
+	if err != nil {
+		return nil, err
+	}
+	drift := make([]string, 0)
+	for _, s := range structs {
+		fields, ok := existing[s.Name]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("%s: type missing for table %s", s.Name, s.Table))
+			continue
+		}
+		drift = append(drift, checkStruct(s, fields)...)
+	}
+	return drift, nil
+}
+
+// checkStruct compare the fields of the existing and the generated
+// structure
+func checkStruct(s *Struct, fields []*goField) []string {
+	drift := make([]string, 0)
+	found := make(map[*goField]bool)
+	for _, f := range s.Fields {
+		var existing *goField
+		for _, gf := range fields {
+			if strings.EqualFold(gf.column, f.Column) {
+				existing = gf
+				break
+			}
+		}
+		if existing == nil {
+			drift = append(drift, fmt.Sprintf("%s: field missing for column %s %s", s.Name, f.Column, f.GoType()))
+			continue
+		}
+		found[existing] = true
+		want := f.GoType()
+		switch {
+		case existing.converted:
+		case typeClass(existing.typeName) != typeClass(want):
+			drift = append(drift, fmt.Sprintf("%s.%s: type %s does not match column %s %s",
+				s.Name, existing.name, existing.typeName, f.Column, want))
+		case strings.HasPrefix(existing.typeName, "*") != strings.HasPrefix(want, "*"):
+			drift = append(drift, fmt.Sprintf("%s.%s: type %s does not match nullability of column %s %s",
+				s.Name, existing.name, existing.typeName, f.Column, want))
+		case typeClass(want) == "string" && existing.length != fieldLength(f):
+			drift = append(drift, fmt.Sprintf("%s.%s: length %d does not match column %s length %d",
+				s.Name, existing.name, existing.length, f.Column, fieldLength(f)))
+		}
+	}
+	for _, gf := range fields {
+		if !found[gf] {
+			drift = append(drift, fmt.Sprintf("%s.%s: column %s missing in table %s", s.Name, gf.name, gf.column, s.Table))
+		}
+	}
+	return drift
+}
+
+// parseStructs struct types of the GO file or of all GO files of the
+// directory
+func parseStructs(path string) (map[string][]*goField, error) {
+	files := []string{path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	structs := make(map[string][]*goField)
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			if st, ok := spec.Type.(*ast.StructType); ok {
+				structs[spec.Name.Name] = structFields(st)
+			}
+			return false
+		})
+	}
+	return structs, nil
+}
+
+// structFields column fields of the struct type, ignored, joined and
+// unexported fields are skipped
+func structFields(st *ast.StructType) []*goField {
+	fields := make([]*goField, 0)
+	for _, f := range st.Fields.List {
+		tag := ""
+		if f.Tag != nil {
+			value, err := strconv.Unquote(f.Tag.Value)
+			if err == nil {
+				tag = reflect.StructTag(value).Get(common.TagName)
+			}
+		}
+		tagName, tagInfo := common.TagInfoParse(tag)
+		if tagInfo == common.IgnoreTag || tagInfo == common.JoinTag {
+			continue
+		}
+		length := 0
+		if parts := strings.Split(tag, ":"); len(parts) > 2 {
+			length, _ = strconv.Atoi(parts[2])
+		}
+		if length == 255 {
+			// default length of strings
+			length = 0
+		}
+		converted := false
+		switch tagInfo {
+		case common.SubTag, common.YAMLTag, common.XMLTag, common.JSONTag:
+			converted = true
+		}
+		for _, n := range f.Names {
+			if !n.IsExported() {
+				continue
+			}
+			column := n.Name
+			if tagName != "" {
+				column = tagName
+			}
+			fields = append(fields, &goField{name: n.Name, column: column,
+				typeName: types.ExprString(f.Type), length: length, converted: converted})
+		}
+	}
+	return fields
+}
+
+// fieldLength string length of the generated field
+func fieldLength(f *Field) int {
+	if parts := strings.Split(f.Tag, ":"); len(parts) > 2 {
+		length, _ := strconv.Atoi(parts[2])
+		return length
+	}
+	return 0
+}
+
+// typeClass class of compatible GO types
+func typeClass(typeName string) string {
+	typeName = strings.TrimPrefix(typeName, "*")
+	switch typeName {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "integer"
+	case "float32", "float64":
+		return "float"
+	case "[]uint8":
+		return "[]byte"
+	}
+	return typeName
+}
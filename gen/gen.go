@@ -0,0 +1,293 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+// Package gen generates GO structures with `flynn` tags out of the table
+// definitions of the database and checks existing structures for drift.
+package gen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tknie/flynn"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+// DefaultPackage package name of the generated source
+const DefaultPackage = "model"
+
+// Options options generating the GO source
+type Options struct {
+	Package string
+}
+
+// Struct GO structure generated out of a table definition
+type Struct struct {
+	Name   string
+	Table  string
+	Fields []*Field
+}
+
+// Field field of the generated structure, Tag is the value of the
+// `flynn` tag
+type Field struct {
+	Name   string
+	Column string
+	Type   reflect.Type
+	Tag    string
+}
+
+var (
+	stringType = reflect.TypeOf("")
+	int64Type  = reflect.TypeOf(int64(0))
+	uint64Type = reflect.TypeOf(uint64(0))
+	floatType  = reflect.TypeOf(float64(0))
+	boolType   = reflect.TypeOf(false)
+	timeType   = reflect.TypeOf(time.Time{})
+	bytesType  = reflect.TypeOf([]byte{})
+)
+
+// Generate connect to the database URL and generate the GO source of the
+// tables. All tables are used if no table is given.
+func Generate(ctx context.Context, url string, options *Options, tables ...string) ([]byte, error) {
+	id, err := flynn.Handle(url)
+	if err != nil {
+		return nil, err
+	}
+	defer id.FreeHandler()
+	structs, err := Describe(ctx, id, tables...)
+	if err != nil {
+		return nil, err
+	}
+	return Source(structs, options)
+}
+
+// Describe read the table definitions and create the GO structures. All
+// tables are used if no table is given.
+func Describe(ctx context.Context, id common.RegDbID, tables ...string) ([]*Struct, error) {
+	if len(tables) == 0 {
+		list, err := id.Tables()
+		if err != nil {
+			return nil, err
+		}
+		tables = list
+	}
+	structs := make([]*Struct, 0, len(tables))
+	for _, t := range tables {
+		info, err := id.DescribeTableContext(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, NewStruct(info))
+	}
+	return structs, nil
+}
+
+// NewStruct GO structure of the table definition. Nullable columns get
+// pointer types, primary key columns the `key` tag and the other columns
+// the `unique`, `index`, `notnull` and `default=` constraint options. The
+// table definition created out of the structure is the same as the
+// definition of the table, except for types without own GO type like
+// BIGINT, CHAR or TEXT. Columns of multi-column indexes get an index of
+// their own.
+func NewStruct(info *common.TableInfo) *Struct {
+	s := &Struct{Name: goName(info.Name), Table: info.Name, Fields: make([]*Field, 0, len(info.Columns))}
+	names := make(map[string]bool)
+	for _, c := range info.Columns {
+		name := goName(c.Name)
+		for i := 2; names[name]; i++ {
+			name = goName(c.Name) + strconv.Itoa(i)
+		}
+		names[name] = true
+		t, length := columnType(c)
+		parts := []string{c.Name, "", ""}
+		if length != 0 {
+			parts[2] = strconv.Itoa(length)
+		}
+		switch {
+		case c.PrimaryKey:
+			parts[1] = "key"
+		case c.Unique:
+			parts = append(parts, "unique")
+		case c.Index:
+			parts = append(parts, "index")
+		}
+		switch {
+		case c.PrimaryKey:
+		case !c.Nullable:
+			parts = append(parts, "notnull")
+		case t != bytesType:
+			t = reflect.PointerTo(t)
+		}
+		if d := defaultValue(c.Default); d != "" && !c.PrimaryKey {
+			parts = append(parts, "default="+d)
+		}
+		for len(parts) > 1 && parts[len(parts)-1] == "" {
+			parts = parts[:len(parts)-1]
+		}
+		s.Fields = append(s.Fields, &Field{Name: name, Column: c.Name, Type: t, Tag: strings.Join(parts, ":")})
+	}
+	log.Log.Debugf("Generated struct %s with %d fields", s.Name, len(s.Fields))
+	return s
+}
+
+// Type struct type of the generated structure, used to create the table
+// definition out of the structure
+func (s *Struct) Type() reflect.Type {
+	fields := make([]reflect.StructField, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		fields = append(fields, reflect.StructField{Name: f.Name, Type: f.Type,
+			Tag: reflect.StructTag(f.StructTag())})
+	}
+	return reflect.StructOf(fields)
+}
+
+// GoType GO type name of the field
+func (f *Field) GoType() string {
+	return typeName(f.Type)
+}
+
+// StructTag struct tag of the field
+func (f *Field) StructTag() string {
+	return common.TagName + ":" + strconv.Quote(f.Tag)
+}
+
+// Source GO source of the structures formatted by gofmt
+func Source(structs []*Struct, options *Options) ([]byte, error) {
+	pkg := DefaultPackage
+	if options != nil && options.Package != "" {
+		pkg = options.Package
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString("// Code generated by flynn-gen. DO NOT EDIT.\n\n")
+	buffer.WriteString("package " + pkg + "\n\n")
+	for _, s := range structs {
+		if s.usesTime() {
+			buffer.WriteString("import \"time\"\n\n")
+			break
+		}
+	}
+	for _, s := range structs {
+		fmt.Fprintf(&buffer, "// %s table %s\ntype %s struct {\n", s.Name, s.Table, s.Name)
+		for _, f := range s.Fields {
+			fmt.Fprintf(&buffer, "%s %s `%s`\n", f.Name, f.GoType(), f.StructTag())
+		}
+		buffer.WriteString("}\n\n")
+	}
+	return format.Source(buffer.Bytes())
+}
+
+// usesTime check if the structure contains time fields
+func (s *Struct) usesTime() bool {
+	for _, f := range s.Fields {
+		t := f.Type
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == timeType {
+			return true
+		}
+	}
+	return false
+}
+
+// columnType GO type and tag length of the column. Strings have the
+// default length 255 and floats 10.
+func columnType(c *common.Column) (reflect.Type, int) {
+	switch c.DataType {
+	case common.Alpha, common.Unicode, common.Character:
+		if c.Length == 0 || c.Length == 255 {
+			return stringType, 0
+		}
+		return stringType, int(c.Length)
+	case common.Integer, common.BigInteger, common.Number:
+		return int64Type, 0
+	case common.Decimal:
+		switch {
+		case c.Digits == 0 && c.Length == 20:
+			return uint64Type, 0
+		case c.Digits == 0 && c.Length > 0:
+			return int64Type, 0
+		case c.Length == 0 || c.Length == 10:
+			return floatType, 0
+		}
+		return floatType, int(c.Length)
+	case common.Boolean, common.Bit:
+		return boolType, 0
+	case common.CurrentTimestamp, common.Date:
+		return timeType, 0
+	case common.Bytes, common.BLOB:
+		return bytesType, 0
+	}
+	return stringType, 0
+}
+
+// defaultValue default of the column usable in the tag, casts like
+// `'none'::character varying` are removed. Sequences and defaults with
+// unquoted colons ending the tag option are skipped.
+func defaultValue(value string) string {
+	value = strings.TrimSpace(value)
+	if index := strings.LastIndex(value, "::"); index > 0 && !strings.Contains(value[index:], "'") {
+		value = value[:index]
+	}
+	if strings.ContainsAny(value, "`\"") || strings.HasPrefix(strings.ToLower(value), "nextval(") ||
+		len(common.SplitTag(value)) > 1 {
+		return ""
+	}
+	return value
+}
+
+// initialisms parts of names written in upper case
+var initialisms = map[string]bool{"id": true, "isn": true, "uid": true, "uuid": true,
+	"url": true, "ip": true, "json": true, "xml": true}
+
+// goName exported GO name of the table or column name like `first_name`
+func goName(name string) string {
+	var buffer strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if initialisms[strings.ToLower(part)] {
+			buffer.WriteString(strings.ToUpper(part))
+			continue
+		}
+		if len(part) > 2 && strings.ToUpper(part) == part {
+			part = strings.ToLower(part)
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		buffer.WriteString(string(r))
+	}
+	result := buffer.String()
+	if result == "" || !unicode.IsLetter([]rune(result)[0]) {
+		result = "F" + result
+	}
+	return result
+}
+
+// typeName GO source type name
+func typeName(t reflect.Type) string {
+	switch {
+	case t.Kind() == reflect.Pointer:
+		return "*" + typeName(t.Elem())
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return "[]byte"
+	}
+	return t.String()
+}
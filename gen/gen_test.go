@@ -0,0 +1,134 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package gen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/flynn/dbsql"
+)
+
+type genRecord struct {
+	ID      int64  `flynn:"ID:key"`
+	Name    string `flynn:"Name::100:unique"`
+	Code    string `flynn:"Code::10:notnull:default='x'"`
+	Opens   string `flynn:"Opens::5:default='12:00'"`
+	Shop    string `flynn:"Shop::10:index"`
+	Amount  float64
+	Total   uint64
+	Flag    bool
+	Created time.Time
+	Data    []byte
+}
+
+func normalizeDDL(ddl string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(ddl), " "))
+}
+
+func TestGoName(t *testing.T) {
+	assert.Equal(t, "FirstName", goName("first_name"))
+	assert.Equal(t, "ID", goName("ID"))
+	assert.Equal(t, "Firstname", goName("FIRSTNAME"))
+	assert.Equal(t, "PictureData", goName("PictureData"))
+	assert.Equal(t, "F1Value", goName("1 value"))
+	assert.Equal(t, "'none'", defaultValue("'none'::character varying"))
+	assert.Equal(t, "UserID", goName("user_id"))
+	assert.Equal(t, "'a:b'", defaultValue("'a:b'"))
+	assert.Equal(t, "'12:00'", defaultValue("'12:00'::time without time zone"))
+	assert.Equal(t, "", defaultValue("nextval('seq'::regclass)"))
+	assert.Equal(t, "", defaultValue("('now'::text)::date"))
+}
+
+func TestNewStruct(t *testing.T) {
+	s := NewStruct(&common.TableInfo{Name: "order_items", Columns: []*common.Column{
+		{Name: "id", DataType: common.Integer, PrimaryKey: true},
+		{Name: "name", DataType: common.Alpha, Length: 50, Nullable: true, Unique: true, Index: true},
+		{Name: "code", DataType: common.Alpha, Length: 255, Default: "'none'::character varying"},
+		{Name: "price", DataType: common.Decimal, Length: 12, Digits: 2, Nullable: true, Index: true},
+		{Name: "created", DataType: common.CurrentTimestamp, Nullable: true},
+		{Name: "data", DataType: common.BLOB, Nullable: true},
+		{Name: "Name", DataType: common.Boolean},
+	}})
+	assert.Equal(t, "OrderItems", s.Name)
+	fields := make([]string, 0)
+	for _, f := range s.Fields {
+		fields = append(fields, f.Name+" "+f.GoType()+" "+f.StructTag())
+	}
+	assert.Equal(t, []string{`ID int64 flynn:"id:key"`, `Name *string flynn:"name::50:unique"`,
+		`Code string flynn:"code:::notnull:default='none'"`, `Price *float64 flynn:"price::12:index"`,
+		`Created *time.Time flynn:"created"`, `Data []byte flynn:"data"`, `Name2 bool flynn:"Name:::notnull"`}, fields)
+
+	source, err := Source([]*Struct{s}, &Options{Package: "shop"})
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(source), "package shop\n\nimport \"time\"\n")
+		assert.Contains(t, string(source), "// OrderItems table order_items\ntype OrderItems struct {\n")
+		assert.Contains(t, string(source), "\tCreated *time.Time `flynn:\"created\"`\n")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	url := "sqlite://" + filepath.Join(t.TempDir(), "gen.db")
+	id, err := flynn.Handle(url)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer id.FreeHandler()
+	if !assert.NoError(t, id.CreateTable("Records", &genRecord{})) {
+		return
+	}
+
+	structs, err := Describe(context.Background(), id, "Records")
+	if !assert.NoError(t, err) || !assert.Len(t, structs, 1) {
+		return
+	}
+	expected, err := dbsql.CreateTableStatements(common.SqliteType, false, "Records", &genRecord{})
+	assert.NoError(t, err)
+	generated, err := dbsql.CreateTableStatements(common.SqliteType, false, "Records",
+		reflect.New(structs[0].Type()).Interface())
+	assert.NoError(t, err)
+	assert.Equal(t, normalizeDDL(strings.Join(expected, ";")), normalizeDDL(strings.Join(generated, ";")))
+
+	source, err := Generate(context.Background(), url, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, string(source), "package model\n")
+	assert.Contains(t, string(source), "\tTotal   *uint64    `flynn:\"Total\"`\n")
+
+	// generated source has no drift
+	dir := t.TempDir()
+	file := filepath.Join(dir, "records.go")
+	assert.NoError(t, os.WriteFile(file, source, 0644))
+	drift, err := Check(dir, structs)
+	assert.NoError(t, err)
+	assert.Empty(t, drift)
+
+	changed := strings.NewReplacer("*uint64", "*string", "`flynn:\"Name::100:unique\"`", "`flynn:\"Name::80:unique\"`",
+		"*bool", "bool", "\tData", "\tOther []byte\n\tIgnored string `flynn:\":ignore\"`\n\tData").Replace(string(source))
+	assert.NoError(t, os.WriteFile(file, []byte(changed), 0644))
+	drift, err = Check(file, append(structs, &Struct{Name: "Missing", Table: "missing"}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Records.Name: length 80 does not match column Name length 100",
+		"Records.Total: type *string does not match column Total *uint64",
+		"Records.Flag: type bool does not match nullability of column Flag *bool",
+		"Records.Other: column Other missing in table Records",
+		"Missing: type missing for table missing"}, drift)
+}
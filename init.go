@@ -16,11 +16,13 @@ import (
 	"sync/atomic"
 
 	"github.com/tknie/errorrepo"
-	"github.com/tknie/flynn/adabas"
+	_ "github.com/tknie/flynn/adabas"
 	"github.com/tknie/flynn/common"
-	"github.com/tknie/flynn/mysql"
-	"github.com/tknie/flynn/oracle"
-	"github.com/tknie/flynn/postgres"
+	_ "github.com/tknie/flynn/memory"
+	_ "github.com/tknie/flynn/mysql"
+	_ "github.com/tknie/flynn/oracle"
+	_ "github.com/tknie/flynn/postgres"
+	_ "github.com/tknie/flynn/sqlite"
 	"github.com/tknie/log"
 )
 
@@ -62,20 +64,11 @@ func Handler(dbref *common.Reference, password string) (common.RegDbID, error) {
 		}
 		log.Log.Debugf("Register database with passwordx %s", p)
 	}
-	var db common.Database
-	var err error
-	switch dbref.Driver {
-	case common.PostgresType:
-		db, err = postgres.NewInstance(id, dbref, password)
-	case common.MysqlType:
-		db, err = mysql.NewInstance(id, dbref, password)
-	case common.AdabasType:
-		db, err = adabas.NewInstance(id, dbref, password)
-	case common.OracleType:
-		db, err = oracle.NewInstance(id, dbref, password)
-	default:
+	factory, ok := common.LookupDriver(dbref.Driver)
+	if !ok {
 		return 0, errorrepo.NewError("DB065535")
 	}
+	db, err := factory(id, dbref, password)
 	if err != nil {
 		return 0, err
 	}
@@ -137,6 +137,14 @@ func adabasTarget(t *testing.T) (string, error) {
 	return ada, nil
 }
 
+func sqliteTarget(t *testing.T) (string, error) {
+	sqlitePath := os.Getenv("SQLITE_PATH")
+	if sqlitePath == "" {
+		sqlitePath = os.TempDir() + "/flynn_test.db"
+	}
+	return "sqlite://" + sqlitePath, nil
+}
+
 func TestInitDatabases(t *testing.T) {
 	pg, err := postgresTarget(t)
 	if !assert.NoError(t, err) {
@@ -0,0 +1,61 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package flynn
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+)
+
+// All query database records into structures of type T returning an
+// iterator. If no data structure is given in the query, a new T is used.
+// Each iteration gets a new structure, leaving the loop closes the query.
+//
+//	for record, err := range flynn.All[Employee](id, query) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func All[T any](id common.RegDbID, query *common.Query) iter.Seq2[*T, error] {
+	return AllContext[T](context.Background(), id, query)
+}
+
+// AllContext query database records into structures of type T returning
+// an iterator, the query is canceled if the context is done. The query of
+// the caller is not changed.
+func AllContext[T any](ctx context.Context, id common.RegDbID, query *common.Query) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		q := *query
+		if q.DataStruct == nil {
+			q.DataStruct = new(T)
+		}
+		if _, ok := q.DataStruct.(*T); !ok {
+			yield(nil, errorrepo.NewError("DB000044", fmt.Sprintf("%T", q.DataStruct),
+				fmt.Sprintf("%T", new(T))))
+			return
+		}
+		for result, err := range id.RowsContext(ctx, &q) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(result.Data.(*T), nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package flynn
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+)
+
+type iterRecord struct {
+	ID      string `flynn:"ID::10"`
+	Name    string
+	Counter int64
+}
+
+func iterTestTable(t *testing.T) common.RegDbID {
+	id, err := Handle("memory://iterator")
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	t.Cleanup(func() {
+		id.DeleteTable("IterRecord")
+		id.FreeHandler()
+	})
+	err = id.CreateTable("IterRecord", &iterRecord{})
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	records := [][]any{}
+	for i := 1; i <= 10; i++ {
+		records = append(records, []any{&iterRecord{ID: fmt.Sprintf("IT%02d", i),
+			Name: fmt.Sprintf("Name %d", i), Counter: int64(i)}})
+	}
+	_, err = id.Insert("IterRecord", &common.Entries{Fields: []string{"*"},
+		DataStruct: &iterRecord{}, Values: records})
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	return id
+}
+
+func TestIteratorAll(t *testing.T) {
+	InitLog(t)
+
+	id := iterTestTable(t)
+	if id == 0 {
+		return
+	}
+	records := make([]*iterRecord, 0)
+	for record, err := range All[iterRecord](id, &common.Query{TableName: "IterRecord",
+		Fields: []string{"*"}, Order: []string{"ID:ASC"}}) {
+		if !assert.NoError(t, err) {
+			return
+		}
+		records = append(records, record)
+	}
+	if assert.Len(t, records, 10) {
+		assert.Equal(t, "IT01", records[0].ID)
+		assert.Equal(t, "IT10", records[9].ID)
+		assert.Equal(t, int64(5), records[4].Counter)
+	}
+
+	records = records[:0]
+	for record, err := range All[iterRecord](id, &common.Query{TableName: "IterRecord",
+		Fields: []string{"*"}, Order: []string{"ID:DESC"}}) {
+		if !assert.NoError(t, err) {
+			return
+		}
+		records = append(records, record)
+		if len(records) == 3 {
+			break
+		}
+	}
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, []string{"IT10", "IT09", "IT08"},
+			[]string{records[0].ID, records[1].ID, records[2].ID})
+	}
+
+	for _, err := range All[iterRecord](id, &common.Query{TableName: "IterRecord",
+		DataStruct: &struct{ ID string }{}}) {
+		assert.Error(t, err)
+	}
+
+	// the query can be reused with another type
+	query := &common.Query{TableName: "IterRecord", Fields: []string{"ID"}, Order: []string{"ID:ASC"}}
+	list, err := QueryAll[iterRecord](id, query)
+	assert.NoError(t, err)
+	assert.Len(t, list, 10)
+	assert.Nil(t, query.DataStruct)
+	type iterID struct {
+		ID string
+	}
+	ids, err := QueryAll[iterID](id, query)
+	assert.NoError(t, err)
+	if assert.Len(t, ids, 10) {
+		assert.Equal(t, "IT01", ids[0].ID)
+	}
+	count := 0
+	for _, err := range All[iterRecord](id, &common.Query{TableName: "Unknown"}) {
+		assert.Error(t, err)
+		count++
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestIteratorRows(t *testing.T) {
+	InitLog(t)
+
+	id := iterTestTable(t)
+	if id == 0 {
+		return
+	}
+	results := make([]*common.Result, 0)
+	for result, err := range id.Rows(&common.Query{TableName: "IterRecord",
+		Fields: []string{"ID", "Counter"}, Search: "Counter > 7", Order: []string{"ID:ASC"}}) {
+		if !assert.NoError(t, err) {
+			return
+		}
+		results = append(results, result)
+	}
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, []any{"IT08", int64(8)}, results[0].Rows)
+		assert.Equal(t, []any{"IT10", int64(10)}, results[2].Rows)
+		assert.Equal(t, []string{"ID", "Counter"}, results[0].Fields)
+	}
+}
@@ -0,0 +1,974 @@
+//go:build !flynn_nomemory
+// +build !flynn_nomemory
+
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+// Memory instance for in-memory databases. All instances using the same
+// database name share the tables inside the process.
+//
+// Transactions are only able to rollback changes, other instances see
+// the changes before the transaction is committed.
+type Memory struct {
+	common.CommonDatabase
+	db        *database
+	snapshots map[string][][]any
+}
+
+// memoryType reference type of the memory driver, assigned by the driver
+// registration
+var memoryType common.ReferenceType
+
+func init() {
+	memoryType = common.RegisterDriverDefinition("memory", &common.Driver{Name: "Memory",
+		Parse: common.ParsePath, Factory: NewInstance})
+}
+
+// NewInstance create new memory reference instance
+func NewInstance(id common.RegDbID, reference *common.Reference, password string) (common.Database, error) {
+	if reference == nil || reference.Database == "" {
+		return nil, errorrepo.NewError("DB000036", "memory://")
+	}
+	mem := &Memory{common.NewCommonDatabase(id, "memory"),
+		lookupDatabase(reference.Database), nil}
+	mem.ConRef = reference
+	log.Log.Debugf("%s: create new instance", mem.ID().String())
+	return mem, nil
+}
+
+// New create new memory reference instance
+func New(id common.RegDbID, url string) (common.Database, error) {
+	ref, p, err := common.NewReference(url)
+	if err != nil {
+		return nil, err
+	}
+	return NewInstance(id, ref, p)
+}
+
+// Clone clone the memory instance sharing the tables
+func (mem *Memory) Clone() common.Database {
+	newMem := &Memory{}
+	*newMem = *mem
+	newMem.snapshots = nil
+	return newMem
+}
+
+// SetCredentials set credentials to connect to database, not used by memory
+func (mem *Memory) SetCredentials(user, password string) error {
+	return nil
+}
+
+// Open open the database connection, nothing to be done for memory
+func (mem *Memory) Open() (dbOpen any, err error) {
+	return mem.db, nil
+}
+
+// Close close the database connection
+func (mem *Memory) Close() {
+	log.Log.Debugf("%s: Close memory", mem.ID().String())
+}
+
+// FreeHandler don't use the driver anymore, a running transaction is
+// rolled back
+func (mem *Memory) FreeHandler() {
+	log.Log.Debugf("%s: free handler", mem.ID().String())
+	mem.Rollback()
+}
+
+// ID current id used
+func (mem *Memory) ID() common.RegDbID {
+	return mem.RegDbID
+}
+
+// URL current URL used
+func (mem *Memory) URL() string {
+	return "memory://" + mem.ConRef.Database
+}
+
+// Ping check database, always available for memory
+func (mem *Memory) Ping() error {
+	return nil
+}
+
+// PingContext check database using context
+func (mem *Memory) PingContext(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Maps database maps, tables or views
+func (mem *Memory) Maps() ([]string, error) {
+	mem.db.lock.RLock()
+	defer mem.db.lock.RUnlock()
+	names := make([]string, 0, len(mem.db.tables))
+	for _, t := range mem.db.tables {
+		names = append(names, t.name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetTableColumn get table columne names
+func (mem *Memory) GetTableColumn(tableName string) ([]string, error) {
+	mem.db.lock.RLock()
+	defer mem.db.lock.RUnlock()
+	t, err := mem.db.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	tableRows := make([]string, 0, len(t.columns))
+	for _, c := range t.columns {
+		tableRows = append(tableRows, strings.ToLower(c.Name))
+	}
+	return tableRows, nil
+}
+
+// DescribeTableContext read the column definitions of the table, memory
+// tables have no keys or indexes
+func (mem *Memory) DescribeTableContext(ctx context.Context, tableName string) (*common.TableInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	mem.db.lock.RLock()
+	defer mem.db.lock.RUnlock()
+	t, err := mem.db.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	info := &common.TableInfo{Name: t.name, Columns: make([]*common.Column, 0, len(t.columns))}
+	for _, c := range t.columns {
+		nc := *c
+		nc.Nullable = true
+		info.Columns = append(info.Columns, &nc)
+	}
+	return info, nil
+}
+
+// tableColumns column definitions out of columns or structure
+func tableColumns(columns any) ([]*common.Column, error) {
+	switch c := columns.(type) {
+	case []*common.Column:
+		newColumns := make([]*common.Column, 0, len(c))
+		for _, col := range c {
+			nc := *col
+			newColumns = append(newColumns, &nc)
+		}
+		return newColumns, nil
+	case nil:
+	default:
+		if newColumns := structColumns(reflect.TypeOf(columns)); len(newColumns) > 0 {
+			return newColumns, nil
+		}
+	}
+	return nil, errorrepo.NewError("DB000005", "", fmt.Sprintf("%T", columns))
+}
+
+// CreateTable create a new table
+func (mem *Memory) CreateTable(name string, columns any) error {
+	newColumns, err := tableColumns(columns)
+	if err != nil {
+		return err
+	}
+	mem.db.lock.Lock()
+	defer mem.db.lock.Unlock()
+	if _, err := mem.db.table(name); err == nil {
+		return errorrepo.NewError("DB000038", name)
+	}
+	mem.db.tables[strings.ToLower(name)] = &table{name: name, columns: newColumns,
+		rows: make([][]any, 0)}
+	return nil
+}
+
+// AdaptTable adapt table to new struct, missing columns are added
+func (mem *Memory) AdaptTable(name string, newStruct any) error {
+	newColumns, err := tableColumns(newStruct)
+	if err != nil {
+		return err
+	}
+	mem.db.lock.Lock()
+	defer mem.db.lock.Unlock()
+	t, err := mem.db.table(name)
+	if err != nil {
+		return err
+	}
+	mem.snapshot(t)
+	t.addColumns(newColumns)
+	return nil
+}
+
+// DeleteTable delete a table
+func (mem *Memory) DeleteTable(name string) error {
+	mem.db.lock.Lock()
+	defer mem.db.lock.Unlock()
+	if _, err := mem.db.table(name); err != nil {
+		return err
+	}
+	delete(mem.db.tables, strings.ToLower(name))
+	return nil
+}
+
+// snapshot keep row list of the table for rollback on first change in
+// the transaction
+func (mem *Memory) snapshot(t *table) {
+	if mem.snapshots == nil {
+		return
+	}
+	if _, ok := mem.snapshots[strings.ToLower(t.name)]; ok {
+		return
+	}
+	mem.snapshots[strings.ToLower(t.name)] = slices.Clone(t.rows)
+}
+
+// entryValues field names and values of the entries, structures are
+// converted into values
+func entryValues(entries *common.Entries) ([]string, [][]any, error) {
+	if entries.DataStruct == nil {
+		return entries.Fields, entries.Values, nil
+	}
+	dynamic := common.CreateInterface(entries.DataStruct, entries.Fields)
+	values := make([][]any, 0, len(entries.Values))
+	for _, vi := range entries.Values {
+		v, err := dynamic.CreateValues(vi[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		values = append(values, v)
+	}
+	return dynamic.RowFields, values, nil
+}
+
+// Insert insert record into table
+func (mem *Memory) Insert(name string, insert *common.Entries) ([][]any, error) {
+	return mem.InsertContext(context.Background(), name, insert)
+}
+
+// InsertContext insert record into table using context
+func (mem *Memory) InsertContext(ctx context.Context, name string, insert *common.Entries) ([][]any, error) {
+	if insert == nil || len(insert.Values) == 0 {
+		return nil, errorrepo.NewError("DB000035")
+	}
+	fields, values, err := entryValues(insert)
+	if err != nil {
+		return nil, err
+	}
+	mem.db.lock.Lock()
+	defer mem.db.lock.Unlock()
+	t, err := mem.db.table(name)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := t.columnIndexes(fields)
+	if err != nil {
+		return nil, err
+	}
+	newRows := make([][]any, 0, len(values))
+	for _, v := range values {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		row := make([]any, len(t.columns))
+		for i, index := range indexes {
+			if i >= len(v) {
+				break
+			}
+			row[index], err = t.convert(index, v[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+		newRows = append(newRows, row)
+	}
+	mem.snapshot(t)
+	t.rows = append(t.rows, newRows...)
+	return make([][]any, 0), nil
+}
+
+// Update update record in table
+func (mem *Memory) Update(name string, insert *common.Entries) ([][]any, int64, error) {
+	return mem.UpdateContext(context.Background(), name, insert)
+}
+
+// UpdateContext update record in table using context. Records are selected
+// by the update expressions, the update key fields and the criteria.
+func (mem *Memory) UpdateContext(ctx context.Context, name string, updateInfo *common.Entries) ([][]any, int64, error) {
+	if updateInfo == nil {
+		return nil, -1, errorrepo.NewError("DB000012")
+	}
+	fields, values, err := entryValues(updateInfo)
+	if err != nil {
+		return nil, -1, err
+	}
+	mem.db.lock.Lock()
+	defer mem.db.lock.Unlock()
+	t, err := mem.db.table(name)
+	if err != nil {
+		return nil, -1, err
+	}
+	indexes, err := t.columnIndexes(fields)
+	if err != nil {
+		return nil, -1, err
+	}
+	conditions := make([]expression, 0)
+	for _, u := range updateInfo.Update {
+		if strings.ContainsAny(u, "=<>") {
+			e, err := parseSearch(t, u)
+			if err != nil {
+				return nil, -1, err
+			}
+			conditions = append(conditions, e)
+		}
+	}
+	if e, err := parseCriteria(t, updateInfo); err != nil {
+		return nil, -1, err
+	} else if e != nil {
+		conditions = append(conditions, e)
+	}
+	keys := make([]int, 0)
+	for i, f := range fields {
+		if slices.Contains(updateInfo.Update, f) {
+			keys = append(keys, i)
+		}
+	}
+	if len(conditions) == 0 && len(keys) == 0 {
+		return nil, -1, errorrepo.NewError("DB000042", name)
+	}
+	mem.snapshot(t)
+	rowsAffected := int64(0)
+	for _, v := range values {
+		if err := ctx.Err(); err != nil {
+			return nil, -1, err
+		}
+		newValues := make([]any, len(indexes))
+		for i, index := range indexes {
+			if i < len(v) {
+				newValues[i], err = t.convert(index, v[i])
+				if err != nil {
+					return nil, -1, err
+				}
+			}
+		}
+		for ri, row := range t.rows {
+			if !matchAll(row, conditions) || !matchKeys(row, indexes, keys, newValues) {
+				continue
+			}
+			newRow := slices.Clone(row)
+			for i, index := range indexes {
+				newRow[index] = newValues[i]
+			}
+			t.rows[ri] = newRow
+			rowsAffected++
+		}
+	}
+	return nil, rowsAffected, nil
+}
+
+// matchAll check if all expressions are true for the row
+func matchAll(row []any, conditions []expression) bool {
+	for _, c := range conditions {
+		if c.evaluate(row) != trueCondition {
+			return false
+		}
+	}
+	return true
+}
+
+// matchKeys check if the key fields of the row are equal to the values
+func matchKeys(row []any, indexes, keys []int, values []any) bool {
+	for _, k := range keys {
+		if cmp, ok := compare(row[indexes[k]], values[k]); !ok || cmp != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Delete Delete database records
+func (mem *Memory) Delete(name string, remove *common.Entries) (int64, error) {
+	return mem.DeleteContext(context.Background(), name, remove)
+}
+
+// DeleteContext Delete database records using context. Records are selected
+// by criteria or by field values, fields starting with '%' are compared
+// using LIKE.
+func (mem *Memory) DeleteContext(ctx context.Context, name string, remove *common.Entries) (int64, error) {
+	if remove == nil {
+		return -1, errorrepo.NewError("DB000012")
+	}
+	mem.db.lock.Lock()
+	defer mem.db.lock.Unlock()
+	t, err := mem.db.table(name)
+	if err != nil {
+		return -1, err
+	}
+	conditions := make([]expression, 0)
+	if remove.Criteria != "" {
+		e, err := parseCriteria(t, remove)
+		if err != nil {
+			return -1, err
+		}
+		conditions = append(conditions, e)
+	} else {
+		for _, v := range remove.Values {
+			e, err := valueCondition(t, remove.Fields, v)
+			if err != nil {
+				return -1, err
+			}
+			conditions = append(conditions, e)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return -1, err
+	}
+	mem.snapshot(t)
+	rows := make([][]any, 0, len(t.rows))
+	for _, row := range t.rows {
+		found := false
+		for _, c := range conditions {
+			if c.evaluate(row) == trueCondition {
+				found = true
+				break
+			}
+		}
+		if !found {
+			rows = append(rows, row)
+		}
+	}
+	rowsAffected := int64(len(t.rows) - len(rows))
+	t.rows = rows
+	return rowsAffected, nil
+}
+
+// valueCondition create condition comparing all fields with the values
+func valueCondition(t *table, fields []string, values []any) (expression, error) {
+	var e expression
+	for i, field := range fields {
+		if i >= len(values) {
+			break
+		}
+		var c expression
+		if strings.HasPrefix(field, "%") {
+			index := t.columnIndex(field[1:])
+			if index == -1 {
+				return nil, errorrepo.NewError("DB000039", field[1:], t.name)
+			}
+			re, err := likePattern(fmt.Sprintf("%v", values[i]))
+			if err != nil {
+				return nil, errorrepo.NewError("DB000040", 0, err.Error())
+			}
+			c = &likeExpression{value: &operand{column: index}, pattern: re}
+		} else {
+			index := t.columnIndex(field)
+			if index == -1 {
+				return nil, errorrepo.NewError("DB000039", field, t.name)
+			}
+			v, err := t.convert(index, values[i])
+			if err != nil {
+				return nil, err
+			}
+			c = &compareExpression{operator: "=", left: &operand{column: index},
+				right: &operand{column: -1, value: v}}
+		}
+		if e == nil {
+			e = c
+		} else {
+			e = &logicalExpression{and: true, left: e, right: c}
+		}
+	}
+	if e == nil {
+		return nil, errorrepo.NewError("DB000012")
+	}
+	return e, nil
+}
+
+// Batch batch SQL query in table, not supported by memory
+func (mem *Memory) Batch(batch string) error {
+	return mem.BatchContext(context.Background(), batch)
+}
+
+// BatchContext batch SQL query in table using context, not supported by memory
+func (mem *Memory) BatchContext(ctx context.Context, batch string) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// BatchSelect batch SQL query in table with values returned, not supported by memory
+func (mem *Memory) BatchSelect(batch string) ([][]interface{}, error) {
+	return mem.BatchSelectContext(context.Background(), batch)
+}
+
+// BatchSelectContext batch SQL query in table with values returned using context,
+// not supported by memory
+func (mem *Memory) BatchSelectContext(ctx context.Context, batch string) ([][]interface{}, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// BatchSelectFct batch SQL query in table with fct called, not supported by memory
+func (mem *Memory) BatchSelectFct(search *common.Query, fct common.ResultFunction) error {
+	return mem.BatchSelectFctContext(context.Background(), search, fct)
+}
+
+// BatchSelectFctContext batch SQL query in table with fct called using context,
+// not supported by memory
+func (mem *Memory) BatchSelectFctContext(ctx context.Context, search *common.Query, fct common.ResultFunction) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// Query query database records with search or SELECT
+func (mem *Memory) Query(search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	return mem.QueryContext(context.Background(), search, f)
+}
+
+// QueryContext query database records with search using context
+func (mem *Memory) QueryContext(ctx context.Context, search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	search.Driver = memoryType
+	set, err := mem.selectRows(search)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if search.DataStruct != nil {
+		rows, err := queryRows(ctx, set)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		return search.ParseStruct(rows, f)
+	}
+	result := &common.Result{Header: set.columns}
+	for _, c := range set.columns {
+		result.Fields = append(result.Fields, c.Name)
+	}
+	for _, row := range set.rows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result.Counter++
+		result.Rows = make([]any, len(row))
+		for i, v := range row {
+			if b, ok := v.([]byte); ok {
+				v = slices.Clone(b)
+			}
+			result.Rows[i] = v
+		}
+		err = f(search, result)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// CountContext count all rows of the query
+func (mem *Memory) CountContext(ctx context.Context, search *common.Query) (int64, error) {
+	query := search.CountQuery()
+	query.Driver = memoryType
+	set, err := mem.selectRows(query)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(set.rows)), ctx.Err()
+}
+
+// selectRows select all rows of the query. The rows are filtered,
+// ordered, made distinct and limited like in the SQL SELECT.
+func (mem *Memory) selectRows(search *common.Query) (*resultSet, error) {
+	if search.TableName == "" {
+		return nil, errorrepo.NewError("DB000016")
+	}
+	if search.Join != "" || len(search.Joins) > 0 || len(search.Group) > 0 {
+		return nil, errorrepo.NewError("DB065535")
+	}
+	fields := search.Fields
+	if search.DataStruct != nil {
+		ti := common.CreateInterface(search.DataStruct, search.Fields)
+		search.TypeInfo = ti
+		fields = ti.RowFields
+	} else if len(fields) == 0 {
+		fields = []string{"*"}
+	}
+	mem.db.lock.RLock()
+	defer mem.db.lock.RUnlock()
+	t, err := mem.db.table(search.TableName)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := t.columnIndexes(fields)
+	if err != nil {
+		return nil, err
+	}
+	searchCmd, args, err := search.SearchParameters()
+	if err != nil {
+		return nil, err
+	}
+	condition, err := parseSearch(t, searchCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]any, 0)
+	for _, row := range t.rows {
+		if condition == nil || condition.evaluate(row) == trueCondition {
+			rows = append(rows, row)
+		}
+	}
+	err = t.orderRows(search.Order, search.Sort, rows)
+	if err != nil {
+		return nil, err
+	}
+	set := &resultSet{}
+	for _, index := range indexes {
+		set.columns = append(set.columns, t.columns[index])
+	}
+	for _, row := range rows {
+		values := make([]any, len(indexes))
+		for i, index := range indexes {
+			values[i] = row[index]
+		}
+		if search.Descriptor && slices.ContainsFunc(set.rows, func(r []any) bool {
+			return equalRow(r, values)
+		}) {
+			continue
+		}
+		set.rows = append(set.rows, values)
+	}
+	if search.Limit != "" {
+		limit, err := strconv.Atoi(strings.TrimSpace(search.Limit))
+		if err != nil || limit < 0 {
+			return nil, errorrepo.NewError("DB000040", 0, "limit "+search.Limit)
+		}
+		if limit < len(set.rows) {
+			set.rows = set.rows[:limit]
+		}
+	} else {
+		set.rows = set.rows[min(search.Offset, uint64(len(set.rows))):]
+		if search.LimitRows > 0 && search.LimitRows < uint64(len(set.rows)) {
+			set.rows = set.rows[:search.LimitRows]
+		}
+	}
+	return set, nil
+}
+
+// orderRows sort rows using order entries like `Name:DESC` and sort fields
+func (t *table) orderRows(order []string, sortFields []common.SortField, rows [][]any) error {
+	if len(order) == 0 && len(sortFields) == 0 {
+		return nil
+	}
+	indexes := make([]int, 0, len(order)+len(sortFields))
+	descending := make([]bool, 0, len(order)+len(sortFields))
+	nulls := make([]common.NullsOrder, 0, len(order)+len(sortFields))
+	for _, o := range order {
+		entry := strings.Split(o, ":")
+		if len(entry) > 2 {
+			return errorrepo.NewError("DB000017")
+		}
+		index := t.columnIndex(entry[0])
+		if index == -1 {
+			return errorrepo.NewError("DB000039", entry[0], t.name)
+		}
+		indexes = append(indexes, index)
+		descending = append(descending, len(entry) == 2 && strings.ToUpper(entry[1]) == "DESC")
+		nulls = append(nulls, common.NullsDefault)
+	}
+	for _, s := range sortFields {
+		index := t.columnIndex(s.Field)
+		if index == -1 {
+			return errorrepo.NewError("DB000039", s.Field, t.name)
+		}
+		indexes = append(indexes, index)
+		descending = append(descending, s.Descending)
+		nulls = append(nulls, s.Nulls)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for x, index := range indexes {
+			a, b := rows[i][index], rows[j][index]
+			if nulls[x] != common.NullsDefault && (a == nil) != (b == nil) {
+				return (a == nil) == (nulls[x] == common.NullsFirst)
+			}
+			cmp := orderCompare(a, b)
+			if cmp == 0 {
+				continue
+			}
+			if descending[x] {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+	return nil
+}
+
+// orderCompare compare values for ordering, NULL values are first
+func orderCompare(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	cmp, _ := compare(a, b)
+	return cmp
+}
+
+// equalRow check if all values of the rows are equal
+func equalRow(a, b []any) bool {
+	for i := range a {
+		if orderCompare(a[i], b[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// BeginTransaction start transaction
+func (mem *Memory) BeginTransaction() error {
+	if mem.snapshots == nil {
+		mem.snapshots = make(map[string][][]any)
+	}
+	mem.Transaction = true
+	return nil
+}
+
+// Commit commit the transaction
+func (mem *Memory) Commit() error {
+	mem.Transaction = false
+	mem.snapshots = nil
+	return nil
+}
+
+// Rollback rollback the transaction, all tables changed in the
+// transaction get the rows back
+func (mem *Memory) Rollback() error {
+	mem.Transaction = false
+	if mem.snapshots == nil {
+		return nil
+	}
+	mem.db.lock.Lock()
+	defer mem.db.lock.Unlock()
+	mem.db.restore(mem.snapshots)
+	mem.snapshots = nil
+	return nil
+}
+
+// restore set the rows of the tables, columns added after the rows were
+// kept are set to nil
+func (db *database) restore(tables map[string][][]any) {
+	for name, rows := range tables {
+		if t, ok := db.tables[name]; ok {
+			t.rows = rows
+			for i, r := range t.rows {
+				for len(r) < len(t.columns) {
+					r = append(r[:len(r):len(r)], nil)
+				}
+				t.rows[i] = r
+			}
+		}
+	}
+}
+
+// BeginTx begin a transaction handle. The changes are visible to other
+// instances before commit, so only read uncommitted isolation is supported.
+func (mem *Memory) BeginTx(ctx context.Context, opts *common.TxOptions) (common.Transaction, error) {
+	if opts != nil && opts.Isolation != sql.LevelDefault &&
+		opts.Isolation != sql.LevelReadUncommitted {
+		return nil, errorrepo.NewError("DB000047", opts.Isolation.String(), "memory")
+	}
+	txMem := mem.Clone().(*Memory)
+	err := txMem.BeginTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{mem: txMem, readOnly: opts != nil && opts.ReadOnly}, nil
+}
+
+// transaction transaction handle of memory
+type transaction struct {
+	mem        *Memory
+	readOnly   bool
+	savepoints []savepoint
+}
+
+// savepoint rows of all tables at the savepoint
+type savepoint struct {
+	name   string
+	tables map[string][][]any
+}
+
+// QueryContext query database records in the transaction
+func (transaction *transaction) QueryContext(ctx context.Context, search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	return transaction.mem.QueryContext(ctx, search, f)
+}
+
+// InsertContext insert records in the transaction
+func (transaction *transaction) InsertContext(ctx context.Context, name string, insert *common.Entries) ([][]any, error) {
+	if transaction.readOnly {
+		return nil, errorrepo.NewError("DB000048", name)
+	}
+	return transaction.mem.InsertContext(ctx, name, insert)
+}
+
+// UpdateContext update records in the transaction
+func (transaction *transaction) UpdateContext(ctx context.Context, name string, updateInfo *common.Entries) ([][]any, int64, error) {
+	if transaction.readOnly {
+		return nil, 0, errorrepo.NewError("DB000048", name)
+	}
+	return transaction.mem.UpdateContext(ctx, name, updateInfo)
+}
+
+// DeleteContext delete records in the transaction
+func (transaction *transaction) DeleteContext(ctx context.Context, name string, remove *common.Entries) (int64, error) {
+	if transaction.readOnly {
+		return 0, errorrepo.NewError("DB000048", name)
+	}
+	return transaction.mem.DeleteContext(ctx, name, remove)
+}
+
+// StreamContext streaming data from a field in the transaction
+func (transaction *transaction) StreamContext(ctx context.Context, search *common.Query, sf common.StreamFunction) error {
+	return transaction.mem.StreamContext(ctx, search, sf)
+}
+
+// BatchContext batch SQL query, not implemented for memory
+func (transaction *transaction) BatchContext(ctx context.Context, batch string) error {
+	return transaction.mem.BatchContext(ctx, batch)
+}
+
+// Savepoint keep the rows of all tables for rollback to the savepoint
+func (transaction *transaction) Savepoint(ctx context.Context, name string) error {
+	db := transaction.mem.db
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+	sp := savepoint{name: name, tables: make(map[string][][]any, len(db.tables))}
+	for n, t := range db.tables {
+		sp.tables[n] = slices.Clone(t.rows)
+	}
+	transaction.savepoints = append(transaction.savepoints, sp)
+	return nil
+}
+
+// findSavepoint index of the last savepoint with the name
+func (transaction *transaction) findSavepoint(name string) (int, error) {
+	for i := len(transaction.savepoints) - 1; i >= 0; i-- {
+		if transaction.savepoints[i].name == name {
+			return i, nil
+		}
+	}
+	return -1, errorrepo.NewError("DB000049", name)
+}
+
+// RollbackTo restore the rows of all tables kept at the savepoint
+func (transaction *transaction) RollbackTo(ctx context.Context, name string) error {
+	i, err := transaction.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+	db := transaction.mem.db
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	for n := range transaction.savepoints[i].tables {
+		if t, ok := db.tables[n]; ok {
+			transaction.mem.snapshot(t)
+		}
+	}
+	db.restore(transaction.savepoints[i].tables)
+	transaction.savepoints = transaction.savepoints[:i+1]
+	return nil
+}
+
+// Release remove the savepoint and all savepoints set after it
+func (transaction *transaction) Release(ctx context.Context, name string) error {
+	i, err := transaction.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+	transaction.savepoints = transaction.savepoints[:i]
+	return nil
+}
+
+// Commit commit the transaction
+func (transaction *transaction) Commit(ctx context.Context) error {
+	return transaction.mem.Commit()
+}
+
+// Rollback rollback all changes of the transaction
+func (transaction *transaction) Rollback(ctx context.Context) error {
+	return transaction.mem.Rollback()
+}
+
+// Stream streaming data from a field
+func (mem *Memory) Stream(search *common.Query, sf common.StreamFunction) error {
+	return mem.StreamContext(context.Background(), search, sf)
+}
+
+// StreamContext streaming data from a field using context
+func (mem *Memory) StreamContext(ctx context.Context, search *common.Query, sf common.StreamFunction) error {
+	if len(search.Fields) == 0 {
+		return errorrepo.NewError("DB000012")
+	}
+	query := &common.Query{Driver: memoryType, TableName: search.TableName, Search: search.Search,
+		Where: search.Where, Parameters: search.Parameters, Fields: search.Fields[:1], Limit: "1"}
+	set, err := mem.selectRows(query)
+	if err != nil {
+		return err
+	}
+	if len(set.rows) == 0 {
+		log.Log.Errorf("rows missing")
+		return errorrepo.NewError("DB000021")
+	}
+	var data []byte
+	switch v := set.rows[0][0].(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	}
+	log.Log.Debugf("Start stream for %s for %s", search.Fields[0], search.TableName)
+	blocksize := int(search.Blocksize)
+	if blocksize <= 0 {
+		blocksize = len(data)
+	}
+	offset := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := min(offset+blocksize, len(data))
+		stream := &common.Stream{Data: slices.Clone(data[offset:end])}
+		err = sf(search, stream)
+		if err != nil {
+			log.Log.Errorf("stream function error: %s", err)
+			return err
+		}
+		offset = end
+		if offset >= len(data) {
+			break
+		}
+	}
+	return nil
+}
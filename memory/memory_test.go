@@ -0,0 +1,433 @@
+//go:build !flynn_nomemory
+// +build !flynn_nomemory
+
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package memory
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+var logRus = logrus.StandardLogger()
+var once = new(sync.Once)
+
+type memoryRecord struct {
+	ID       string `flynn:"ID::10"`
+	Name     string
+	Counter  int64
+	Flag     bool
+	Created  time.Time
+	Document []byte
+}
+
+func InitLog(t *testing.T) {
+	once.Do(startLog)
+	log.Log.Debugf("TEST: %s", t.Name())
+}
+
+func startLog() {
+	fmt.Println("Init logging")
+	fileName := "db.trace.log"
+	level := os.Getenv("ENABLE_DB_DEBUG")
+	logLevel := logrus.WarnLevel
+	switch level {
+	case "debug", "1":
+		log.SetDebugLevel(true)
+		logLevel = logrus.DebugLevel
+	case "info", "2":
+		log.SetDebugLevel(false)
+		logLevel = logrus.InfoLevel
+	default:
+	}
+	logRus.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp:   true,
+		TimestampFormat: "2006-01-02T15:04:05",
+	})
+	logRus.SetLevel(logLevel)
+	p := os.Getenv("LOGPATH")
+	if p == "" {
+		p = os.TempDir()
+	}
+	f, err := os.OpenFile(p+"/"+fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		fmt.Println("Error opening log:", err)
+		return
+	}
+	logRus.SetOutput(f)
+	logRus.Infof("Init logrus")
+	log.Log = logRus
+	fmt.Println("Logging running")
+}
+
+func memoryInstance(t *testing.T, id common.RegDbID, url string) *Memory {
+	db, err := New(id, url)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	common.RegisterDbClient(db)
+	t.Cleanup(func() { id.FreeHandler() })
+	return db.(*Memory)
+}
+
+func TestMemoryReference(t *testing.T) {
+	InitLog(t)
+
+	ref, _, err := common.NewReference("memory://testdata")
+	assert.NoError(t, err)
+	assert.Equal(t, &common.Reference{Driver: memoryType, Database: "testdata"}, ref)
+	assert.True(t, memoryType > common.SqliteType)
+	_, _, err = common.NewReference("memory://")
+	assert.Error(t, err)
+	assert.Contains(t, common.Drivers(), "memory")
+	assert.Equal(t, "Memory", memoryType.String())
+}
+
+func TestMemoryStruct(t *testing.T) {
+	InitLog(t)
+
+	mem := memoryInstance(t, 2001, "memory://structs")
+	if mem == nil {
+		return
+	}
+	err := mem.CreateTable("MemoryStruct", &memoryRecord{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer mem.DeleteTable("MemoryStruct")
+	err = mem.CreateTable("MemoryStruct", &memoryRecord{})
+	assert.Error(t, err)
+	m, err := mem.Maps()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"MemoryStruct"}, m)
+	c, err := mem.GetTableColumn("MemoryStruct")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "counter", "flag", "created", "document"}, c)
+	info, err := mem.ID().DescribeTable("memorystruct")
+	if assert.NoError(t, err) && assert.Len(t, info.Columns, 6) {
+		assert.Equal(t, "MemoryStruct", info.Name)
+		assert.Equal(t, "Name", info.Columns[1].Name)
+		assert.True(t, info.Columns[1].Nullable)
+		assert.Empty(t, info.PrimaryKey())
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	records := [][]any{}
+	for i := 1; i <= 5; i++ {
+		records = append(records, []any{&memoryRecord{ID: fmt.Sprintf("ID%03d", i),
+			Name: fmt.Sprintf("Name %d", i), Counter: int64(i * 10), Flag: i%2 == 0,
+			Created: now, Document: []byte{byte(i), 1, 2}}})
+	}
+	_, err = mem.Insert("MemoryStruct", &common.Entries{Fields: []string{"*"},
+		DataStruct: &memoryRecord{}, Values: records})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	result := make([]*memoryRecord, 0)
+	_, err = mem.Query(&common.Query{TableName: "MemoryStruct", DataStruct: &memoryRecord{},
+		Fields: []string{"*"}, Search: "Counter >= 20 AND Name LIKE 'Name%'",
+		Order: []string{"ID:DESC"}, Limit: "3"},
+		func(search *common.Query, result2 *common.Result) error {
+			record := *result2.Data.(*memoryRecord)
+			result = append(result, &record)
+			return nil
+		})
+	assert.NoError(t, err)
+	if assert.Len(t, result, 3) {
+		assert.Equal(t, "ID005", result[0].ID)
+		assert.Equal(t, "ID004", result[1].ID)
+		assert.Equal(t, "Name 4", result[1].Name)
+		assert.Equal(t, int64(40), result[1].Counter)
+		assert.True(t, result[1].Flag)
+		assert.False(t, result[2].Flag)
+		assert.Equal(t, now, result[1].Created.UTC())
+		assert.Equal(t, []byte{4, 1, 2}, result[1].Document)
+	}
+
+	_, n, err := mem.Update("MemoryStruct", &common.Entries{Fields: []string{"Name", "ID"},
+		Update: []string{"ID"}, Values: [][]any{{"Changed", "ID003"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, n, err = mem.Update("MemoryStruct", &common.Entries{Fields: []string{"Counter"},
+		Criteria: "Flag = true", Values: [][]any{{99}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	_, n, err = mem.Update("MemoryStruct", &common.Entries{Fields: []string{"Counter"},
+		Criteria: "Flag = ?", Parameters: []any{true}, Values: [][]any{{99}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	_, _, err = mem.Update("MemoryStruct", &common.Entries{Fields: []string{"Name"},
+		Values: [][]any{{"All"}}})
+	assert.Error(t, err)
+
+	n, err = mem.Delete("MemoryStruct", &common.Entries{Fields: []string{"ID"},
+		Values: [][]any{{"ID001"}, {"ID005"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	names := make([]any, 0)
+	counters := make([]any, 0)
+	_, err = mem.Query(&common.Query{TableName: "MemoryStruct",
+		Fields: []string{"Name", "Counter"}, Order: []string{"ID:ASC"}},
+		func(search *common.Query, result *common.Result) error {
+			names = append(names, result.Rows[0])
+			counters = append(counters, result.Rows[1])
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"Name 2", "Changed", "Name 4"}, names)
+	assert.Equal(t, []any{int64(99), int64(30), int64(99)}, counters)
+
+	_, err = mem.Query(&common.Query{TableName: "MemoryStruct", Fields: []string{"Unknown"}},
+		func(search *common.Query, result *common.Result) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestMemoryTransaction(t *testing.T) {
+	InitLog(t)
+
+	mem := memoryInstance(t, 2002, "memory://transaction")
+	if mem == nil {
+		return
+	}
+	columns := []*common.Column{{Name: "Id", DataType: common.Alpha, Length: 8},
+		{Name: "Name", DataType: common.Alpha, Length: 20}}
+	err := mem.CreateTable("MemoryTransaction", columns)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer mem.DeleteTable("MemoryTransaction")
+	insert := &common.Entries{Fields: []string{"Id", "Name"},
+		Values: [][]any{{"T1", "Rollback"}}}
+
+	err = mem.BeginTransaction()
+	assert.NoError(t, err)
+	_, err = mem.Insert("MemoryTransaction", insert)
+	assert.NoError(t, err)
+	assert.NoError(t, mem.Rollback())
+
+	insert.Values = [][]any{{"T2", "Commit"}}
+	err = mem.BeginTransaction()
+	assert.NoError(t, err)
+	_, err = mem.Insert("MemoryTransaction", insert)
+	assert.NoError(t, err)
+	assert.NoError(t, mem.Commit())
+
+	// second instance shares the same database
+	other := memoryInstance(t, 2003, "memory://transaction")
+	if other == nil {
+		return
+	}
+	ids := make([]any, 0)
+	_, err = other.Query(&common.Query{TableName: "MemoryTransaction", Fields: []string{"Id"}},
+		func(search *common.Query, result *common.Result) error {
+			ids = append(ids, result.Rows[0])
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"T2"}, ids)
+}
+
+func TestMemoryStream(t *testing.T) {
+	InitLog(t)
+
+	mem := memoryInstance(t, 2004, "memory://stream")
+	if mem == nil {
+		return
+	}
+	columns := []*common.Column{{Name: "Id", DataType: common.Alpha, Length: 8},
+		{Name: "Data", DataType: common.BLOB, Length: 4096}}
+	err := mem.CreateTable("MemoryStream", columns)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer mem.DeleteTable("MemoryStream")
+	blob := bytes.Repeat([]byte("0123456789"), 105)
+	_, err = mem.Insert("MemoryStream", &common.Entries{Fields: []string{"Id", "Data"},
+		Values: [][]any{{"S1", blob}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var buffer bytes.Buffer
+	count := 0
+	err = mem.Stream(&common.Query{TableName: "MemoryStream", Fields: []string{"Data"},
+		Search: "Id='S1'", Blocksize: 100},
+		func(search *common.Query, stream *common.Stream) error {
+			buffer.Write(stream.Data)
+			count++
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, 11, count)
+	assert.Equal(t, blob, buffer.Bytes())
+
+	err = mem.Stream(&common.Query{TableName: "MemoryStream", Fields: []string{"Data"},
+		Search: "Id='S2'", Blocksize: 100},
+		func(search *common.Query, stream *common.Stream) error {
+			return nil
+		})
+	assert.Error(t, err)
+}
+
+func TestMemorySearch(t *testing.T) {
+	InitLog(t)
+
+	tb := &table{name: "Search", columns: []*common.Column{
+		{Name: "Name", DataType: common.Alpha},
+		{Name: "Counter", DataType: common.Integer},
+		{Name: "Created", DataType: common.Date}}}
+	row := []any{"O'Brien", int64(42), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+	nullRow := []any{nil, nil, nil}
+	tests := []struct {
+		search string
+		match  bool
+		null   bool
+	}{
+		{"Name = 'O''Brien'", true, false},
+		{"tn.Counter > 40 AND Counter <= 42", true, false},
+		{"Counter <> 42 OR Name LIKE 'O%'", true, false},
+		{"NOT (Counter = 42)", false, false},
+		{"Counter IN (1, 2, 42)", true, false},
+		{"Counter NOT IN (1, 2)", true, false},
+		{"Counter BETWEEN 40 AND 50", true, false},
+		{"Name NOT LIKE '_''B%'", false, false},
+		{"Created > '2024-01-01'", true, false},
+		{"Name IS NULL", false, true},
+		{"Name IS NOT NULL", true, false},
+		{"Counter = 42 OR Name = 'X'", true, false},
+	}
+	for _, test := range tests {
+		e, err := parseSearch(tb, test.search)
+		if !assert.NoError(t, err, test.search) {
+			continue
+		}
+		assert.Equal(t, toCondition(test.match), e.evaluate(row), test.search)
+		assert.Equal(t, test.null, e.evaluate(nullRow) == trueCondition, test.search)
+	}
+	for _, search := range []string{"Name = 'open", "Unknown = 1", "Counter =", "(Counter = 1",
+		"Counter 1", "Name LIKE Name"} {
+		_, err := parseSearch(tb, search)
+		assert.Error(t, err, search)
+	}
+}
+
+func TestMemorySearchParameters(t *testing.T) {
+	InitLog(t)
+
+	tb := &table{name: "Search", columns: []*common.Column{
+		{Name: "Name", DataType: common.Alpha},
+		{Name: "Counter", DataType: common.Integer}}}
+	row := []any{"O'Brien", int64(42)}
+	tests := []struct {
+		search     string
+		parameters []any
+		match      bool
+	}{
+		{"Name = ?", []any{"O'Brien"}, true},
+		{"Name = ?", []any{"x' OR '1'='1"}, false},
+		{"Counter > ? AND Counter < ?", []any{40, uint8(50)}, true},
+		{"Counter IN (?, ?)", []any{int32(1), 42.0}, true},
+		{"Name LIKE ?", []any{"O%"}, true},
+		{"Name = ?", []any{nil}, false},
+	}
+	for _, test := range tests {
+		e, err := parseSearch(tb, test.search, test.parameters...)
+		if assert.NoError(t, err, test.search) {
+			assert.Equal(t, test.match, e.evaluate(row) == trueCondition, test.search)
+		}
+	}
+	_, err := parseSearch(tb, "Name = ? AND Counter = ?", "O'Brien")
+	assert.Error(t, err)
+
+	mem := memoryInstance(t, 2005, "memory://parameters")
+	if mem == nil {
+		return
+	}
+	err = mem.CreateTable("Parameters", &memoryRecord{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = mem.Insert("Parameters", &common.Entries{Fields: []string{"ID", "Name"},
+		Values: [][]any{{"P1", "O'Brien"}, {"P2", "Smith"}}})
+	assert.NoError(t, err)
+	ids := make([]any, 0)
+	_, err = mem.Query(&common.Query{TableName: "Parameters", Fields: []string{"ID"},
+		Search: "Name = :name OR ID = $1", Parameters: []any{"P1", sql.Named("name", "Smith")},
+		Order: []string{"ID"}},
+		func(search *common.Query, result *common.Result) error {
+			ids = append(ids, result.Rows[0])
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"P1", "P2"}, ids)
+}
+
+func TestMemoryPredicate(t *testing.T) {
+	InitLog(t)
+
+	mem := memoryInstance(t, 2006, "memory://predicate")
+	if mem == nil {
+		return
+	}
+	err := mem.CreateTable("Predicate", &memoryRecord{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = mem.Insert("Predicate", &common.Entries{Fields: []string{"ID", "Name", "Counter"},
+		Values: [][]any{{"P1", "Anna", 3}, {"P2", "Berta", 1}, {"P3", "Anton", 2}, {"P4", "Carl", 4}}})
+	assert.NoError(t, err)
+	_, err = mem.Insert("Predicate", &common.Entries{Fields: []string{"ID", "Name"},
+		Values: [][]any{{"P5", "Dora"}}})
+	assert.NoError(t, err)
+
+	query := func(q *common.Query) []any {
+		ids := make([]any, 0)
+		q.TableName = "Predicate"
+		q.Fields = []string{"ID"}
+		_, err := mem.Query(q, func(search *common.Query, result *common.Result) error {
+			ids = append(ids, result.Rows[0])
+			return nil
+		})
+		assert.NoError(t, err)
+		return ids
+	}
+	assert.Equal(t, []any{"P3", "P1"}, query(&common.Query{
+		Where: common.And(common.Like("Name", "An%"), common.Between("Counter", 2, 3)),
+		Sort:  []common.SortField{common.Asc("Counter")}}))
+	assert.Equal(t, []any{"P2", "P4", "P5"}, query(&common.Query{
+		Where: common.Or(common.In("ID", "P2", "P4"), common.IsNull("Counter")),
+		Sort:  []common.SortField{common.Asc("ID")}}))
+	assert.Equal(t, []any{"P2", "P5"}, query(&common.Query{Search: "Counter < ? OR Counter IS NULL",
+		Parameters: []any{3}, Where: common.Not(common.Eq("ID", "P3")), Order: []string{"ID"}}))
+	assert.Equal(t, []any{"P5", "P4", "P1"}, query(&common.Query{
+		Sort: []common.SortField{common.Desc("Counter").WithNulls(common.NullsFirst)}, LimitRows: 3}))
+	assert.Equal(t, []any{"P1", "P4", "P5"}, query(&common.Query{
+		Sort: []common.SortField{common.Asc("Counter").WithNulls(common.NullsLast)}, Offset: 2}))
+
+	_, err = mem.Query(&common.Query{TableName: "Predicate", Fields: []string{"ID"},
+		Where: common.Eq("ID;DROP", 1)}, func(search *common.Query, result *common.Result) error {
+		return nil
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,158 @@
+//go:build !flynn_nomemory
+// +build !flynn_nomemory
+
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+)
+
+// resultLayer internal database/sql driver name used to provide query
+// results as `*sql.Rows`. This way the struct mapping of the SQL drivers
+// is reused for memory tables.
+const resultLayer = "flynn-memory-result"
+
+var (
+	resultOnce sync.Once
+	resultDB   *sql.DB
+	resultErr  error
+	resultSets sync.Map
+	resultID   atomic.Uint64
+)
+
+// resultSet query result prepared to be read using database/sql
+type resultSet struct {
+	columns []*common.Column
+	rows    [][]any
+}
+
+type resultDriver struct{}
+
+type resultConn struct{}
+
+type resultRows struct {
+	set   *resultSet
+	index int
+}
+
+func init() {
+	sql.Register(resultLayer, &resultDriver{})
+}
+
+// Open open connection of the internal result driver
+func (d *resultDriver) Open(name string) (driver.Conn, error) {
+	return &resultConn{}, nil
+}
+
+// Prepare statements are not supported by the internal result driver
+func (c *resultConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// Close close connection
+func (c *resultConn) Close() error {
+	return nil
+}
+
+// Begin transactions are not supported by the internal result driver
+func (c *resultConn) Begin() (driver.Tx, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// QueryContext return the result set registered for the query token
+func (c *resultConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	set, ok := resultSets.LoadAndDelete(query)
+	if !ok {
+		return nil, errorrepo.NewError("DB000021")
+	}
+	return &resultRows{set: set.(*resultSet)}, nil
+}
+
+// Columns names of result columns
+func (r *resultRows) Columns() []string {
+	names := make([]string, len(r.set.columns))
+	for i, c := range r.set.columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Close close result rows
+func (r *resultRows) Close() error {
+	r.index = len(r.set.rows)
+	return nil
+}
+
+// Next provide next result row
+func (r *resultRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.set.rows) {
+		return io.EOF
+	}
+	for i, v := range r.set.rows[r.index] {
+		dest[i] = v
+	}
+	r.index++
+	return nil
+}
+
+// ColumnTypeDatabaseTypeName SQL type name of the column
+func (r *resultRows) ColumnTypeDatabaseTypeName(index int) string {
+	switch r.set.columns[index].DataType {
+	case common.Alpha, common.Text, common.Unicode, common.Character:
+		return "VARCHAR"
+	case common.Integer, common.BigInteger, common.Bit:
+		return "INTEGER"
+	case common.Decimal, common.Number:
+		return "DECIMAL"
+	case common.Boolean:
+		return "BOOLEAN"
+	case common.Date, common.CurrentTimestamp:
+		return "TIMESTAMP"
+	case common.Bytes, common.BLOB:
+		return "BLOB"
+	}
+	return ""
+}
+
+// ColumnTypeNullable all memory columns are nullable
+func (r *resultRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return true, true
+}
+
+// queryRows provide the result set as `*sql.Rows`
+func queryRows(ctx context.Context, set *resultSet) (*sql.Rows, error) {
+	resultOnce.Do(func() {
+		resultDB, resultErr = sql.Open(resultLayer, "")
+	})
+	if resultErr != nil {
+		return nil, resultErr
+	}
+	token := "result-" + strconv.FormatUint(resultID.Add(1), 10)
+	resultSets.Store(token, set)
+	rows, err := resultDB.QueryContext(ctx, token)
+	if err != nil {
+		resultSets.Delete(token)
+		return nil, err
+	}
+	return rows, nil
+}
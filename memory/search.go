@@ -0,0 +1,592 @@
+//go:build !flynn_nomemory
+// +build !flynn_nomemory
+
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package memory
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+)
+
+// condition result using SQL three-valued logic
+type condition int8
+
+const (
+	unknownCondition condition = iota - 1
+	falseCondition
+	trueCondition
+)
+
+func toCondition(b bool) condition {
+	if b {
+		return trueCondition
+	}
+	return falseCondition
+}
+
+// expression search expression evaluated on a table row
+type expression interface {
+	evaluate(row []any) condition
+}
+
+// operand column reference or constant value used in a search expression
+type operand struct {
+	column int
+	value  any
+}
+
+func (o *operand) get(row []any) any {
+	if o.column >= 0 {
+		return row[o.column]
+	}
+	return o.value
+}
+
+type logicalExpression struct {
+	and         bool
+	left, right expression
+}
+
+func (l *logicalExpression) evaluate(row []any) condition {
+	lc := l.left.evaluate(row)
+	if l.and && lc == falseCondition {
+		return falseCondition
+	}
+	if !l.and && lc == trueCondition {
+		return trueCondition
+	}
+	rc := l.right.evaluate(row)
+	switch {
+	case l.and && rc == falseCondition:
+		return falseCondition
+	case !l.and && rc == trueCondition:
+		return trueCondition
+	case lc == unknownCondition || rc == unknownCondition:
+		return unknownCondition
+	}
+	return rc
+}
+
+type notExpression struct {
+	expression expression
+}
+
+func (n *notExpression) evaluate(row []any) condition {
+	c := n.expression.evaluate(row)
+	if c == unknownCondition {
+		return c
+	}
+	return toCondition(c == falseCondition)
+}
+
+type compareExpression struct {
+	operator    string
+	left, right *operand
+}
+
+func (c *compareExpression) evaluate(row []any) condition {
+	cmp, ok := compare(c.left.get(row), c.right.get(row))
+	if !ok {
+		return unknownCondition
+	}
+	switch c.operator {
+	case "=":
+		return toCondition(cmp == 0)
+	case "<>", "!=":
+		return toCondition(cmp != 0)
+	case "<":
+		return toCondition(cmp < 0)
+	case "<=":
+		return toCondition(cmp <= 0)
+	case ">":
+		return toCondition(cmp > 0)
+	default:
+		return toCondition(cmp >= 0)
+	}
+}
+
+type likeExpression struct {
+	value   *operand
+	pattern *regexp.Regexp
+}
+
+func (l *likeExpression) evaluate(row []any) condition {
+	v := l.value.get(row)
+	if v == nil {
+		return unknownCondition
+	}
+	return toCondition(l.pattern.MatchString(stringValue(v)))
+}
+
+type inExpression struct {
+	value *operand
+	list  []*operand
+}
+
+func (in *inExpression) evaluate(row []any) condition {
+	v := in.value.get(row)
+	result := falseCondition
+	for _, o := range in.list {
+		cmp, ok := compare(v, o.get(row))
+		if !ok {
+			result = unknownCondition
+			continue
+		}
+		if cmp == 0 {
+			return trueCondition
+		}
+	}
+	return result
+}
+
+type nullExpression struct {
+	value *operand
+}
+
+func (n *nullExpression) evaluate(row []any) condition {
+	return toCondition(n.value.get(row) == nil)
+}
+
+// stringValue string representation used for LIKE comparison
+func stringValue(v any) string {
+	switch vt := v.(type) {
+	case string:
+		return vt
+	case []byte:
+		return string(vt)
+	case time.Time:
+		return vt.Format(time.RFC3339Nano)
+	case int64:
+		return strconv.FormatInt(vt, 10)
+	case float64:
+		return strconv.FormatFloat(vt, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(vt)
+	}
+	return ""
+}
+
+// parameterValue convert the query parameter into the value types used
+// by the search expressions
+func parameterValue(v any) any {
+	switch v.(type) {
+	case nil, string, []byte, time.Time, int64, float64, bool:
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+		return parameterValue(rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	return v
+}
+
+// numberValue numeric representation of value
+func numberValue(v any) (float64, bool) {
+	switch vt := v.(type) {
+	case int64:
+		return float64(vt), true
+	case float64:
+		return vt, true
+	case bool:
+		if vt {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(vt), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// compare compare two values, the second return value is false if one of
+// the values is NULL
+func compare(a, b any) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	switch at := a.(type) {
+	case time.Time:
+		if bt, ok := convertValue(common.Date, b); ok {
+			return at.Compare(bt.(time.Time)), true
+		}
+	case []byte:
+		return bytes.Compare(at, []byte(stringValue(b))), true
+	case string:
+		switch b.(type) {
+		case string, []byte:
+			return strings.Compare(at, stringValue(b)), true
+		case time.Time:
+			cmp, ok := compare(b, a)
+			return -cmp, ok
+		}
+	}
+	if _, ok := b.(time.Time); ok {
+		cmp, ok := compare(b, a)
+		return -cmp, ok
+	}
+	af, aok := numberValue(a)
+	bf, bok := numberValue(b)
+	if aok && bok {
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	}
+	return strings.Compare(stringValue(a), stringValue(b)), true
+}
+
+// likePattern convert SQL LIKE pattern into regular expression
+func likePattern(pattern string) (*regexp.Regexp, error) {
+	var buffer strings.Builder
+	buffer.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			buffer.WriteString(".*")
+		case '_':
+			buffer.WriteString(".")
+		default:
+			buffer.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	buffer.WriteString("$")
+	return regexp.Compile(buffer.String())
+}
+
+// token search expression token
+type token struct {
+	text   string
+	quoted bool
+	pos    int
+}
+
+// searchParser parser of SQL search expressions like used in WHERE clauses
+type searchParser struct {
+	table      *table
+	tokens     []token
+	pos        int
+	parameters []any
+}
+
+// parseSearch parse the search expression for the given table. The `?`
+// placeholders are replaced by the parameters. An empty search returns nil.
+func parseSearch(t *table, search string, parameters ...any) (expression, error) {
+	if strings.TrimSpace(search) == "" {
+		return nil, nil
+	}
+	tokens, err := tokenize(search)
+	if err != nil {
+		return nil, err
+	}
+	p := &searchParser{table: t, tokens: tokens, parameters: parameters}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.error("unexpected " + p.tokens[p.pos].text)
+	}
+	return e, nil
+}
+
+// parseCriteria parse the Criteria of the entries with the placeholders
+// bound to the Parameters
+func parseCriteria(t *table, entries *common.Entries) (expression, error) {
+	criteria, args, err := common.BindParameters(memoryType, entries.Criteria, entries.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	return parseSearch(t, criteria, args...)
+}
+
+// tokenize split the search into tokens
+func tokenize(search string) ([]token, error) {
+	tokens := make([]token, 0)
+	runes := []rune(search)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'':
+			var buffer strings.Builder
+			start := i
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, errorrepo.NewError("DB000040", start, "string not terminated")
+				}
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						buffer.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				buffer.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{text: buffer.String(), quoted: true, pos: start})
+		case strings.ContainsRune("(),", r):
+			tokens = append(tokens, token{text: string(r), pos: i})
+			i++
+		case strings.ContainsRune("=<>!", r):
+			start := i
+			i++
+			if i < len(runes) && strings.ContainsRune("=>", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{text: string(runes[start:i]), pos: start})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) &&
+				!strings.ContainsRune("(),=<>!'", runes[i]) {
+				i++
+			}
+			if start == i {
+				return nil, errorrepo.NewError("DB000040", start, string(r))
+			}
+			tokens = append(tokens, token{text: string(runes[start:i]), pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+func (p *searchParser) error(message string) error {
+	pos := 0
+	if p.pos < len(p.tokens) {
+		pos = p.tokens[p.pos].pos
+	}
+	return errorrepo.NewError("DB000040", pos, message)
+}
+
+// keyword check if the next token is the keyword and consume it
+func (p *searchParser) keyword(k string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted &&
+		strings.EqualFold(p.tokens[p.pos].text, k) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *searchParser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *searchParser) parseAnd() (expression, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *searchParser) parseNot() (expression, error) {
+	if p.keyword("NOT") {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpression{e}, nil
+	}
+	if p.keyword("(") {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, p.error("missing )")
+		}
+		return e, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *searchParser) parsePredicate() (expression, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("IS") {
+		not := p.keyword("NOT")
+		if !p.keyword("NULL") {
+			return nil, p.error("NULL expected")
+		}
+		var e expression = &nullExpression{left}
+		if not {
+			e = &notExpression{e}
+		}
+		return e, nil
+	}
+	not := p.keyword("NOT")
+	var e expression
+	switch {
+	case p.keyword("LIKE"):
+		pattern, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if pattern.column >= 0 {
+			return nil, p.error("LIKE pattern must be constant")
+		}
+		re, err := likePattern(stringValue(pattern.value))
+		if err != nil {
+			return nil, p.error(err.Error())
+		}
+		e = &likeExpression{value: left, pattern: re}
+	case p.keyword("IN"):
+		if !p.keyword("(") {
+			return nil, p.error("( expected")
+		}
+		in := &inExpression{value: left}
+		for {
+			o, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			in.list = append(in.list, o)
+			if p.keyword(")") {
+				break
+			}
+			if !p.keyword(",") {
+				return nil, p.error(", expected")
+			}
+		}
+		e = in
+	case p.keyword("BETWEEN"):
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("AND") {
+			return nil, p.error("AND expected")
+		}
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		e = &logicalExpression{and: true,
+			left:  &compareExpression{operator: ">=", left: left, right: low},
+			right: &compareExpression{operator: "<=", left: left, right: high}}
+	case not:
+		return nil, p.error("LIKE, IN or BETWEEN expected")
+	default:
+		if p.pos >= len(p.tokens) {
+			return nil, p.error("operator expected")
+		}
+		operator := p.tokens[p.pos].text
+		switch operator {
+		case "=", "<>", "!=", "<", "<=", ">", ">=":
+			p.pos++
+		default:
+			return nil, p.error("operator expected instead of " + operator)
+		}
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &compareExpression{operator: operator, left: left, right: right}, nil
+	}
+	if not {
+		e = &notExpression{e}
+	}
+	return e, nil
+}
+
+func (p *searchParser) parseOperand() (*operand, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, p.error("operand missing")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	if t.quoted {
+		return &operand{column: -1, value: t.text}, nil
+	}
+	if t.text == "?" {
+		if len(p.parameters) == 0 {
+			p.pos--
+			return nil, errorrepo.NewError("DB000050", "?")
+		}
+		value := parameterValue(p.parameters[0])
+		p.parameters = p.parameters[1:]
+		return &operand{column: -1, value: value}, nil
+	}
+	switch strings.ToUpper(t.text) {
+	case "NULL":
+		return &operand{column: -1}, nil
+	case "TRUE":
+		return &operand{column: -1, value: true}, nil
+	case "FALSE":
+		return &operand{column: -1, value: false}, nil
+	}
+	if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+		return &operand{column: -1, value: i}, nil
+	}
+	if f, err := strconv.ParseFloat(t.text, 64); err == nil {
+		return &operand{column: -1, value: f}, nil
+	}
+	column := p.table.columnIndex(t.text)
+	if column == -1 {
+		p.pos--
+		return nil, errorrepo.NewError("DB000039", t.text, p.table.name)
+	}
+	return &operand{column: column}, nil
+}
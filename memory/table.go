@@ -0,0 +1,296 @@
+//go:build !flynn_nomemory
+// +build !flynn_nomemory
+
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package memory
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+)
+
+// timeLayouts layouts used to convert strings into timestamp values
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02"}
+
+// databases all memory databases referenced by name
+var databases sync.Map
+
+// database memory database containing all tables of one name
+type database struct {
+	name   string
+	lock   sync.RWMutex
+	tables map[string]*table
+}
+
+// table memory table with column definition and row values. The rows
+// are never changed in place, updates replace the row slice entry so
+// snapshots for rollback only need to copy the row list.
+type table struct {
+	name    string
+	columns []*common.Column
+	rows    [][]any
+}
+
+// lookupDatabase get memory database for name, it is created if not exists
+func lookupDatabase(name string) *database {
+	db, _ := databases.LoadOrStore(name, &database{name: name,
+		tables: make(map[string]*table)})
+	return db.(*database)
+}
+
+// table search table by name
+func (db *database) table(name string) (*table, error) {
+	if t, ok := db.tables[strings.ToLower(name)]; ok {
+		return t, nil
+	}
+	return nil, errorrepo.NewError("DB000037", name)
+}
+
+// columnName column name without table alias and quotes
+func columnName(name string) string {
+	name = strings.TrimSpace(name)
+	if index := strings.LastIndexByte(name, '.'); index != -1 {
+		name = name[index+1:]
+	}
+	return strings.Trim(name, "\"`")
+}
+
+// columnIndex index of column name, -1 if not found
+func (t *table) columnIndex(name string) int {
+	name = columnName(name)
+	for i, c := range t.columns {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnIndexes indexes of all column names
+func (t *table) columnIndexes(names []string) ([]int, error) {
+	indexes := make([]int, 0, len(names))
+	for _, n := range names {
+		if n == "*" {
+			for i := range t.columns {
+				indexes = append(indexes, i)
+			}
+			continue
+		}
+		i := t.columnIndex(n)
+		if i == -1 {
+			return nil, errorrepo.NewError("DB000039", n, t.name)
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes, nil
+}
+
+// addColumns add all columns not already part of the table
+func (t *table) addColumns(columns []*common.Column) {
+	for _, c := range columns {
+		if t.columnIndex(c.Name) != -1 {
+			continue
+		}
+		t.columns = append(t.columns, c)
+		for i, r := range t.rows {
+			t.rows[i] = append(r[:len(r):len(r)], nil)
+		}
+	}
+}
+
+// convert convert value to the data type of the column
+func (t *table) convert(index int, value any) (any, error) {
+	c := t.columns[index]
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		value = b
+	}
+	v, err := driver.DefaultParameterConverter.ConvertValue(value)
+	if err != nil {
+		return nil, errorrepo.NewError("DB000041", value, c.DataType.SqlType(), c.Name)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	cv, ok := convertValue(c.DataType, v)
+	if !ok {
+		return nil, errorrepo.NewError("DB000041", value, c.DataType.SqlType(), c.Name)
+	}
+	return cv, nil
+}
+
+// convertValue convert driver value to the value stored for the data type
+func convertValue(dataType common.DataType, v any) (any, bool) {
+	switch dataType {
+	case common.Alpha, common.Text, common.Unicode, common.Character:
+		switch vt := v.(type) {
+		case string:
+			return vt, true
+		case []byte:
+			return string(vt), true
+		case time.Time:
+			return vt.Format(time.RFC3339Nano), true
+		default:
+			return fmt.Sprintf("%v", vt), true
+		}
+	case common.Integer, common.BigInteger, common.Bit:
+		return convertInteger(v)
+	case common.Decimal, common.Number:
+		switch vt := v.(type) {
+		case int64:
+			return vt, true
+		case float64:
+			return vt, true
+		case bool:
+			return convertInteger(vt)
+		case string:
+			if i, err := strconv.ParseInt(vt, 10, 64); err == nil {
+				return i, true
+			}
+			f, err := strconv.ParseFloat(vt, 64)
+			return f, err == nil
+		}
+	case common.Boolean:
+		switch vt := v.(type) {
+		case bool:
+			return vt, true
+		case int64:
+			return vt != 0, true
+		case float64:
+			return vt != 0, true
+		case string:
+			b, err := strconv.ParseBool(vt)
+			return b, err == nil
+		}
+	case common.Date, common.CurrentTimestamp:
+		switch vt := v.(type) {
+		case time.Time:
+			return vt, true
+		case string:
+			for _, l := range timeLayouts {
+				if tv, err := time.Parse(l, vt); err == nil {
+					return tv, true
+				}
+			}
+		}
+	case common.Bytes, common.BLOB:
+		switch vt := v.(type) {
+		case []byte:
+			return append([]byte(nil), vt...), true
+		case string:
+			return []byte(vt), true
+		}
+	default:
+		if b, ok := v.([]byte); ok {
+			return append([]byte(nil), b...), true
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+// convertInteger convert driver value to integer value
+func convertInteger(v any) (any, bool) {
+	switch vt := v.(type) {
+	case int64:
+		return vt, true
+	case float64:
+		if vt != math.Trunc(vt) {
+			return nil, false
+		}
+		return int64(vt), true
+	case bool:
+		if vt {
+			return int64(1), true
+		}
+		return int64(0), true
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(vt), 10, 64)
+		return i, err == nil
+	}
+	return nil, false
+}
+
+// structColumns generate column definitions out of the structure type
+// using the same field names like the SQL drivers
+func structColumns(rt reflect.Type) []*common.Column {
+	if rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	columns := make([]*common.Column, 0)
+	if rt.Kind() != reflect.Struct {
+		return columns
+	}
+	for fi := 0; fi < rt.NumField(); fi++ {
+		sf := rt.Field(fi)
+		if sf.Name == "" || unicode.IsLower([]rune(sf.Name)[0]) {
+			continue
+		}
+		tagName, tagInfo := common.TagInfoParse(sf.Tag.Get(common.TagName))
+		fieldName := sf.Name
+		if tagName != "" {
+			fieldName = tagName
+		}
+		st := sf.Type
+		if st.Kind() == reflect.Pointer {
+			st = st.Elem()
+		}
+		switch tagInfo {
+		case common.IgnoreTag, common.IndexTag, common.JoinTag:
+			continue
+		case common.SubTag:
+			columns = append(columns, &common.Column{Name: fieldName, DataType: common.Bytes})
+			continue
+		case common.YAMLTag, common.XMLTag, common.JSONTag:
+			columns = append(columns, &common.Column{Name: fieldName, DataType: common.Text})
+			continue
+		}
+		dataType := common.None
+		switch st.Kind() {
+		case reflect.String:
+			dataType = common.Alpha
+		case reflect.Bool:
+			dataType = common.Boolean
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			dataType = common.Integer
+		case reflect.Float32, reflect.Float64:
+			dataType = common.Decimal
+		case reflect.Slice, reflect.Array:
+			if st.Elem().Kind() == reflect.Uint8 {
+				dataType = common.Bytes
+			}
+		case reflect.Struct:
+			if st.PkgPath() == "time" && st.Name() == "Time" {
+				dataType = common.Date
+			} else {
+				columns = append(columns, structColumns(st)...)
+				continue
+			}
+		}
+		columns = append(columns, &common.Column{Name: fieldName, DataType: dataType})
+	}
+	return columns
+}
@@ -0,0 +1,422 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+// DefaultTable bookkeeping table of the applied migrations
+const DefaultTable = "flynn_schema_migrations"
+
+// DefaultLockTimeout time waiting for the migration lock held by another
+// instance
+var DefaultLockTimeout = time.Minute
+
+// lockPoll interval checking the migration lock
+var lockPoll = 500 * time.Millisecond
+
+// lockID id of the single record of the lock table
+const lockID = 1
+
+// MigrationFunc migration implemented in GO. It is called in the
+// transaction of the migration after the SQL statements.
+type MigrationFunc func(ctx context.Context, tx *common.Tx) error
+
+// Migration schema migration. The SQL of the up and down migration is
+// stored per driver name like `postgres`, the empty driver name is used for
+// all drivers without own SQL.
+type Migration struct {
+	Version int64
+	Name    string
+	UpSQL   map[string]string
+	DownSQL map[string]string
+	Up      MigrationFunc
+	Down    MigrationFunc
+}
+
+// Status status of a migration. Changed is set if the applied migration
+// was changed after it was applied.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Changed   bool
+}
+
+// Migrator apply the migrations to the database of the registry id. Each
+// migration is applied in its own transaction and recorded in the
+// bookkeeping table. A lock table prevents concurrent migrations.
+type Migrator struct {
+	Table       string
+	LockTimeout time.Duration
+	id          common.RegDbID
+	driver      string
+	migrations  []*Migration
+}
+
+// appliedMigration record of the bookkeeping table
+type appliedMigration struct {
+	Version   int64     `flynn:"version:PRIMARY KEY"`
+	Name      string    `flynn:"name"`
+	Checksum  string    `flynn:"checksum"`
+	AppliedAt time.Time `flynn:"applied_at"`
+}
+
+// migrationLock record of the lock table
+type migrationLock struct {
+	ID       int64     `flynn:"id:PRIMARY KEY"`
+	LockedAt time.Time `flynn:"locked_at"`
+}
+
+// New new migrator of the migrations for the database of the registry id
+func New(id common.RegDbID, migrations ...*Migration) (*Migrator, error) {
+	m := &Migrator{Table: DefaultTable, LockTimeout: DefaultLockTimeout, id: id,
+		driver: strings.ToLower(id.DriverName())}
+	for _, migration := range migrations {
+		if err := m.add(migration); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Register register a migration implemented in GO, the down migration may
+// be nil
+func (m *Migrator) Register(version int64, name string, up, down MigrationFunc) error {
+	return m.add(&Migration{Version: version, Name: name, Up: up, Down: down})
+}
+
+// add add the migration in version order
+func (m *Migrator) add(migration *Migration) error {
+	if migration.Version <= 0 {
+		return errorrepo.NewError("DB000070", migration.Name, "no valid version")
+	}
+	if slices.ContainsFunc(m.migrations, func(o *Migration) bool { return o.Version == migration.Version }) {
+		return errorrepo.NewError("DB000070", migration.Name, "version is used twice")
+	}
+	m.migrations = append(m.migrations, migration)
+	sort.Slice(m.migrations, func(i, j int) bool { return m.migrations[i].Version < m.migrations[j].Version })
+	return nil
+}
+
+// Up apply all migrations not applied yet and return the number of
+// applied migrations
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.withLock(ctx, func(applied map[int64]*appliedMigration) (int, error) {
+		return m.up(ctx, applied, math.MaxInt64)
+	})
+}
+
+// Down roll back the last n applied migrations and return the number of
+// rolled back migrations
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	return m.withLock(ctx, func(applied map[int64]*appliedMigration) (int, error) {
+		versions := appliedVersions(applied)
+		if n < len(versions) {
+			versions = versions[:n]
+		}
+		return m.down(ctx, versions)
+	})
+}
+
+// Goto apply or roll back the migrations until the version is the last
+// applied migration. Version 0 rolls back all migrations.
+func (m *Migrator) Goto(ctx context.Context, version int64) (int, error) {
+	return m.withLock(ctx, func(applied map[int64]*appliedMigration) (int, error) {
+		versions := make([]int64, 0)
+		for _, v := range appliedVersions(applied) {
+			if v > version {
+				versions = append(versions, v)
+			}
+		}
+		count, err := m.down(ctx, versions)
+		if err != nil {
+			return count, err
+		}
+		for _, v := range versions {
+			delete(applied, v)
+		}
+		up, err := m.up(ctx, applied, version)
+		return count + up, err
+	})
+}
+
+// Status status of all migrations and of applied migrations unknown to
+// the migrator, ordered by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied := make(map[int64]*appliedMigration)
+	exists, err := m.tableExists(ctx, m.Table)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		applied, err = m.applied(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	status := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := Status{Version: migration.Version, Name: migration.Name}
+		if a, ok := applied[migration.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = a.AppliedAt
+			s.Changed = m.checkApplied(migration, a) != nil
+			delete(applied, migration.Version)
+		}
+		status = append(status, s)
+	}
+	for _, a := range applied {
+		status = append(status, Status{Version: a.Version, Name: a.Name, Applied: true, AppliedAt: a.AppliedAt})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status, nil
+}
+
+// Unlock remove the migration lock. It is needed if a migration process
+// was stopped while it held the lock.
+func (m *Migrator) Unlock(ctx context.Context) error {
+	_, err := m.id.DeleteContext(ctx, m.lockTable(), &common.Entries{Fields: []string{"id"},
+		Values: [][]any{{lockID}}})
+	return err
+}
+
+// withLock call the function holding the migration lock with the applied
+// migrations, the checksums of the applied migrations are verified before
+func (m *Migrator) withLock(ctx context.Context, f func(applied map[int64]*appliedMigration) (int, error)) (int, error) {
+	if err := m.ensureTable(ctx, m.Table, &appliedMigration{}); err != nil {
+		return 0, err
+	}
+	if err := m.ensureTable(ctx, m.lockTable(), &migrationLock{}); err != nil {
+		return 0, err
+	}
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := m.Unlock(context.WithoutCancel(ctx)); err != nil {
+			log.Log.Errorf("Error removing migration lock %s: %v", m.lockTable(), err)
+		}
+	}()
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, migration := range m.migrations {
+		if a, ok := applied[migration.Version]; ok {
+			if err := m.checkApplied(migration, a); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return f(applied)
+}
+
+// up apply the migrations not applied up to the version
+func (m *Migrator) up(ctx context.Context, applied map[int64]*appliedMigration, version int64) (int, error) {
+	count := 0
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.apply(ctx, migration, true); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// down roll back the applied migrations of the versions in the given order
+func (m *Migrator) down(ctx context.Context, versions []int64) (int, error) {
+	for i, v := range versions {
+		index := slices.IndexFunc(m.migrations, func(o *Migration) bool { return o.Version == v })
+		if index < 0 {
+			return i, errorrepo.NewError("DB000069", v)
+		}
+		if err := m.apply(ctx, m.migrations[index], false); err != nil {
+			return i, err
+		}
+	}
+	return len(versions), nil
+}
+
+// apply apply or roll back the migration in a transaction and update the
+// bookkeeping table
+func (m *Migrator) apply(ctx context.Context, migration *Migration, up bool) error {
+	statement, ok := migration.sql(up, m.driver)
+	f := migration.Up
+	if !up {
+		f = migration.Down
+	}
+	if !ok && f == nil {
+		if up {
+			return errorrepo.NewError("DB000070", migration.Name, "no up migration for driver "+m.driver)
+		}
+		return errorrepo.NewError("DB000068", migration.Name, m.driver)
+	}
+	log.Log.Debugf("Migrate %d_%s up=%v", migration.Version, migration.Name, up)
+	return m.id.WithTransaction(ctx, func(tx *common.Tx) error {
+		for _, s := range splitStatements(statement) {
+			if err := tx.Batch(s); err != nil {
+				return err
+			}
+		}
+		if f != nil {
+			if err := f(tx.Context(), tx); err != nil {
+				return err
+			}
+		}
+		if !up {
+			_, err := tx.Delete(m.Table, &common.Entries{Fields: []string{"version"},
+				Values: [][]any{{migration.Version}}})
+			return err
+		}
+		record := &appliedMigration{Version: migration.Version, Name: migration.Name,
+			Checksum: migration.checksum(m.driver), AppliedAt: time.Now()}
+		_, err := tx.Insert(m.Table, &common.Entries{DataStruct: record, Fields: []string{"*"},
+			Values: [][]any{{record}}})
+		return err
+	})
+}
+
+// checkApplied check that the migration was not changed after it was
+// applied
+func (m *Migrator) checkApplied(migration *Migration, applied *appliedMigration) error {
+	checksum := migration.checksum(m.driver)
+	if checksum != applied.Checksum {
+		return errorrepo.NewError("DB000067", migration.Name, applied.Checksum, checksum)
+	}
+	return nil
+}
+
+// applied applied migrations of the bookkeeping table
+func (m *Migrator) applied(ctx context.Context) (map[int64]*appliedMigration, error) {
+	applied := make(map[int64]*appliedMigration)
+	_, err := m.id.QueryContext(ctx, &common.Query{TableName: m.Table, DataStruct: &appliedMigration{},
+		Fields: []string{"*"}},
+		func(search *common.Query, result *common.Result) error {
+			a := *result.Data.(*appliedMigration)
+			applied[a.Version] = &a
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// appliedVersions versions of the applied migrations, the last applied
+// version first
+func appliedVersions(applied map[int64]*appliedMigration) []int64 {
+	versions := make([]int64, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	slices.Sort(versions)
+	slices.Reverse(versions)
+	return versions
+}
+
+// lock acquire the migration lock inserting the lock record. If another
+// instance holds the lock, it is retried until the lock timeout.
+func (m *Migrator) lock(ctx context.Context) error {
+	deadline := time.Now().Add(m.LockTimeout)
+	for {
+		_, err := m.id.InsertContext(ctx, m.lockTable(), &common.Entries{Fields: []string{"id", "locked_at"},
+			Values: [][]any{{lockID, time.Now()}}})
+		if err == nil {
+			return nil
+		}
+		log.Log.Debugf("Migration lock %s not acquired: %v", m.lockTable(), err)
+		if !time.Now().Before(deadline) {
+			return errorrepo.NewError("DB000071", m.lockTable())
+		}
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(lockPoll):
+		}
+	}
+}
+
+func (m *Migrator) lockTable() string {
+	return m.Table + "_lock"
+}
+
+// ensureTable create the table if it does not exist
+func (m *Migrator) ensureTable(ctx context.Context, name string, columns any) error {
+	exists, err := m.tableExists(ctx, name)
+	if err != nil || exists {
+		return err
+	}
+	err = m.id.CreateTable(name, columns)
+	if err != nil {
+		// another instance may have created the table
+		if exists, _ := m.tableExists(ctx, name); exists {
+			return nil
+		}
+	}
+	return err
+}
+
+// tableExists check if the table exists, the table names are read again
+func (m *Migrator) tableExists(ctx context.Context, name string) (bool, error) {
+	if err := m.id.PingContext(ctx); err != nil {
+		return false, err
+	}
+	tables, err := m.id.Tables()
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(tables, func(t string) bool { return strings.EqualFold(t, name) }), nil
+}
+
+// sql SQL of the up or down migration for the driver
+func (migration *Migration) sql(up bool, driver string) (string, bool) {
+	statements := migration.UpSQL
+	if !up {
+		statements = migration.DownSQL
+	}
+	if s, ok := statements[driver]; ok {
+		return s, true
+	}
+	s, ok := statements[""]
+	return s, ok
+}
+
+// checksum SHA-256 checksum of the up SQL for the driver, empty for
+// migrations implemented in GO only
+func (migration *Migration) checksum(driver string) string {
+	s, ok := migration.sql(true, driver)
+	if !ok {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
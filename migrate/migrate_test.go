@@ -0,0 +1,196 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/flynn/sqlite"
+)
+
+var migrationFiles = fstest.MapFS{
+	"db/0001_users.up.sql":          {Data: []byte("CREATE TABLE users (id INTEGER, name VARCHAR(255));\nINSERT INTO users VALUES (1, 'a;b');")},
+	"db/0001_users.down.sql":        {Data: []byte("DROP TABLE users;")},
+	"db/0002_orders.up.sql":         {Data: []byte("CREATE TABLE orders (id BIGINT)")},
+	"db/0002_orders.up.sqlite.sql":  {Data: []byte("-- SQLite; own statement\nCREATE TABLE orders (id INTEGER)")},
+	"db/0002_orders.down.sql":       {Data: []byte("DROP TABLE orders")},
+	"db/README.md":                  {Data: []byte("not a migration")},
+	"invalid/users.up.sql":          {Data: []byte("SELECT 1")},
+	"invalid/0001_users.apply.sql":  {Data: []byte("SELECT 1")},
+	"duplicate/0001_users.up.sql":   {Data: []byte("SELECT 1")},
+	"duplicate/0001_orders.up.sql":  {Data: []byte("SELECT 1")},
+	"duplicate/0001_users.down.sql": {Data: []byte("SELECT 1")},
+}
+
+func migrateInstance(t *testing.T, id common.RegDbID) common.RegDbID {
+	db, err := sqlite.New(id, "sqlite://:memory:")
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	common.RegisterDbClient(db)
+	t.Cleanup(func() { id.FreeHandler() })
+	return id
+}
+
+func selectValue(t *testing.T, id common.RegDbID, query string) string {
+	rows, err := id.BatchSelect(query)
+	if !assert.NoError(t, err) || !assert.Len(t, rows, 1) {
+		return ""
+	}
+	return rows[0][0].(sql.NullString).String
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(migrationFiles, "db")
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.Len(t, migrations, 2) {
+		assert.Equal(t, int64(1), migrations[0].Version)
+		assert.Equal(t, "users", migrations[0].Name)
+		assert.Equal(t, "DROP TABLE users;", migrations[0].DownSQL[""])
+		assert.Len(t, migrations[1].UpSQL, 2)
+		s, ok := migrations[1].sql(true, "postgres")
+		assert.True(t, ok)
+		assert.Equal(t, "CREATE TABLE orders (id BIGINT)", s)
+		s, _ = migrations[1].sql(true, "sqlite")
+		assert.Equal(t, "-- SQLite; own statement\nCREATE TABLE orders (id INTEGER)", s)
+		_, ok = migrations[1].sql(true, "")
+		assert.True(t, ok)
+	}
+	_, err = Load(migrationFiles, "invalid")
+	assert.Error(t, err)
+	_, err = Load(migrationFiles, "duplicate")
+	assert.Error(t, err)
+}
+
+func TestSplitStatements(t *testing.T) {
+	assert.Equal(t, []string{"CREATE TABLE a (x INTEGER)", "INSERT INTO a VALUES ('x;y')"},
+		splitStatements("CREATE TABLE a (x INTEGER);\n INSERT INTO a VALUES ('x;y');\n"))
+	assert.Equal(t, []string{"-- a; b\nSELECT 1", "/* ; */ SELECT \"a;\"", "DO $$ BEGIN PERFORM 1; END $$"},
+		splitStatements("-- a; b\nSELECT 1; /* ; */ SELECT \"a;\"; DO $$ BEGIN PERFORM 1; END $$"))
+	assert.Equal(t, []string{"SELECT 'open;"}, splitStatements("SELECT 'open;"))
+	assert.Empty(t, splitStatements(" ;\n"))
+}
+
+func TestMigrate(t *testing.T) {
+	id := migrateInstance(t, 3001)
+	if id == 0 {
+		return
+	}
+	ctx := context.Background()
+	migrations, err := Load(migrationFiles, "db")
+	if !assert.NoError(t, err) {
+		return
+	}
+	m, err := New(id, migrations...)
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = m.Register(3, "fill", func(ctx context.Context, tx *common.Tx) error {
+		_, err := tx.Insert("orders", &common.Entries{Fields: []string{"id"}, Values: [][]any{{10}, {11}}})
+		return err
+	}, func(ctx context.Context, tx *common.Tx) error {
+		return tx.Batch("DELETE FROM orders")
+	})
+	assert.NoError(t, err)
+	assert.Error(t, m.Register(3, "twice", nil, nil))
+
+	status, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, status, 3)
+	assert.False(t, status[0].Applied)
+
+	count, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, "a;b", selectValue(t, id, "SELECT name FROM users"))
+	assert.Equal(t, "2", selectValue(t, id, "SELECT COUNT(*) FROM orders"))
+	count, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	status, err = m.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range status {
+		assert.True(t, s.Applied)
+		assert.False(t, s.Changed)
+		assert.False(t, s.AppliedAt.IsZero())
+	}
+
+	count, err = m.Down(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "0", selectValue(t, id, "SELECT COUNT(*) FROM orders"))
+
+	count, err = m.Goto(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	status, err = m.Status(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, false, false}, []bool{status[0].Applied, status[1].Applied, status[2].Applied})
+	count, err = m.Goto(ctx, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// changed migration file of an applied migration
+	migrations[0].UpSQL[""] += "\nINSERT INTO users VALUES (2, 'c');"
+	status, err = m.Status(ctx)
+	assert.NoError(t, err)
+	assert.True(t, status[0].Changed)
+	_, err = m.Up(ctx)
+	assert.Error(t, err)
+	_, err = m.Down(ctx, 1)
+	assert.Error(t, err)
+
+	// migration lock held by another instance
+	other, err := New(id)
+	assert.NoError(t, err)
+	other.LockTimeout = 10 * time.Millisecond
+	assert.NoError(t, other.lock(ctx))
+	_, err = other.Up(ctx)
+	assert.Error(t, err)
+	assert.NoError(t, other.Unlock(ctx))
+	_, err = other.Up(ctx)
+	assert.NoError(t, err)
+}
+
+func TestMigrateFailure(t *testing.T) {
+	id := migrateInstance(t, 3002)
+	if id == 0 {
+		return
+	}
+	ctx := context.Background()
+	m, err := New(id, &Migration{Version: 1, Name: "broken",
+		UpSQL: map[string]string{"": "CREATE TABLE broken (id INTEGER); INSERT INTO unknown VALUES (1)"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = m.Up(ctx)
+	assert.Error(t, err)
+	status, err := m.Status(ctx)
+	assert.NoError(t, err)
+	assert.False(t, status[0].Applied)
+	tables, err := id.Tables()
+	assert.NoError(t, err)
+	assert.NotContains(t, tables, "broken")
+	_, err = m.Down(ctx, 1)
+	assert.NoError(t, err)
+	_, err = m.Goto(ctx, 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,150 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package migrate
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/log"
+)
+
+// Load load the SQL migrations of the directory of the file system, like
+// an embed.FS. The files are named `<version>_<name>.up.sql` and
+// `<version>_<name>.down.sql`. Files named
+// `<version>_<name>.up.<driver>.sql` are used for the driver only, like
+// `0002_users.up.postgres.sql`.
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	migrations := make(map[int64]*Migration)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".sql") {
+			continue
+		}
+		version, name, up, dialect, err := parseFileName(f.Name())
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := migrations[version]
+		switch {
+		case !ok:
+			m = &Migration{Version: version, Name: name,
+				UpSQL: make(map[string]string), DownSQL: make(map[string]string)}
+			migrations[version] = m
+		case m.Name != name:
+			return nil, errorrepo.NewError("DB000070", f.Name(), "version used by "+m.Name)
+		}
+		if up {
+			m.UpSQL[dialect] = string(data)
+		} else {
+			m.DownSQL[dialect] = string(data)
+		}
+		log.Log.Debugf("Load migration file %s", f.Name())
+	}
+	list := make([]*Migration, 0, len(migrations))
+	for _, m := range migrations {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// LoadDir load the SQL migrations of the directory
+func LoadDir(dir string) ([]*Migration, error) {
+	return Load(os.DirFS(dir), ".")
+}
+
+// parseFileName version, name, direction and driver of the migration file
+// name
+func parseFileName(fileName string) (int64, string, bool, string, error) {
+	parts := strings.Split(strings.TrimSuffix(fileName, ".sql"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, "", false, "", errorrepo.NewError("DB000070", fileName, "name is not <version>_<name>.up|down[.<driver>].sql")
+	}
+	versionPart, name, _ := strings.Cut(parts[0], "_")
+	version, err := strconv.ParseInt(versionPart, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", false, "", errorrepo.NewError("DB000070", fileName, "no valid version")
+	}
+	var up bool
+	switch strings.ToLower(parts[1]) {
+	case "up":
+		up = true
+	case "down":
+	default:
+		return 0, "", false, "", errorrepo.NewError("DB000070", fileName, "direction is not up or down")
+	}
+	dialect := ""
+	if len(parts) == 3 {
+		dialect = strings.ToLower(parts[2])
+	}
+	return version, name, up, dialect, nil
+}
+
+// splitStatements split the SQL into the statements separated by
+// semicolons. Semicolons in quotes, comments and PostgreSQL dollar quotes
+// do not separate statements.
+func splitStatements(sql string) []string {
+	statements := make([]string, 0)
+	var current strings.Builder
+	add := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			statements = append(statements, s)
+		}
+		current.Reset()
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		end := -1
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end = strings.IndexByte(sql[i+1:], c)
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end = strings.IndexByte(sql[i+1:], '\n')
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if end = strings.Index(sql[i+2:], "*/"); end >= 0 {
+				end += 2
+			}
+		case c == '$' && strings.HasPrefix(sql[i:], "$$"):
+			if end = strings.Index(sql[i+2:], "$$"); end >= 0 {
+				end += 2
+			}
+		case c == ';':
+			add()
+			continue
+		default:
+			current.WriteByte(c)
+			continue
+		}
+		if end < 0 {
+			current.WriteString(sql[i:])
+			break
+		}
+		next := i + 1 + end
+		current.WriteString(sql[i : next+1])
+		i = next
+	}
+	add()
+	return statements
+}
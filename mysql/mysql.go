@@ -17,11 +17,12 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/tknie/errorrepo"
 	"github.com/tknie/flynn/common"
 	"github.com/tknie/flynn/dbsql"
@@ -45,6 +46,11 @@ type Mysql struct {
 	ctx          context.Context
 }
 
+func init() {
+	common.RegisterDriverDefinition("mysql", &common.Driver{Type: common.MysqlType,
+		Name: "Mysql", Factory: NewInstance})
+}
+
 // NewInstance create new mysql reference instance
 func NewInstance(id common.RegDbID, reference *common.Reference, password string) (common.Database, error) {
 	// o := reference.OptionString()
@@ -250,6 +256,11 @@ func (mysql *Mysql) Maps() ([]string, error) {
 
 // Ping create short test database connection
 func (mysql *Mysql) Ping() error {
+	return mysql.PingContext(context.Background())
+}
+
+// PingContext create short test database connection using context
+func (mysql *Mysql) PingContext(ctx context.Context) error {
 	dbOpen, err := mysql.Open()
 	if err != nil {
 		return err
@@ -260,7 +271,7 @@ func (mysql *Mysql) Ping() error {
 
 	mysql.dbTableNames = make([]string, 0)
 
-	rows, err := db.Query("SHOW TABLES")
+	rows, err := db.QueryContext(ctx, "SHOW TABLES")
 	if err != nil {
 		return err
 	}
@@ -278,42 +289,47 @@ func (mysql *Mysql) Ping() error {
 
 // Delete Delete database records
 func (mysql *Mysql) Delete(name string, remove *common.Entries) (int64, error) {
-	return dbsql.Delete(mysql, name, remove)
+	return mysql.DeleteContext(context.Background(), name, remove)
+}
+
+// DeleteContext Delete database records using context
+func (mysql *Mysql) DeleteContext(ctx context.Context, name string, remove *common.Entries) (int64, error) {
+	return dbsql.DeleteContext(ctx, mysql, common.MysqlType, name, remove)
 }
 
 // GetTableColumn get table columne names
 func (mysql *Mysql) GetTableColumn(tableName string) ([]string, error) {
+	return mysql.GetTableColumnContext(context.Background(), tableName)
+}
+
+// GetTableColumnContext get table column names using context
+func (mysql *Mysql) GetTableColumnContext(ctx context.Context, tableName string) ([]string, error) {
 	log.Log.Debugf("Get table column ...")
-	dbOpen, err := mysql.Open()
-	if err != nil {
-		return nil, err
-	}
-	defer mysql.Close()
+	return dbsql.TableColumnNames(ctx, mysql, common.MysqlType, tableName)
+}
 
-	db := dbOpen.(*sql.DB)
-	// rows, err := db.Query(`SELECT table_schema, table_name, column_name, data_type
-	// FROM INFORMATION_SCHEMA.COLUMNS
-	rows, err := db.Query(`SELECT column_name FROM INFORMATION_SCHEMA.COLUMNS WHERE table_name = '` + strings.ToUpper(tableName) + `'`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	// c, err := rows.Columns()
-	tableRows := make([]string, 0)
-	tableRow := ""
-	for rows.Next() {
-		err = rows.Scan(&tableRow)
-		if err != nil {
-			return nil, err
-		}
-		tableRows = append(tableRows, strings.ToLower(tableRow))
-	}
+// DescribeTableContext read the column definitions of the table
+func (mysql *Mysql) DescribeTableContext(ctx context.Context, tableName string) (*common.TableInfo, error) {
+	return dbsql.DescribeTable(ctx, mysql, common.MysqlType, tableName)
+}
+
+// CreateIndexContext create the index on the columns of the table
+func (mysql *Mysql) CreateIndexContext(ctx context.Context, tableName string, index *common.Index) error {
+	return dbsql.CreateIndex(ctx, mysql, common.MysqlType, tableName, index)
+}
 
-	return tableRows, nil
+// DropIndexContext drop the index of the table
+func (mysql *Mysql) DropIndexContext(ctx context.Context, tableName, indexName string) error {
+	return dbsql.DropIndex(ctx, mysql, common.MysqlType, tableName, indexName)
 }
 
 // Query query database records with search or SELECT
 func (mysql *Mysql) Query(search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	return mysql.QueryContext(context.Background(), search, f)
+}
+
+// QueryContext query database records with search or SELECT using context
+func (mysql *Mysql) QueryContext(ctx context.Context, search *common.Query, f common.ResultFunction) (*common.Result, error) {
 	search.Driver = common.MysqlType
 	dbOpen, err := mysql.Open()
 	if err != nil {
@@ -322,12 +338,12 @@ func (mysql *Mysql) Query(search *common.Query, f common.ResultFunction) (*commo
 	defer mysql.Close()
 
 	db := dbOpen.(*sql.DB)
-	selectCmd, err := search.Select()
+	selectCmd, args, err := search.SelectParameters()
 	if err != nil {
 		return nil, err
 	}
 	log.Log.Debugf("Query: %s", selectCmd)
-	rows, err := db.Query(selectCmd)
+	rows, err := db.QueryContext(ctx, selectCmd, args...)
 	if err != nil {
 		log.Log.Debugf("%s: error query data", mysql.ID().String(), err)
 		return nil, err
@@ -340,41 +356,113 @@ func (mysql *Mysql) Query(search *common.Query, f common.ResultFunction) (*commo
 
 // CreateTable create a new table
 func (mysql *Mysql) CreateTable(name string, columns any) error {
-	return dbsql.CreateTable(mysql, name, columns)
+	return mysql.CreateTableContext(context.Background(), name, columns)
+}
+
+// CreateTableContext create a new table using context
+func (mysql *Mysql) CreateTableContext(ctx context.Context, name string, columns any) error {
+	return dbsql.CreateTableContext(ctx, mysql, name, columns)
 }
 
-// AdaptTable create a new table
+// AdaptTable adapt the table to the new struct, only missing columns are
+// added
 func (mysql *Mysql) AdaptTable(name string, newStruct any) error {
-	return dbsql.AdaptTable(mysql, name, newStruct)
+	return dbsql.AdaptTable(context.Background(), mysql, common.MysqlType, name, newStruct)
+}
+
+// PlanAdaptTableContext plan the changes adapting the table to the new
+// struct
+func (mysql *Mysql) PlanAdaptTableContext(ctx context.Context, name string, newStruct any) (*common.AdaptPlan, error) {
+	return dbsql.PlanAdaptTable(ctx, mysql, common.MysqlType, name, newStruct)
 }
 
 // DeleteTable delete a table
 func (mysql *Mysql) DeleteTable(name string) error {
-	return dbsql.DeleteTable(mysql, name)
+	return mysql.DeleteTableContext(context.Background(), name)
+}
+
+// DeleteTableContext delete a table using context
+func (mysql *Mysql) DeleteTableContext(ctx context.Context, name string) error {
+	return dbsql.DeleteTableContext(ctx, mysql, name)
 }
 
 // Insert insert record into table
 func (mysql *Mysql) Insert(name string, insert *common.Entries) ([][]any, error) {
-	return dbsql.Insert(mysql, name, insert)
+	return mysql.InsertContext(context.Background(), name, insert)
+}
+
+// InsertContext insert record into table using context
+func (mysql *Mysql) InsertContext(ctx context.Context, name string, insert *common.Entries) ([][]any, error) {
+	return dbsql.BulkInsertContext(ctx, mysql, common.MysqlType, name, insert)
+}
+
+// Upsert insert record into table or update the record with the same key
+func (mysql *Mysql) Upsert(name string, upsert *common.Entries) ([][]any, error) {
+	return mysql.UpsertContext(context.Background(), name, upsert)
+}
+
+// UpsertContext insert or update record in table using context
+func (mysql *Mysql) UpsertContext(ctx context.Context, name string, upsert *common.Entries) ([][]any, error) {
+	return dbsql.UpsertContext(ctx, mysql, common.MysqlType, name, upsert)
 }
 
 // Update update record in table
 func (mysql *Mysql) Update(name string, insert *common.Entries) ([][]any, int64, error) {
-	return dbsql.Update(mysql, name, insert)
+	return mysql.UpdateContext(context.Background(), name, insert)
+}
+
+// UpdateContext update record in table using context
+func (mysql *Mysql) UpdateContext(ctx context.Context, name string, insert *common.Entries) ([][]any, int64, error) {
+	return dbsql.UpdateContext(ctx, mysql, common.MysqlType, name, insert)
+}
+
+// BatchUpdate update records of all value rows using one prepared statement
+func (mysql *Mysql) BatchUpdate(name string, update *common.Entries) ([]int64, error) {
+	return mysql.BatchUpdateContext(context.Background(), name, update)
+}
+
+// BatchUpdateContext update records of all value rows using context
+func (mysql *Mysql) BatchUpdateContext(ctx context.Context, name string, update *common.Entries) ([]int64, error) {
+	return dbsql.BatchUpdateContext(ctx, mysql, common.MysqlType, name, update)
+}
+
+// BatchDelete delete records of all value rows using one prepared statement
+func (mysql *Mysql) BatchDelete(name string, remove *common.Entries) ([]int64, error) {
+	return mysql.BatchDeleteContext(context.Background(), name, remove)
+}
+
+// BatchDeleteContext delete records of all value rows using context
+func (mysql *Mysql) BatchDeleteContext(ctx context.Context, name string, remove *common.Entries) ([]int64, error) {
+	return dbsql.BatchDeleteContext(ctx, mysql, common.MysqlType, name, remove)
 }
 
 // Batch batch SQL query in table
 func (mysql *Mysql) Batch(batch string) error {
-	return dbsql.Batch(mysql, batch)
+	return mysql.BatchContext(context.Background(), batch)
+}
+
+// BatchContext batch SQL query in table using context
+func (mysql *Mysql) BatchContext(ctx context.Context, batch string) error {
+	return dbsql.BatchContext(ctx, mysql, batch)
 }
 
 // BatchSelect batch SQL query in table with values returned
 func (mysql *Mysql) BatchSelect(batch string) ([][]interface{}, error) {
-	return dbsql.BatchSelect(mysql, batch)
+	return mysql.BatchSelectContext(context.Background(), batch)
+}
+
+// BatchSelectContext batch SQL query in table with values returned using context
+func (mysql *Mysql) BatchSelectContext(ctx context.Context, batch string) ([][]interface{}, error) {
+	return dbsql.BatchSelectContext(ctx, mysql, batch)
 }
 
 // BatchSelectFct batch SQL query in table with fct called
 func (mysql *Mysql) BatchSelectFct(search *common.Query, fct common.ResultFunction) error {
+	return mysql.BatchSelectFctContext(context.Background(), search, fct)
+}
+
+// BatchSelectFctContext batch SQL query in table with fct called using context
+func (mysql *Mysql) BatchSelectFctContext(ctx context.Context, search *common.Query, fct common.ResultFunction) error {
 	dbOpen, err := mysql.Open()
 	if err != nil {
 		return err
@@ -382,9 +470,12 @@ func (mysql *Mysql) BatchSelectFct(search *common.Query, fct common.ResultFuncti
 	defer mysql.Close()
 
 	db := dbOpen.(*sql.DB)
-	selectCmd := search.Search
+	selectCmd, args, err := common.BindParameters(common.MysqlType, search.Search, search.Parameters)
+	if err != nil {
+		return err
+	}
 	log.Log.Debugf("Query: %s", selectCmd)
-	rows, err := db.Query(selectCmd, search.Parameters...)
+	rows, err := db.QueryContext(ctx, selectCmd, args...)
 	if err != nil {
 		return err
 	}
@@ -419,6 +510,21 @@ func (mysql *Mysql) StartTransaction() (*sql.Tx, context.Context, error) {
 	return mysql.tx, mysql.ctx, nil
 }
 
+// BeginTx begin a transaction handle using its own database connection
+func (mysql *Mysql) BeginTx(ctx context.Context, opts *common.TxOptions) (common.Transaction, error) {
+	return dbsql.BeginTx(ctx, mysql, common.MysqlType, opts)
+}
+
+// Retryable check if the transaction failed because of a deadlock or
+// serialization failure and can be retried
+func (mysql *Mysql) Retryable(err error) bool {
+	var mysqlErr *mysqlDriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213 || string(mysqlErr.SQLState[:]) == "40001"
+	}
+	return false
+}
+
 // Commit commit the transaction
 func (mysql *Mysql) Commit() error {
 	mysql.Transaction = false
@@ -432,7 +538,13 @@ func (mysql *Mysql) Rollback() error {
 	return mysql.EndTransaction(false)
 }
 
+// Stream streaming data from a field
 func (mysql *Mysql) Stream(search *common.Query, sf common.StreamFunction) error {
+	return mysql.StreamContext(context.Background(), search, sf)
+}
+
+// StreamContext streaming data from a field using context
+func (mysql *Mysql) StreamContext(ctx context.Context, search *common.Query, sf common.StreamFunction) error {
 	dbOpen, err := mysql.Open()
 	if err != nil {
 		return err
@@ -449,7 +561,11 @@ func (mysql *Mysql) Stream(search *common.Query, sf common.StreamFunction) error
 		search.Fields[0], offset, blocksize, search.Fields[0], search.TableName, search.Search)
 	for offset < dataMaxLen {
 		log.Log.Debugf("Query: %s", selectCmd)
-		rows, err := db.Query(selectCmd)
+		streamCmd, args, err := common.BindParameters(common.MysqlType, selectCmd, search.Parameters)
+		if err != nil {
+			return err
+		}
+		rows, err := db.QueryContext(ctx, streamCmd, args...)
 		if err != nil {
 			log.Log.Errorf("Stream query error: %v", err)
 			return err
@@ -18,12 +18,13 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"strings"
 	"text/template"
 
-	_ "github.com/godror/godror"
+	"github.com/godror/godror"
 	"github.com/tknie/errorrepo"
 	"github.com/tknie/flynn/common"
 	"github.com/tknie/flynn/dbsql"
@@ -61,6 +62,11 @@ const templateConnectString = `user="<user>" password="<password>"` +
 	`(HOST = {{ .Host}})(PORT = {{ .Port}})))` +
 	`(CONNECT_DATA=(SERVICE_NAME = {{ .ServiceName}}))"`
 
+func init() {
+	common.RegisterDriverDefinition("oracle", &common.Driver{Type: common.OracleType,
+		Name: "Oracle", Parse: common.ParseOracle, Factory: NewInstance})
+}
+
 // NewInstance create new oracle reference instance
 func NewInstance(id common.RegDbID, reference *common.Reference, password string) (common.Database, error) {
 	if reference.Driver != common.OracleType {
@@ -251,6 +257,11 @@ func (oracle *Oracle) Maps() ([]string, error) {
 
 // Ping create short test database connection
 func (oracle *Oracle) Ping() error {
+	return oracle.PingContext(context.Background())
+}
+
+// PingContext create short test database connection using context
+func (oracle *Oracle) PingContext(ctx context.Context) error {
 	dbOpen, err := oracle.Open()
 	if err != nil {
 		return err
@@ -262,7 +273,7 @@ func (oracle *Oracle) Ping() error {
 	oracle.dbTableNames = make([]string, 0)
 
 	log.Log.Debugf("Query all tables with: SELECT owner, table_name FROM all_tables")
-	rows, err := db.Query("SELECT owner, table_name FROM all_tables")
+	rows, err := db.QueryContext(ctx, "SELECT owner, table_name FROM all_tables")
 	if err != nil {
 		return err
 	}
@@ -281,16 +292,46 @@ func (oracle *Oracle) Ping() error {
 
 // Delete Delete database records
 func (oracle *Oracle) Delete(name string, remove *common.Entries) (int64, error) {
-	return dbsql.Delete(oracle, name, remove)
+	return oracle.DeleteContext(context.Background(), name, remove)
+}
+
+// DeleteContext Delete database records using context
+func (oracle *Oracle) DeleteContext(ctx context.Context, name string, remove *common.Entries) (int64, error) {
+	return dbsql.DeleteContext(ctx, oracle, common.OracleType, name, remove)
 }
 
 // GetTableColumn get table columne names
 func (oracle *Oracle) GetTableColumn(tableName string) ([]string, error) {
-	return nil, errorrepo.NewError("DB065535")
+	return oracle.GetTableColumnContext(context.Background(), tableName)
+}
+
+// GetTableColumnContext get table column names using context
+func (oracle *Oracle) GetTableColumnContext(ctx context.Context, tableName string) ([]string, error) {
+	return dbsql.TableColumnNames(ctx, oracle, common.OracleType, tableName)
+}
+
+// DescribeTableContext read the column definitions of the table
+func (oracle *Oracle) DescribeTableContext(ctx context.Context, tableName string) (*common.TableInfo, error) {
+	return dbsql.DescribeTable(ctx, oracle, common.OracleType, tableName)
+}
+
+// CreateIndexContext create the index on the columns of the table
+func (oracle *Oracle) CreateIndexContext(ctx context.Context, tableName string, index *common.Index) error {
+	return dbsql.CreateIndex(ctx, oracle, common.OracleType, tableName, index)
+}
+
+// DropIndexContext drop the index of the table
+func (oracle *Oracle) DropIndexContext(ctx context.Context, tableName, indexName string) error {
+	return dbsql.DropIndex(ctx, oracle, common.OracleType, tableName, indexName)
 }
 
 // Query query database records with search or SELECT
 func (oracle *Oracle) Query(search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	return oracle.QueryContext(context.Background(), search, f)
+}
+
+// QueryContext query database records with search or SELECT using context
+func (oracle *Oracle) QueryContext(ctx context.Context, search *common.Query, f common.ResultFunction) (*common.Result, error) {
 	search.Driver = common.OracleType
 	dbOpen, err := oracle.Open()
 	if err != nil {
@@ -299,12 +340,12 @@ func (oracle *Oracle) Query(search *common.Query, f common.ResultFunction) (*com
 	defer oracle.Close()
 
 	db := dbOpen.(*sql.DB)
-	selectCmd, err := search.Select()
+	selectCmd, args, err := search.SelectParameters()
 	if err != nil {
 		return nil, err
 	}
 	log.Log.Debugf("Query: %s", selectCmd)
-	rows, err := db.Query(selectCmd)
+	rows, err := db.QueryContext(ctx, selectCmd, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -316,41 +357,113 @@ func (oracle *Oracle) Query(search *common.Query, f common.ResultFunction) (*com
 
 // CreateTable create a new table
 func (oracle *Oracle) CreateTable(name string, columns any) error {
-	return dbsql.CreateTable(oracle, name, columns)
+	return oracle.CreateTableContext(context.Background(), name, columns)
+}
+
+// CreateTableContext create a new table using context
+func (oracle *Oracle) CreateTableContext(ctx context.Context, name string, columns any) error {
+	return dbsql.CreateTableContext(ctx, oracle, name, columns)
 }
 
-// AdaptTable adapt a new table
-func (oracle *Oracle) AdaptTable(string, any) error {
-	return errorrepo.NewError("DB065535")
+// AdaptTable adapt the table to the new struct, only missing columns are
+// added
+func (oracle *Oracle) AdaptTable(name string, newStruct any) error {
+	return dbsql.AdaptTable(context.Background(), oracle, common.OracleType, name, newStruct)
+}
+
+// PlanAdaptTableContext plan the changes adapting the table to the new
+// struct
+func (oracle *Oracle) PlanAdaptTableContext(ctx context.Context, name string, newStruct any) (*common.AdaptPlan, error) {
+	return dbsql.PlanAdaptTable(ctx, oracle, common.OracleType, name, newStruct)
 }
 
 // DeleteTable delete a table
 func (oracle *Oracle) DeleteTable(name string) error {
-	return dbsql.DeleteTable(oracle, name)
+	return oracle.DeleteTableContext(context.Background(), name)
+}
+
+// DeleteTableContext delete a table using context
+func (oracle *Oracle) DeleteTableContext(ctx context.Context, name string) error {
+	return dbsql.DeleteTableContext(ctx, oracle, name)
 }
 
 // Insert insert record into table
 func (oracle *Oracle) Insert(name string, insert *common.Entries) ([][]any, error) {
-	return dbsql.Insert(oracle, name, insert)
+	return oracle.InsertContext(context.Background(), name, insert)
+}
+
+// InsertContext insert record into table using context
+func (oracle *Oracle) InsertContext(ctx context.Context, name string, insert *common.Entries) ([][]any, error) {
+	return dbsql.BulkInsertContext(ctx, oracle, common.OracleType, name, insert)
+}
+
+// Upsert insert record into table or update the record with the same key
+func (oracle *Oracle) Upsert(name string, upsert *common.Entries) ([][]any, error) {
+	return oracle.UpsertContext(context.Background(), name, upsert)
+}
+
+// UpsertContext insert or update record in table using context
+func (oracle *Oracle) UpsertContext(ctx context.Context, name string, upsert *common.Entries) ([][]any, error) {
+	return dbsql.UpsertContext(ctx, oracle, common.OracleType, name, upsert)
 }
 
 // Update update record in table
 func (oracle *Oracle) Update(name string, insert *common.Entries) ([][]any, int64, error) {
-	return dbsql.Update(oracle, name, insert)
+	return oracle.UpdateContext(context.Background(), name, insert)
+}
+
+// UpdateContext update record in table using context
+func (oracle *Oracle) UpdateContext(ctx context.Context, name string, insert *common.Entries) ([][]any, int64, error) {
+	return dbsql.UpdateContext(ctx, oracle, common.OracleType, name, insert)
+}
+
+// BatchUpdate update records of all value rows using one prepared statement
+func (oracle *Oracle) BatchUpdate(name string, update *common.Entries) ([]int64, error) {
+	return oracle.BatchUpdateContext(context.Background(), name, update)
+}
+
+// BatchUpdateContext update records of all value rows using context
+func (oracle *Oracle) BatchUpdateContext(ctx context.Context, name string, update *common.Entries) ([]int64, error) {
+	return dbsql.BatchUpdateContext(ctx, oracle, common.OracleType, name, update)
+}
+
+// BatchDelete delete records of all value rows using one prepared statement
+func (oracle *Oracle) BatchDelete(name string, remove *common.Entries) ([]int64, error) {
+	return oracle.BatchDeleteContext(context.Background(), name, remove)
+}
+
+// BatchDeleteContext delete records of all value rows using context
+func (oracle *Oracle) BatchDeleteContext(ctx context.Context, name string, remove *common.Entries) ([]int64, error) {
+	return dbsql.BatchDeleteContext(ctx, oracle, common.OracleType, name, remove)
 }
 
 // Batch batch SQL query in table
 func (oracle *Oracle) Batch(batch string) error {
-	return dbsql.Batch(oracle, batch)
+	return oracle.BatchContext(context.Background(), batch)
+}
+
+// BatchContext batch SQL query in table using context
+func (oracle *Oracle) BatchContext(ctx context.Context, batch string) error {
+	return dbsql.BatchContext(ctx, oracle, batch)
 }
 
 // BatchSelect batch SQL query in table with values returned
 func (oracle *Oracle) BatchSelect(batch string) ([][]interface{}, error) {
-	return dbsql.BatchSelect(oracle, batch)
+	return oracle.BatchSelectContext(context.Background(), batch)
+}
+
+// BatchSelectContext batch SQL query in table with values returned using context
+func (oracle *Oracle) BatchSelectContext(ctx context.Context, batch string) ([][]interface{}, error) {
+	return dbsql.BatchSelectContext(ctx, oracle, batch)
 }
 
 // BatchSelectFct batch SQL query in table with fct called
 func (oracle *Oracle) BatchSelectFct(search *common.Query, fct common.ResultFunction) error {
+	return oracle.BatchSelectFctContext(context.Background(), search, fct)
+}
+
+// BatchSelectFctContext batch SQL query in table with fct called using context
+func (oracle *Oracle) BatchSelectFctContext(ctx context.Context, search *common.Query, fct common.ResultFunction) error {
 	dbOpen, err := oracle.Open()
 	if err != nil {
 		return err
@@ -358,9 +471,12 @@ func (oracle *Oracle) BatchSelectFct(search *common.Query, fct common.ResultFunc
 	defer oracle.Close()
 
 	db := dbOpen.(*sql.DB)
-	selectCmd := search.Search
+	selectCmd, args, err := common.BindParameters(common.OracleType, search.Search, search.Parameters)
+	if err != nil {
+		return err
+	}
 	log.Log.Debugf("Query: %s", selectCmd)
-	rows, err := db.Query(selectCmd, search.Parameters...)
+	rows, err := db.QueryContext(ctx, selectCmd, args...)
 	if err != nil {
 		return err
 	}
@@ -389,6 +505,21 @@ func (oracle *Oracle) StartTransaction() (*sql.Tx, context.Context, error) {
 	return oracle.tx, oracle.ctx, nil
 }
 
+// BeginTx begin a transaction handle using its own database connection
+func (oracle *Oracle) BeginTx(ctx context.Context, opts *common.TxOptions) (common.Transaction, error) {
+	return dbsql.BeginTx(ctx, oracle, common.OracleType, opts)
+}
+
+// Retryable check if the transaction failed because of a deadlock
+// (ORA-00060) or serialization failure (ORA-08177) and can be retried
+func (oracle *Oracle) Retryable(err error) bool {
+	var oraErr *godror.OraErr
+	if errors.As(err, &oraErr) {
+		return oraErr.Code() == 60 || oraErr.Code() == 8177
+	}
+	return false
+}
+
 // Commit commit the transaction
 func (oracle *Oracle) Commit() error {
 	oracle.Transaction = false
@@ -401,7 +532,13 @@ func (oracle *Oracle) Rollback() error {
 	return oracle.EndTransaction(false)
 }
 
+// Stream streaming data from a field
 func (oracle *Oracle) Stream(search *common.Query, sf common.StreamFunction) error {
+	return oracle.StreamContext(context.Background(), search, sf)
+}
+
+// StreamContext streaming data from a field using context
+func (oracle *Oracle) StreamContext(ctx context.Context, search *common.Query, sf common.StreamFunction) error {
 	dbOpen, err := oracle.Open()
 	if err != nil {
 		return err
@@ -418,7 +555,11 @@ func (oracle *Oracle) Stream(search *common.Query, sf common.StreamFunction) err
 		search.Fields[0], offset, blocksize, search.Fields[0], search.TableName, search.Search)
 	for offset < dataMaxLen {
 		log.Log.Debugf("Query: %s", selectCmd)
-		rows, err := db.Query(selectCmd)
+		streamCmd, args, err := common.BindParameters(common.OracleType, selectCmd, search.Parameters)
+		if err != nil {
+			return err
+		}
+		rows, err := db.QueryContext(ctx, streamCmd, args...)
 		if err != nil {
 			log.Log.Errorf("Stream query error: %v", err)
 			return err
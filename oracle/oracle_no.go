@@ -15,6 +15,7 @@
 package oracle
 
 import (
+	"context"
 	"math"
 
 	"github.com/tknie/errorrepo"
@@ -150,3 +151,48 @@ func (ada *oracle) Rollback() error {
 func (ada *oracle) Stream(search *common.Query, sf common.StreamFunction) error {
 	return errorrepo.NewError("DB065535")
 }
+
+// PingContext create short test database connection
+func (ada *oracle) PingContext(context.Context) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// InsertContext insert record into table
+func (ada *oracle) InsertContext(context.Context, string, *common.Entries) ([][]any, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// UpdateContext update record in table
+func (ada *oracle) UpdateContext(context.Context, string, *common.Entries) ([][]any, int64, error) {
+	return nil, 0, errorrepo.NewError("DB065535")
+}
+
+// DeleteContext Delete database records
+func (ada *oracle) DeleteContext(context.Context, string, *common.Entries) (int64, error) {
+	return 0, errorrepo.NewError("DB065535")
+}
+
+// QueryContext query database records with search or SELECT
+func (ada *oracle) QueryContext(context.Context, *common.Query, common.ResultFunction) (*common.Result, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// BatchContext batch SQL query in table
+func (ada *oracle) BatchContext(context.Context, string) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// BatchSelectContext batch SQL query in table with values returned
+func (ada *oracle) BatchSelectContext(context.Context, string) ([][]interface{}, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// BatchSelectFctContext batch SQL query in table with fct called
+func (ada *oracle) BatchSelectFctContext(context.Context, *common.Query, common.ResultFunction) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// StreamContext streaming data from a field
+func (ada *oracle) StreamContext(context.Context, *common.Query, common.StreamFunction) error {
+	return errorrepo.NewError("DB065535")
+}
@@ -0,0 +1,64 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package flynn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+)
+
+func readPages(t *testing.T, id common.RegDbID, query *common.Query) ([]string, int64) {
+	ids := make([]string, 0)
+	total := int64(0)
+	for pages := 0; pages < 10; pages++ {
+		result, err := id.Query(query, func(search *common.Query, result *common.Result) error {
+			ids = append(ids, result.Data.(*iterRecord).ID)
+			return nil
+		})
+		if !assert.NoError(t, err) {
+			return nil, 0
+		}
+		total = result.Total
+		if result.NextToken == "" {
+			break
+		}
+		query.Page.Token = result.NextToken
+	}
+	return ids, total
+}
+
+func TestQueryPage(t *testing.T) {
+	InitLog(t)
+
+	id := iterTestTable(t)
+	if id == 0 {
+		return
+	}
+	ids, total := readPages(t, id, &common.Query{TableName: "IterRecord", DataStruct: &iterRecord{},
+		Fields: []string{"*"}, Search: "Counter > 3", Order: []string{"ID:ASC"},
+		Page: &common.Page{Size: 3, Total: true}})
+	assert.Equal(t, []string{"IT04", "IT05", "IT06", "IT07", "IT08", "IT09", "IT10"}, ids)
+	assert.Equal(t, int64(7), total)
+
+	ids, total = readPages(t, id, &common.Query{TableName: "IterRecord", DataStruct: &iterRecord{},
+		Fields: []string{"*"}, Sort: []common.SortField{common.Desc("Counter")},
+		Page: &common.Page{Size: 4, Keyset: true}})
+	assert.Equal(t, []string{"IT10", "IT09", "IT08", "IT07", "IT06", "IT05", "IT04", "IT03", "IT02", "IT01"}, ids)
+	assert.Equal(t, int64(0), total)
+
+	_, err := id.Query(&common.Query{TableName: "IterRecord", DataStruct: &iterRecord{},
+		Fields: []string{"*"}, Page: &common.Page{Size: 4, Keyset: true}},
+		func(search *common.Query, result *common.Result) error { return nil })
+	assert.ErrorContains(t, err, "DB000078")
+}
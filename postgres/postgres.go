@@ -15,13 +15,12 @@
 package postgres
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"runtime/debug"
-	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,6 +28,7 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/tknie/errorrepo"
@@ -100,6 +100,11 @@ func (pg *PostGres) reset() {
 	pg.lock = sync.Mutex{}
 }
 
+func init() {
+	common.RegisterDriverDefinition("postgres", &common.Driver{Type: common.PostgresType,
+		Name: "Postgres", Factory: NewInstance})
+}
+
 // NewInstance create new postgres reference instance using reference structure
 func NewInstance(id common.RegDbID, reference *common.Reference, password string) (common.Database, error) {
 	pg := postgresPool.Get().(*PostGres)
@@ -112,11 +117,13 @@ func NewInstance(id common.RegDbID, reference *common.Reference, password string
 
 func (pg *PostGres) Clone() common.Database {
 	newPg := postgresPool.Get().(*PostGres)
-	*newPg = *pg
+	newPg.CommonDatabase = pg.CommonDatabase
+	newPg.dbTableNames = pg.dbTableNames
+	newPg.password = pg.password
+	newPg.cancel = nil
 	newPg.ctx = nil
 	newPg.openDB = nil
 	newPg.tx = nil
-	newPg.lock = sync.Mutex{}
 	return newPg
 }
 
@@ -369,6 +376,15 @@ func (pg *PostGres) EndTransaction(commit bool) (err error) {
 	return err
 }
 
+// rollbackOwn roll back the transaction started by the operation itself.
+// The transaction of a transaction handle is ended by its owner.
+func (pg *PostGres) rollbackOwn(transaction bool) error {
+	if transaction {
+		return nil
+	}
+	return pg.EndTransaction(false)
+}
+
 // Close close the database connection
 func (pg *PostGres) Close() {
 	log.Log.Debugf("%s Close of connection", pg.ID().String())
@@ -417,6 +433,11 @@ func (pg *PostGres) FreeHandler() {
 
 // Ping create short test database connection
 func (pg *PostGres) Ping() error {
+	return pg.PingContext(context.Background())
+}
+
+// PingContext create short test database connection using context
+func (pg *PostGres) PingContext(ctx context.Context) error {
 	log.Log.Debugf("Ping database ... by receiving table names")
 	pg.dbTableNames = nil
 	dbOpen, err := pg.Open()
@@ -428,7 +449,7 @@ func (pg *PostGres) Ping() error {
 
 	pg.dbTableNames = make([]string, 0)
 
-	rows, err := db.Query(context.Background(), "SELECT table_name FROM information_schema.tables WHERE table_schema='public' and (table_type = 'BASE TABLE' or table_type = 'VIEW')")
+	rows, err := db.Query(ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema='public' and (table_type = 'BASE TABLE' or table_type = 'VIEW')")
 	if err != nil {
 		log.Log.Debugf("%s Error pinging database ...%v", pg.ID().String(), err)
 		return err
@@ -449,91 +470,160 @@ func (pg *PostGres) Ping() error {
 
 // Delete Delete database records
 func (pg *PostGres) Delete(name string, remove *common.Entries) (rowsAffected int64, err error) {
+	return pg.DeleteContext(context.Background(), name, remove)
+}
+
+// DeleteContext Delete database records using context
+func (pg *PostGres) DeleteContext(ctx context.Context, name string, remove *common.Entries) (rowsAffected int64, err error) {
+	counts, err := pg.BatchDeleteContext(ctx, name, remove)
+	if err != nil {
+		return -1, err
+	}
+	for _, c := range counts {
+		rowsAffected += c
+	}
+	log.Log.Debugf("Delete done")
+	return
+}
+
+// BatchDelete delete records of all value rows in one pipelined batch
+func (pg *PostGres) BatchDelete(name string, remove *common.Entries) ([]int64, error) {
+	return pg.BatchDeleteContext(context.Background(), name, remove)
+}
+
+// BatchDeleteContext delete records of all value rows in one pipelined
+// batch using context and return the rows affected by each value row. A
+// delete using Criteria returns the rows affected by the criteria.
+func (pg *PostGres) BatchDeleteContext(ctx context.Context, name string, remove *common.Entries) ([]int64, error) {
+	batch := &pgx.Batch{}
+	if remove.Criteria != "" {
+		deleteCmd, args, err := dbsql.GenerateCriteriaDelete(common.PostgresType, name, remove)
+		if err != nil {
+			return nil, err
+		}
+		batch.Queue(deleteCmd, args...)
+	} else {
+		deleteCmd, err := dbsql.GenerateBatchDelete(common.PostgresType, name, remove.Fields)
+		if err != nil {
+			return nil, err
+		}
+		log.Log.Debugf("Delete cmd: %s", deleteCmd)
+		for _, v := range remove.Values {
+			batch.Queue(deleteCmd, v...)
+		}
+	}
+	return pg.sendBatch(ctx, batch, nil)
+}
+
+// BatchUpdate update records of all value rows in one pipelined batch
+func (pg *PostGres) BatchUpdate(name string, updateInfo *common.Entries) ([]int64, error) {
+	return pg.BatchUpdateContext(context.Background(), name, updateInfo)
+}
+
+// BatchUpdateContext update records of all value rows in one pipelined
+// batch using context and return the rows affected by each value row
+func (pg *PostGres) BatchUpdateContext(ctx context.Context, name string, updateInfo *common.Entries) ([]int64, error) {
+	fields, values, err := updateInfo.InsertValues()
+	if err != nil {
+		return nil, err
+	}
+	updateCmd, arguments, err := dbsql.GenerateBatchUpdate(common.PostgresType, name, fields, updateInfo)
+	if err != nil {
+		return nil, err
+	}
+	log.Log.Debugf("Update cmd: %s", updateCmd)
+	batch := &pgx.Batch{}
+	for _, v := range values {
+		batch.Queue(updateCmd, dbsql.BatchUpdateArguments(v, arguments)...)
+	}
+	return pg.sendBatch(ctx, batch, dbsql.StaleVersion(name, fields, updateInfo))
+}
+
+// sendBatch send all queued statements in one pipeline and return the rows
+// affected by each statement. The stale error is returned if a statement
+// affects no record, nil disables the check.
+func (pg *PostGres) sendBatch(ctx context.Context, batch *pgx.Batch, stale error) (rowsAffected []int64, err error) {
 	transaction := pg.IsTransaction()
-	var ctx context.Context
 	var tx pgx.Tx
 	if !transaction {
-		tx, ctx, err = pg.StartTransaction()
+		tx, _, err = pg.StartTransaction()
 		if err != nil {
-			return -1, err
+			return nil, err
 		}
 		defer pg.Close()
 	} else {
 		log.Log.Debugf("Tx used pg=%p/tx=%p", pg, pg.tx)
 		tx = pg.tx
-		ctx = pg.ctx
 	}
-
-	if remove.Criteria != "" {
-		deleteCmd := "DELETE FROM " + name + " WHERE " + remove.Criteria
-		log.Log.Debugf("Delete cmd: %s", deleteCmd)
-		res, err := tx.Exec(ctx, deleteCmd)
+	if tx == nil {
+		return nil, errorrepo.NewError("DB000031")
+	}
+	log.Log.Debugf("Send batch with %d statements", batch.Len())
+	results := tx.SendBatch(ctx, batch)
+	rowsAffected = make([]int64, 0, batch.Len())
+	for i := 0; i < batch.Len(); i++ {
+		tag, err := results.Exec()
 		if err != nil {
-			log.Log.Debugf("Delete error: %v", err)
-			pg.EndTransaction(false)
-			return -1, err
+			log.Log.Debugf("Batch error: %v", err)
+			results.Close()
+			pg.rollbackOwn(transaction)
+			return nil, err
 		}
-		rowsAffected += res.RowsAffected()
-	} else {
-		for i := 0; i < len(remove.Values); i++ {
-			deleteCmd, av := dbsql.GenerateDelete(pg.IndexNeeded(), name, 0, remove)
-			log.Log.Debugf("Delete cmd: %s -> %#v", deleteCmd, av)
-			res, err := tx.Exec(ctx, deleteCmd, av...)
-			// tx.ExecContext(ctx, deleteCmd, av...)
-			if err != nil {
-				log.Log.Debugf("Delete error: %v", err)
-				pg.EndTransaction(false)
-				return -1, err
-			}
-			rowsAffected += res.RowsAffected()
+		if tag.RowsAffected() == 0 && stale != nil {
+			log.Log.Debugf("Stale version in batch statement %d", i)
+			results.Close()
+			pg.rollbackOwn(transaction)
+			return nil, stale
 		}
+		rowsAffected = append(rowsAffected, tag.RowsAffected())
+	}
+	err = results.Close()
+	if err != nil {
+		pg.rollbackOwn(transaction)
+		return nil, err
 	}
-
 	if !transaction {
-		log.Log.Debugf("Delete not in transaction and commit data to database")
 		err = pg.EndTransaction(true)
 		if err != nil {
-			return -1, err
+			return nil, err
 		}
 	}
-
-	log.Log.Debugf("Delete done")
-	return
+	return rowsAffected, nil
 }
 
 // GetTableColumn get table columne names
 func (pg *PostGres) GetTableColumn(tableName string) ([]string, error) {
+	return pg.GetTableColumnContext(context.Background(), tableName)
+}
+
+// GetTableColumnContext get table column names using context
+func (pg *PostGres) GetTableColumnContext(ctx context.Context, tableName string) ([]string, error) {
 	log.Log.Debugf("Get table column ...")
-	dbOpen, err := pg.Open()
-	if err != nil {
-		return nil, err
-	}
-	defer pg.Close()
+	return dbsql.TableColumnNames(ctx, pg, common.PostgresType, tableName)
+}
 
-	db := dbOpen.(*pgxpool.Conn)
-	// rows, err := db.Query(`SELECT table_schema, table_name, column_name, data_type
-	// FROM INFORMATION_SCHEMA.COLUMNS
-	rows, err := db.Query(context.Background(), `SELECT column_name FROM INFORMATION_SCHEMA.COLUMNS WHERE table_name = '`+strings.ToLower(tableName)+`'`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	// c, err := rows.Columns()
-	tableRows := make([]string, 0)
-	tableRow := ""
-	for rows.Next() {
-		err = rows.Scan(&tableRow)
-		if err != nil {
-			return nil, err
-		}
-		tableRows = append(tableRows, tableRow)
-	}
+// DescribeTableContext read the column definitions of the table
+func (pg *PostGres) DescribeTableContext(ctx context.Context, tableName string) (*common.TableInfo, error) {
+	return dbsql.DescribeTable(ctx, pg, common.PostgresType, tableName)
+}
+
+// CreateIndexContext create the index on the columns of the table
+func (pg *PostGres) CreateIndexContext(ctx context.Context, tableName string, index *common.Index) error {
+	return dbsql.CreateIndex(ctx, pg, common.PostgresType, tableName, index)
+}
 
-	return tableRows, nil
+// DropIndexContext drop the index of the table
+func (pg *PostGres) DropIndexContext(ctx context.Context, tableName, indexName string) error {
+	return dbsql.DropIndex(ctx, pg, common.PostgresType, tableName, indexName)
 }
 
 // Query query database records with search or SELECT
 func (pg *PostGres) Query(search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	return pg.QueryContext(context.Background(), search, f)
+}
+
+// QueryContext query database records with search or SELECT using context
+func (pg *PostGres) QueryContext(ctx context.Context, search *common.Query, f common.ResultFunction) (*common.Result, error) {
 	log.LogFunctionStarts(pg.ID().String())
 	defer log.LogFunctionEnds(time.Now(), pg.ID().String())
 	search.Driver = common.PostgresType
@@ -545,15 +635,14 @@ func (pg *PostGres) Query(search *common.Query, f common.ResultFunction) (*commo
 	}
 
 	db := dbOpen.(*pgxpool.Conn)
-	ctx := context.Background()
 	defer pg.Close()
-	selectCmd, err := search.Select()
+	selectCmd, args, err := search.SelectParameters()
 	if err != nil {
 		return nil, err
 	}
 	log.Log.Debugf("Postgres Query: %s (%p)", selectCmd, db)
 	startTime := time.Now()
-	rows, err := db.Query(ctx, selectCmd)
+	rows, err := db.Query(ctx, selectCmd, args...)
 	used := time.Since(startTime)
 	if err != nil {
 		log.Log.Infof("Postgres Query error (%v): %v (%p)", used, err, db)
@@ -652,110 +741,57 @@ func (pg *PostGres) ParseStruct(search *common.Query, rows pgx.Rows, f common.Re
 	return
 }
 
-// CreateTable create a new table
+// CreateTable create a new table including the constraints and indexes
 func (pg *PostGres) CreateTable(name string, col any) error {
+	return pg.CreateTableContext(context.Background(), name, col)
+}
+
+// CreateTableContext create a new table including the constraints and
+// indexes using context
+func (pg *PostGres) CreateTableContext(ctx context.Context, name string, col any) error {
 	log.Log.Debugf("Create SQL table")
+	statements, err := dbsql.CreateTableStatements(common.PostgresType, pg.ByteArrayAvailable(), name, col)
+	if err != nil {
+		log.Log.Errorf("Error parsing structure: %v", err)
+		return err
+	}
 	layer, url := pg.Reference()
 	db, err := sql.Open(layer, url)
 	if err != nil {
 		return err
 	}
 	defer db.Close()
-	createCmd := `CREATE TABLE ` + name + ` (`
-	switch columns := col.(type) {
-	case []*common.Column:
-		createCmd += dbsql.CreateTableByColumns(pg.ByteArrayAvailable(), columns)
-	case map[string]interface{}:
-		createCmd += dbsql.CreateTableByMaps(pg.ByteArrayAvailable(), columns)
-	default:
-		c, err := dbsql.CreateTableByStruct(pg.ByteArrayAvailable(), col)
+	for _, createCmd := range statements {
+		log.Log.Debugf("Create cmd %s", createCmd)
+		_, err = db.ExecContext(ctx, createCmd)
 		if err != nil {
-			log.Log.Errorf("Error parsing structure: %v", err)
+			log.Log.Errorf("Error returned by SQL: %v", err)
 			return err
 		}
-		createCmd += c
 	}
-	createCmd += ")"
-	log.Log.Debugf("Create cmd %s", createCmd)
-	_, err = db.Exec(createCmd)
-	if err != nil {
-		log.Log.Errorf("Error returned by SQL: %v", err)
-		return err
-	}
-	//log.Log.Debugf("Table created, waiting ....")
-	//time.Sleep(60 * time.Second)
 	log.Log.Debugf("Table created")
-	// err = db.Close()
-	// if err != nil {
-	// 	return err
-	// }
-	log.Log.Debugf("Table db handler closed")
 	return nil
 }
 
-// AdaptTable adapt a new table
+// AdaptTable adapt the table to the new struct or columns, only missing
+// columns are added
 func (pg *PostGres) AdaptTable(name string, col any) error {
-	log.Log.Debugf("%s: Adapt SQL table of type %T", pg.ID(), col)
-	layer, url := pg.Reference()
-	db, err := sql.Open(layer, url)
-	if err != nil {
-		return err
-	}
-	defer db.Close()
-
-	if columns, ok := col.([]*common.Column); ok {
-		log.Log.Debugf("%s: Found %d SQL columns", pg.ID(), len(columns))
-		var buffer bytes.Buffer
-
-		buffer.WriteString(`ALTER TABLE ` + name)
-		i := 0
-		for _, c := range columns {
-			if i > 0 {
-				buffer.WriteString(",")
-			}
-			i++
-			buffer.WriteString(` ADD COLUMN `)
-			dbsql.CreateTableByColumn(&buffer, pg.ByteArrayAvailable(), c)
-		}
-		log.Log.Debugf(buffer.String())
-		_, err = db.Query(buffer.String())
-		if err != nil {
-			log.Log.Errorf("Error returned by SQL: %v", err)
-			return err
-		}
-		return nil
-	}
-	columnCurrent, err := pg.ID().GetTableColumn(name)
-	if err != nil {
-		return err
-	}
-	log.Log.Debugf("Got struct and new columns: %v", columnCurrent)
-	columStruct, err := dbsql.SqlDataType(false, col, columnCurrent)
-	if err != nil {
-		return err
-	}
-	var buffer bytes.Buffer
-	buffer.WriteString(`ALTER TABLE ` + name)
-	i := 0
-	for _, f := range strings.Split(columStruct, ",") {
-		if i > 0 {
-			buffer.WriteString(",")
-		}
-		i++
-		buffer.WriteString(` ADD COLUMN ` + f)
-	}
+	return dbsql.AdaptTable(context.Background(), pg, common.PostgresType, name, col)
+}
 
-	_, err = db.Query(buffer.String())
-	if err != nil {
-		log.Log.Errorf("Error returned by SQL: %v", err)
-		return err
-	}
-	log.Log.Debugf("Table adapted")
-	return nil
+// PlanAdaptTableContext plan the changes adapting the table to the new
+// struct or columns
+func (pg *PostGres) PlanAdaptTableContext(ctx context.Context, name string, col any) (*common.AdaptPlan, error) {
+	return dbsql.PlanAdaptTable(ctx, pg, common.PostgresType, name, col)
 }
 
 // DeleteTable delete a table
 func (pg *PostGres) DeleteTable(name string) error {
+	return pg.DeleteTableContext(context.Background(), name)
+}
+
+// DeleteTableContext delete a table using context
+func (pg *PostGres) DeleteTableContext(ctx context.Context, name string) error {
 	layer, url := pg.Reference()
 	db, err := sql.Open(layer, url)
 	if err != nil {
@@ -764,7 +800,7 @@ func (pg *PostGres) DeleteTable(name string) error {
 	defer db.Close()
 
 	log.Log.Debugf("Init DROP TABLE %s", name)
-	_, err = db.Query("DROP TABLE " + name)
+	_, err = db.ExecContext(ctx, "DROP TABLE "+name)
 	if err != nil {
 		log.Log.Debugf("DROP TABLE error: %v", err)
 		return err
@@ -775,6 +811,11 @@ func (pg *PostGres) DeleteTable(name string) error {
 
 // Insert insert record into table
 func (pg *PostGres) Insert(name string, insert *common.Entries) (returning [][]any, err error) {
+	return pg.InsertContext(context.Background(), name, insert)
+}
+
+// InsertContext insert record into table using context
+func (pg *PostGres) InsertContext(ctx context.Context, name string, insert *common.Entries) (returning [][]any, err error) {
 	log.LogFunctionStarts(pg.ID().String())
 	defer log.LogFunctionEnds(time.Now(), pg.ID().String())
 	log.Log.Debugf("%s: Insert in posgres database", pg.ID().String())
@@ -782,14 +823,22 @@ func (pg *PostGres) Insert(name string, insert *common.Entries) (returning [][]a
 		return nil, errorrepo.NewError("DB000029")
 	}
 	defer log.Log.Debugf("%s: Insert ended for posgres database", pg.ID().String())
+	log.Log.Debugf("%s Insert SQL record.. preparing values", pg.ID().String())
+	insertFields, insertValues, err := insert.InsertValues()
+	if err != nil {
+		return nil, err
+	}
+	bulk, err := insert.UseBulk(name, len(insertValues))
+	if err != nil {
+		return nil, err
+	}
 
-	var ctx context.Context
 	var tx pgx.Tx
 
 	transaction := pg.IsTransaction()
 	log.Log.Debugf("%s Transaction (begin insert): %v", pg.ID().String(), transaction)
 	if !transaction {
-		tx, ctx, err = pg.StartTransaction()
+		tx, _, err = pg.StartTransaction()
 		if err != nil {
 			log.Log.Debugf("%s Error start transaction: %v", pg.ID().String(), err)
 			return nil, err
@@ -799,7 +848,6 @@ func (pg *PostGres) Insert(name string, insert *common.Entries) (returning [][]a
 		log.Log.Debugf("%s Tx ended pg=%p/tx=%p", pg.ID().String(), pg, pg.tx)
 
 		tx = pg.tx
-		ctx = pg.ctx
 	}
 	if tx == nil || ctx == nil {
 		log.Log.Debugf("Error context transaction")
@@ -815,25 +863,21 @@ func (pg *PostGres) Insert(name string, insert *common.Entries) (returning [][]a
 	values := "("
 
 	indexNeed := pg.IndexNeeded()
-	var insertValues [][]any
-	var insertFields []string
-	log.Log.Debugf("%s Insert SQL record.. preparing values", pg.ID().String())
-	_, isMap := insert.Values[0][0].(map[string]interface{})
-	switch {
-	case insert.DataStruct != nil:
-		insertFields, insertValues, err = createDynamic(insert)
+	if bulk {
+		err = pg.copyFrom(ctx, tx, name, insertFields, insertValues)
 		if err != nil {
+			trErr := pg.rollbackOwn(transaction)
+			log.Log.Debugf("Error copy into %s: %v trErr=%v", name, err, trErr)
 			return nil, err
 		}
-
-	case isMap:
-		insertFields, insertValues, err = createMaps(insert)
-		if err != nil {
-			return nil, err
+		if !transaction {
+			err = pg.EndTransaction(true)
+			if err != nil {
+				return nil, err
+			}
+			pg.Close()
 		}
-	default:
-		insertFields = insert.Fields
-		insertValues = insert.Values
+		return make([][]any, 0), nil
 	}
 
 	log.Log.Debugf("Final values: %#v", insertValues)
@@ -873,7 +917,7 @@ func (pg *PostGres) Insert(name string, insert *common.Entries) (returning [][]a
 				log.Log.Debugf("Use data struct for returning")
 				rv, err := scanStruct(row, insert)
 				if err != nil {
-					trErr := pg.EndTransaction(false)
+					trErr := pg.rollbackOwn(transaction)
 					log.Log.Debugf("Error insert CMD: %v of %s and cmd %s trErr=%v",
 						err, name, insertCmd, trErr)
 					return nil, err
@@ -882,7 +926,7 @@ func (pg *PostGres) Insert(name string, insert *common.Entries) (returning [][]a
 			} else {
 				rv, err := scanRow(row, len(insert.Returning))
 				if err != nil {
-					trErr := pg.EndTransaction(false)
+					trErr := pg.rollbackOwn(transaction)
 					log.Log.Debugf("Error insert CMD: %v of %s and cmd %s trErr=%v",
 						err, name, insertCmd, trErr)
 					return nil, err
@@ -892,13 +936,14 @@ func (pg *PostGres) Insert(name string, insert *common.Entries) (returning [][]a
 		} else {
 			res, err := tx.Exec(ctx, insertCmd, av...)
 			if err != nil {
-				trErr := pg.EndTransaction(false)
+				trErr := pg.rollbackOwn(transaction)
 				log.Log.Debugf("Error insert CMD: %v of %s and cmd %s trErr=%v",
 					err, name, insertCmd, trErr)
 				return nil, err
 			}
 			l := res.RowsAffected()
 			if l == 0 {
+				pg.rollbackOwn(transaction)
 				return nil, errorrepo.NewError("DB000030")
 			}
 		}
@@ -916,47 +961,22 @@ func (pg *PostGres) Insert(name string, insert *common.Entries) (returning [][]a
 	return returning, nil
 }
 
-func createDynamic(insert *common.Entries) ([]string, [][]any, error) {
-	insertValues := make([][]any, 0)
-	dynamic := common.CreateInterface(insert.DataStruct, insert.Fields)
-	insertFields := dynamic.RowFields
-	for _, vi := range insert.Values {
-		v, err := dynamic.CreateValues(vi[0])
-		if err != nil {
-			return nil, nil, err
-		}
-		log.Log.Debugf("Row   fields: %#v", insertFields)
-		log.Log.Debugf("Value fields: %#v", insertValues)
-		insertValues = append(insertValues, v)
+// copyFrom insert all rows using the COPY protocol
+func (pg *PostGres) copyFrom(ctx context.Context, tx pgx.Tx, name string, fields []string, values [][]any) error {
+	columns := make([]string, 0, len(fields))
+	for _, f := range fields {
+		columns = append(columns, strings.ToLower(f))
 	}
-	log.Log.Debugf("Final number records %d", len(insertValues))
-	return insertFields, insertValues, nil
-}
-
-func createMaps(insert *common.Entries) ([]string, [][]any, error) {
-	insertFields := insert.Fields
-	insertValues := make([][]any, 0)
-	if slices.Contains(insert.Fields, "*") {
-		insertFields = make([]string, 0)
-		for n, _ := range insert.Values[0][0].(map[string]interface{}) {
-			insertFields = append(insertFields, n)
-		}
+	table := pgx.Identifier(strings.Split(strings.ToLower(name), "."))
+	log.Log.Debugf("%s Copy %d rows into %v", pg.ID().String(), len(values), table)
+	n, err := tx.CopyFrom(ctx, table, columns, pgx.CopyFromRows(values))
+	if err != nil {
+		return err
 	}
-	for _, vals := range insert.Values[0] {
-		m := vals.(map[string]interface{})
-		rv := make([]any, 0)
-		for _, f := range insertFields {
-			if slices.Contains(insert.Fields, f) {
-				if v, ok := m[f]; ok {
-					rv = append(rv, v)
-				} else {
-					rv = append(rv, nil)
-				}
-			}
-		}
-		insertValues = append(insertValues, rv)
+	if n != int64(len(values)) {
+		return errorrepo.NewError("DB000030")
 	}
-	return insertFields, insertValues, nil
+	return nil
 }
 
 func scanRow(row pgx.Row, cols int) ([]any, error) {
@@ -1002,17 +1022,91 @@ func scanStruct(row pgx.Row, insert *common.Entries) ([]any, error) {
 	return rv, nil
 }
 
+// Upsert insert record into table or update the record with the same key
+func (pg *PostGres) Upsert(name string, upsert *common.Entries) (returning [][]any, err error) {
+	return pg.UpsertContext(context.Background(), name, upsert)
+}
+
+// UpsertContext insert or update record in table using context, the
+// conflict key fields need an unique index or constraint
+func (pg *PostGres) UpsertContext(ctx context.Context, name string, upsert *common.Entries) (returning [][]any, err error) {
+	log.LogFunctionStarts(pg.ID().String())
+	defer log.LogFunctionEnds(time.Now(), pg.ID().String())
+	fields, values, keys, err := upsert.UpsertValues(name)
+	if err != nil {
+		return nil, err
+	}
+	transaction := pg.IsTransaction()
+	var tx pgx.Tx
+	if !transaction {
+		tx, _, err = pg.StartTransaction()
+		if err != nil {
+			return nil, err
+		}
+		defer pg.Close()
+	} else {
+		tx = pg.tx
+	}
+	if tx == nil {
+		return nil, errorrepo.NewError("DB000031")
+	}
+	upsertCmd := dbsql.GenerateUpsert(common.PostgresType, name, fields, keys, upsert.Returning, upsert)
+	log.Log.Debugf("%s Upsert CMD: %s", pg.ID().String(), upsertCmd)
+	returning = make([][]any, 0)
+	for _, v := range values {
+		if len(upsert.Returning) > 0 {
+			var rv []any
+			row := tx.QueryRow(ctx, upsertCmd, v...)
+			if upsert.DataStruct != nil {
+				rv, err = scanStruct(row, upsert)
+			} else {
+				rv, err = scanRow(row, len(upsert.Returning))
+			}
+			returning = append(returning, rv)
+		} else {
+			_, err = tx.Exec(ctx, upsertCmd, v...)
+		}
+		if err != nil {
+			trErr := pg.rollbackOwn(transaction)
+			log.Log.Debugf("Error upsert CMD: %v of %s and cmd %s trErr=%v",
+				err, name, upsertCmd, trErr)
+			return nil, err
+		}
+	}
+	if !transaction {
+		err = pg.EndTransaction(true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return returning, nil
+}
+
 // Update update record in table
 func (pg *PostGres) Update(name string, updateInfo *common.Entries) (returning [][]any, rowsAffected int64, err error) {
+	return pg.UpdateContext(context.Background(), name, updateInfo)
+}
+
+// UpdateContext update record in table using context
+func (pg *PostGres) UpdateContext(ctx context.Context, name string, updateInfo *common.Entries) (returning [][]any, rowsAffected int64, err error) {
 	log.LogFunctionStarts(pg.ID().String())
 	defer log.LogFunctionEnds(time.Now(), pg.ID().String())
 	log.Log.Debugf("%s: Update in posgres database", pg.ID().String())
 	defer log.Log.Debugf("%s: Update ended for posgres database", pg.ID().String())
+	if len(updateInfo.Returning) == 0 {
+		counts, err := pg.BatchUpdateContext(ctx, name, updateInfo)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, c := range counts {
+			rowsAffected += c
+		}
+		return make([][]any, 0), rowsAffected, nil
+	}
 	transaction := pg.IsTransaction()
-	var ctx context.Context
 	var tx pgx.Tx
 	if !transaction {
-		tx, ctx, err = pg.StartTransaction()
+		tx, _, err = pg.StartTransaction()
 		if err != nil {
 			return nil, -1, err
 		}
@@ -1020,79 +1114,37 @@ func (pg *PostGres) Update(name string, updateInfo *common.Entries) (returning [
 	} else {
 		log.Log.Debugf("Tx used pg=%p/tx=%p", pg, pg.tx)
 		tx = pg.tx
-		ctx = pg.ctx
 	}
 	if tx == nil {
 		return nil, 0, errorrepo.NewError("DB000031")
 	}
-	var insertFields []string
-	var updateValues [][]any
-	if updateInfo.DataStruct != nil {
-		updateValues = make([][]any, 0)
-		dynamic := common.CreateInterface(updateInfo.DataStruct, updateInfo.Fields)
-		insertFields = dynamic.RowFields
-		for _, vi := range updateInfo.Values {
-			v, err := dynamic.CreateValues(vi[0])
-			if err != nil {
-				return nil, -1, err
-			}
-			updateValues = append(updateValues, v)
-			log.Log.Debugf("Row   fields: %#v", insertFields)
-			log.Log.Debugf("Value fields: %#v", updateValues)
-		}
-	} else {
-		updateValues = updateInfo.Values
+	updateFields, updateValues, err := updateInfo.InsertValues()
+	if err != nil {
+		return nil, -1, err
 	}
-	updateCmd, whereFields := dbsql.GenerateUpdate(pg.IndexNeeded(), name, updateInfo)
-	if len(updateInfo.Returning) > 0 {
-		updateCmd += " RETURNING "
-		for i, r := range updateInfo.Returning {
-			if i > 0 {
-				updateCmd += ","
-			}
-			updateCmd += r
-		}
+	updateCmd, arguments, err := dbsql.GenerateBatchUpdate(common.PostgresType, name, updateFields, updateInfo)
+	if err != nil {
+		return nil, -1, err
 	}
+	updateCmd += " RETURNING " + strings.Join(updateInfo.Returning, ",")
+	log.Log.Debugf("Update call: %s", updateCmd)
 
+	stale := dbsql.StaleVersion(name, updateFields, updateInfo)
 	returning = make([][]any, 0)
-	for i, v := range updateValues {
-		av := v
-		whereClause := dbsql.CreateWhere(i, updateInfo, whereFields)
-		ic := updateCmd + whereClause
-		log.Log.Debugf("Update call: %s", ic)
-		log.Log.Debugf("Update values: %d -> %#v tx=%v %v", len(v), v, tx, ctx)
-		if len(updateInfo.Returning) > 0 {
-			row := tx.QueryRow(ctx, updateCmd, av...)
-			if updateInfo.DataStruct != nil {
-				log.Log.Debugf("Use data struct for returning")
-				rv, err := scanStruct(row, updateInfo)
-				if err != nil {
-					trErr := pg.EndTransaction(false)
-					log.Log.Debugf("Error insert CMD: %v of %s and cmd %s trErr=%v",
-						err, name, updateCmd, trErr)
-					return nil, 0, err
-				}
-				returning = append(returning, rv)
-			} else {
-				rv, err := scanRow(row, len(updateInfo.Returning))
-				if err != nil {
-					trErr := pg.EndTransaction(false)
-					log.Log.Debugf("Error insert CMD: %v of %s and cmd %s trErr=%v",
-						err, name, updateCmd, trErr)
-					return nil, 0, err
-				}
-				returning = append(returning, rv)
-			}
-		} else {
-			res, err := tx.Exec(ctx, ic, v...)
-			if err != nil {
-				log.Log.Debugf("Update error: %s -> %v", ic, err)
-				pg.EndTransaction(false)
-				return nil, 0, err
-			}
-			rowsAffected += res.RowsAffected()
+	for _, v := range updateValues {
+		log.Log.Debugf("Update values: %d -> %#v", len(v), v)
+		rows, err := pg.queryReturning(ctx, tx, updateCmd, dbsql.BatchUpdateArguments(v, arguments), updateInfo)
+		if err == nil && len(rows) == 0 && stale != nil {
+			err = stale
+		}
+		if err != nil {
+			trErr := pg.rollbackOwn(transaction)
+			log.Log.Debugf("Error update CMD: %v of %s and cmd %s trErr=%v",
+				err, name, updateCmd, trErr)
+			return nil, 0, err
 		}
-		log.Log.Debugf("Rows affected %d", rowsAffected)
+		returning = append(returning, rows...)
+		rowsAffected += int64(len(rows))
 	}
 	log.Log.Debugf("Update done")
 
@@ -1105,8 +1157,36 @@ func (pg *PostGres) Update(name string, updateInfo *common.Entries) (returning [
 	return returning, rowsAffected, nil
 }
 
+// queryReturning query the returning fields of all rows of the statement
+func (pg *PostGres) queryReturning(ctx context.Context, tx pgx.Tx, statement string, args []any, entries *common.Entries) ([][]any, error) {
+	rows, err := tx.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	returning := make([][]any, 0)
+	for rows.Next() {
+		var rv []any
+		if entries.DataStruct != nil {
+			rv, err = scanStruct(rows, entries)
+		} else {
+			rv, err = scanRow(rows, len(entries.Returning))
+		}
+		if err != nil {
+			return nil, err
+		}
+		returning = append(returning, rv)
+	}
+	return returning, rows.Err()
+}
+
 // Batch batch SQL query in table
 func (pg *PostGres) Batch(batch string) error {
+	return pg.BatchContext(context.Background(), batch)
+}
+
+// BatchContext batch SQL query in table using context
+func (pg *PostGres) BatchContext(ctx context.Context, batch string) error {
 	layer, url := pg.Reference()
 	db, err := sql.Open(layer, url)
 	if err != nil {
@@ -1117,7 +1197,7 @@ func (pg *PostGres) Batch(batch string) error {
 	log.Log.Debugf("Calling batch " + batch)
 
 	// Query batch SQL
-	rows, err := db.Query(batch)
+	rows, err := db.QueryContext(ctx, batch)
 	if err != nil {
 		return err
 	}
@@ -1133,6 +1213,11 @@ func (pg *PostGres) Batch(batch string) error {
 
 // BatchSelect batch SQL query in table with values returned
 func (pg *PostGres) BatchSelect(batch string) ([][]interface{}, error) {
+	return pg.BatchSelectContext(context.Background(), batch)
+}
+
+// BatchSelectContext batch SQL query in table with values returned using context
+func (pg *PostGres) BatchSelectContext(ctx context.Context, batch string) ([][]interface{}, error) {
 	layer, url := pg.Reference()
 	db, err := sql.Open(layer, url)
 	if err != nil {
@@ -1140,7 +1225,7 @@ func (pg *PostGres) BatchSelect(batch string) ([][]interface{}, error) {
 	}
 	defer db.Close()
 	// Query batch SQL
-	rows, err := db.Query(batch)
+	rows, err := db.QueryContext(ctx, batch)
 	if err != nil {
 		return nil, err
 	}
@@ -1168,6 +1253,11 @@ func (pg *PostGres) BatchSelect(batch string) ([][]interface{}, error) {
 
 // BatchSelectFct batch SQL query in table with fct called
 func (pg *PostGres) BatchSelectFct(search *common.Query, fct common.ResultFunction) error {
+	return pg.BatchSelectFctContext(context.Background(), search, fct)
+}
+
+// BatchSelectFctContext batch SQL query in table with fct called using context
+func (pg *PostGres) BatchSelectFctContext(ctx context.Context, search *common.Query, fct common.ResultFunction) error {
 	log.Log.Debugf("%s: Query posgres database", pg.ID().String())
 	dbOpen, err := pg.Open()
 	if err != nil {
@@ -1175,14 +1265,16 @@ func (pg *PostGres) BatchSelectFct(search *common.Query, fct common.ResultFuncti
 	}
 
 	db := dbOpen.(*pgxpool.Conn)
-	ctx := context.Background()
 	defer pg.Close()
-	selectCmd := search.Search
-	if selectCmd == "" {
+	if search.Search == "" {
 		return errorrepo.NewError("DB000034")
 	}
-	log.Log.Debugf("%s: Query: %s Parameters: %#v", pg.ID().String(), selectCmd, search.Parameters)
-	rows, err := db.Query(ctx, selectCmd, search.Parameters...)
+	selectCmd, args, err := common.BindParameters(common.PostgresType, search.Search, search.Parameters)
+	if err != nil {
+		return err
+	}
+	log.Log.Debugf("%s: Query: %s Parameters: %#v", pg.ID().String(), selectCmd, args)
+	rows, err := db.Query(ctx, selectCmd, args...)
 	if err != nil {
 		log.Log.Debugf("%s: Query error: %v", pg.ID().String(), err)
 		return err
@@ -1247,16 +1339,192 @@ func (pg *PostGres) Rollback() error {
 	return pg.EndTransaction(false)
 }
 
+// BeginTx begin a transaction handle using its own connection of the pool
+func (pg *PostGres) BeginTx(ctx context.Context, opts *common.TxOptions) (common.Transaction, error) {
+	txOptions := pgx.TxOptions{}
+	if opts != nil {
+		switch opts.Isolation {
+		case sql.LevelDefault:
+		case sql.LevelReadUncommitted:
+			txOptions.IsoLevel = pgx.ReadUncommitted
+		case sql.LevelReadCommitted:
+			txOptions.IsoLevel = pgx.ReadCommitted
+		case sql.LevelRepeatableRead:
+			txOptions.IsoLevel = pgx.RepeatableRead
+		case sql.LevelSerializable:
+			txOptions.IsoLevel = pgx.Serializable
+		default:
+			return nil, errorrepo.NewError("DB000047", opts.Isolation.String(), "postgres")
+		}
+		if opts.ReadOnly {
+			txOptions.AccessMode = pgx.ReadOnly
+		}
+	}
+	txPg := pg.Clone().(*PostGres)
+	db, err := txPg.open()
+	if err != nil {
+		txPg.FreeHandler()
+		return nil, err
+	}
+	txPg.openDB = db
+	tx, err := db.BeginTx(ctx, txOptions)
+	if err != nil {
+		log.Log.Debugf("%s Begin of transaction handle fails: %v", pg.ID().String(), err)
+		txPg.Close()
+		txPg.FreeHandler()
+		return nil, err
+	}
+	txPg.tx = tx
+	txPg.ctx = ctx
+	txPg.Transaction = true
+	log.Log.Debugf("%s Begin transaction handle (pg=%p/tx=%p)", pg.ID().String(), txPg, tx)
+	return &transaction{pg: txPg}, nil
+}
+
+// transaction transaction handle of postgres
+type transaction struct {
+	pg *PostGres
+}
+
+// QueryContext query database records in the transaction
+func (transaction *transaction) QueryContext(ctx context.Context, search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	search.Driver = common.PostgresType
+	selectCmd, args, err := search.SelectParameters()
+	if err != nil {
+		return nil, err
+	}
+	log.Log.Debugf("Postgres transaction query: %s", selectCmd)
+	rows, err := transaction.pg.tx.Query(ctx, selectCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if search.DataStruct == nil {
+		return transaction.pg.ParseRows(search, rows, f)
+	}
+	return transaction.pg.ParseStruct(search, rows, f)
+}
+
+// InsertContext insert records in the transaction
+func (transaction *transaction) InsertContext(ctx context.Context, name string, insert *common.Entries) ([][]any, error) {
+	return transaction.pg.InsertContext(ctx, name, insert)
+}
+
+// UpsertContext insert or update records in the transaction
+func (transaction *transaction) UpsertContext(ctx context.Context, name string, upsert *common.Entries) ([][]any, error) {
+	return transaction.pg.UpsertContext(ctx, name, upsert)
+}
+
+// BatchUpdateContext update records of all value rows in one pipelined
+// batch in the transaction
+func (transaction *transaction) BatchUpdateContext(ctx context.Context, name string, updateInfo *common.Entries) ([]int64, error) {
+	return transaction.pg.BatchUpdateContext(ctx, name, updateInfo)
+}
+
+// BatchDeleteContext delete records of all value rows in one pipelined
+// batch in the transaction
+func (transaction *transaction) BatchDeleteContext(ctx context.Context, name string, remove *common.Entries) ([]int64, error) {
+	return transaction.pg.BatchDeleteContext(ctx, name, remove)
+}
+
+// UpdateContext update records in the transaction
+func (transaction *transaction) UpdateContext(ctx context.Context, name string, updateInfo *common.Entries) ([][]any, int64, error) {
+	return transaction.pg.UpdateContext(ctx, name, updateInfo)
+}
+
+// DeleteContext delete records in the transaction
+func (transaction *transaction) DeleteContext(ctx context.Context, name string, remove *common.Entries) (int64, error) {
+	return transaction.pg.DeleteContext(ctx, name, remove)
+}
+
+// StreamContext streaming data from a field in the transaction
+func (transaction *transaction) StreamContext(ctx context.Context, search *common.Query, sf common.StreamFunction) error {
+	return transaction.pg.stream(ctx, transaction.pg.tx, search, sf)
+}
+
+// BatchContext batch SQL query in the transaction
+func (transaction *transaction) BatchContext(ctx context.Context, batch string) error {
+	if transaction.pg.tx == nil {
+		return errorrepo.NewError("DB000027")
+	}
+	_, err := transaction.pg.tx.Exec(ctx, batch)
+	return err
+}
+
+// Savepoint set savepoint in the transaction
+func (transaction *transaction) Savepoint(ctx context.Context, name string) error {
+	return transaction.BatchContext(ctx, "SAVEPOINT "+name)
+}
+
+// RollbackTo rollback the transaction to the savepoint
+func (transaction *transaction) RollbackTo(ctx context.Context, name string) error {
+	return transaction.BatchContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+}
+
+// Release release the savepoint
+func (transaction *transaction) Release(ctx context.Context, name string) error {
+	return transaction.BatchContext(ctx, "RELEASE SAVEPOINT "+name)
+}
+
+// Retryable check if the transaction failed because of a serialization
+// failure or deadlock and can be retried
+func (transaction *transaction) Retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// serialization_failure or deadlock_detected
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return false
+}
+
+// Commit commit the transaction and release the connection
+func (transaction *transaction) Commit(ctx context.Context) error {
+	return transaction.end(ctx, true)
+}
+
+// Rollback rollback the transaction and release the connection
+func (transaction *transaction) Rollback(ctx context.Context) error {
+	return transaction.end(ctx, false)
+}
+
+// end end the transaction, a transaction already rolled back because of
+// an error returns an error
+func (transaction *transaction) end(ctx context.Context, commit bool) error {
+	pg := transaction.pg
+	defer pg.FreeHandler()
+	defer pg.Close()
+	if pg.tx == nil {
+		return errorrepo.NewError("DB000027")
+	}
+	pg.ctx = ctx
+	return pg.EndTransaction(commit)
+}
+
+// Stream streaming data from a field
 func (pg *PostGres) Stream(search *common.Query, sf common.StreamFunction) error {
+	return pg.StreamContext(context.Background(), search, sf)
+}
+
+// StreamContext streaming data from a field using context
+func (pg *PostGres) StreamContext(ctx context.Context, search *common.Query, sf common.StreamFunction) error {
 	dbOpen, err := pg.Open()
 	if err != nil {
 		return err
 	}
 
-	ctx := pg.ctx
 	conn := dbOpen.(*pgxpool.Conn)
 	defer pg.Close()
+	return pg.stream(ctx, conn, search, sf)
+}
 
+// queryer query interface of connections and transactions
+type queryer interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// stream read the field of the stream query in blocks and call the
+// stream function for each block
+func (pg *PostGres) stream(ctx context.Context, conn queryer, search *common.Query, sf common.StreamFunction) error {
 	blocksize := search.Blocksize
 	if blocksize == 0 {
 		blocksize = defaultBlocksize
@@ -1275,7 +1543,11 @@ func (pg *PostGres) Stream(search *common.Query, sf common.StreamFunction) error
 				search.Fields[0], offset, blocksize, search.TableName, search.Search)
 		}
 		log.Log.Debugf("Read = %d,%d -> %s\n", offset, offset+blocksize, selectCmd)
-		rows, err := conn.Query(ctx, selectCmd)
+		streamCmd, args, err := common.BindParameters(common.PostgresType, selectCmd, search.Parameters)
+		if err != nil {
+			return err
+		}
+		rows, err := conn.Query(ctx, streamCmd, args...)
 		if err != nil {
 			log.Log.Debugf("Stream query error: %v", err)
 			return err
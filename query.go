@@ -0,0 +1,112 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package flynn
+
+import (
+	"context"
+	"reflect"
+	"slices"
+
+	"github.com/tknie/flynn/common"
+)
+
+// QueryAll query all database records into a slice of structures of
+// type T. Each record gets its own structure.
+func QueryAll[T any](id common.RegDbID, query *common.Query) ([]T, error) {
+	return QueryAllContext[T](context.Background(), id, query)
+}
+
+// QueryAllContext query all database records into a slice of structures of
+// type T using context
+func QueryAllContext[T any](ctx context.Context, id common.RegDbID, query *common.Query) ([]T, error) {
+	list := make([]T, 0)
+	for record, err := range AllContext[T](ctx, id, query) {
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *record)
+	}
+	return list, nil
+}
+
+// QueryOne query the first database record into a structure of type T.
+// If no record is found, an error of type common.NotFoundError is returned.
+func QueryOne[T any](id common.RegDbID, query *common.Query) (*T, error) {
+	return QueryOneContext[T](context.Background(), id, query)
+}
+
+// QueryOneContext query the first database record into a structure of
+// type T using context
+func QueryOneContext[T any](ctx context.Context, id common.RegDbID, query *common.Query) (*T, error) {
+	for record, err := range AllContext[T](ctx, id, query) {
+		if err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+	return nil, &common.NotFoundError{TableName: query.TableName, Search: query.Search}
+}
+
+// QueryMap query all database records into maps of field names and values.
+// Structure queries use the structure field names as keys.
+func QueryMap(id common.RegDbID, query *common.Query) ([]map[string]any, error) {
+	return QueryMapContext(context.Background(), id, query)
+}
+
+// QueryMapContext query all database records into maps of field names and
+// values using context
+func QueryMapContext(ctx context.Context, id common.RegDbID, query *common.Query) ([]map[string]any, error) {
+	var structFields []string
+	if query.DataStruct != nil {
+		structFields = common.CreateInterface(query.DataStruct, query.Fields).QueryFields
+	}
+	list := make([]map[string]any, 0)
+	for result, err := range id.RowsContext(ctx, query) {
+		if err != nil {
+			return nil, err
+		}
+		fields := result.Fields
+		if structFields != nil {
+			fields = structFields
+		}
+		m := make(map[string]any, len(fields))
+		for i, f := range fields {
+			if i >= len(result.Rows) {
+				break
+			}
+			m[f] = mapValue(result.Rows[i])
+		}
+		list = append(list, m)
+	}
+	return list, nil
+}
+
+// mapValue value of the row entry. Structure queries provide pointers to
+// the fields of the structure reused for all rows, the pointers are
+// dereferenced so that each map gets its own copy of the value.
+func mapValue(v any) any {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	v = rv.Interface()
+	if b, ok := v.([]byte); ok {
+		return slices.Clone(b)
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package flynn
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+)
+
+func TestQueryAll(t *testing.T) {
+	InitLog(t)
+
+	id := iterTestTable(t)
+	if id == 0 {
+		return
+	}
+	list, err := QueryAll[iterRecord](id, &common.Query{TableName: "IterRecord",
+		Fields: []string{"*"}, Search: "Counter <= 3", Order: []string{"Counter:DESC"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []iterRecord{{ID: "IT03", Name: "Name 3", Counter: 3},
+		{ID: "IT02", Name: "Name 2", Counter: 2}, {ID: "IT01", Name: "Name 1", Counter: 1}}, list)
+
+	list, err = QueryAll[iterRecord](id, &common.Query{TableName: "IterRecord",
+		Fields: []string{"*"}, Search: "Counter > 100"})
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	_, err = QueryAll[iterRecord](id, &common.Query{TableName: "Unknown"})
+	assert.Error(t, err)
+}
+
+func TestQueryOne(t *testing.T) {
+	InitLog(t)
+
+	id := iterTestTable(t)
+	if id == 0 {
+		return
+	}
+	record, err := QueryOne[iterRecord](id, &common.Query{TableName: "IterRecord",
+		Fields: []string{"*"}, Search: "ID = 'IT07'"})
+	assert.NoError(t, err)
+	assert.Equal(t, &iterRecord{ID: "IT07", Name: "Name 7", Counter: 7}, record)
+
+	record, err = QueryOne[iterRecord](id, &common.Query{TableName: "IterRecord",
+		Fields: []string{"*"}, Search: "ID = 'XXXX'"})
+	assert.Nil(t, record)
+	assert.True(t, errors.Is(err, common.ErrNotFound))
+	var notFound *common.NotFoundError
+	if assert.True(t, errors.As(err, &notFound)) {
+		assert.Equal(t, "IterRecord", notFound.TableName)
+		assert.Equal(t, "ID = 'XXXX'", notFound.Search)
+	}
+}
+
+func TestQueryMap(t *testing.T) {
+	InitLog(t)
+
+	id := iterTestTable(t)
+	if id == 0 {
+		return
+	}
+	list, err := QueryMap(id, &common.Query{TableName: "IterRecord",
+		Fields: []string{"ID", "Counter"}, Search: "Counter IN (4, 5)", Order: []string{"ID"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"ID": "IT04", "Counter": int64(4)},
+		{"ID": "IT05", "Counter": int64(5)}}, list)
+
+	list, err = QueryMap(id, &common.Query{TableName: "IterRecord", DataStruct: &iterRecord{},
+		Fields: []string{"Name", "Counter"}, Search: "Counter BETWEEN 1 AND 2", Order: []string{"ID"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"Name": "Name 1", "Counter": int64(1)},
+		{"Name": "Name 2", "Counter": int64(2)}}, list)
+
+	// each row gets its own time value
+	type mapRecord struct {
+		ID   string `flynn:"ID::10"`
+		When time.Time
+	}
+	if !assert.NoError(t, id.CreateTable("MapRecord", &mapRecord{})) {
+		return
+	}
+	defer id.DeleteTable("MapRecord")
+	first := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err = id.Insert("MapRecord", &common.Entries{Fields: []string{"*"}, DataStruct: &mapRecord{},
+		Values: [][]any{{&mapRecord{ID: "a", When: first}}, {&mapRecord{ID: "b", When: second}}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	list, err = QueryMap(id, &common.Query{TableName: "MapRecord", DataStruct: &mapRecord{},
+		Fields: []string{"*"}, Order: []string{"ID"}})
+	assert.NoError(t, err)
+	if assert.Len(t, list, 2) {
+		assert.True(t, first.Equal(list[0]["When"].(time.Time)))
+		assert.True(t, second.Equal(list[1]["When"].(time.Time)))
+	}
+}
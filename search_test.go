@@ -621,6 +621,57 @@ func TestSearchMariaDBRows(t *testing.T) {
 	finalCheck(t, 1)
 }
 
+func TestSearchSqliteRows(t *testing.T) {
+	InitLog(t)
+
+	db, err := sqliteTarget(t)
+	if !assert.NoError(t, err) {
+		return
+	}
+	target := &target{"sqlite", db}
+	if createStructTestTable(t, target) != nil {
+		return
+	}
+	if fillStructTestTable(t, target) != nil {
+		return
+	}
+
+	x, err := Handle("sqlite", db)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer x.FreeHandler()
+
+	q := &common.Query{TableName: testStructTable,
+		Search: "ID='1'",
+		Fields: []string{"ID", "Name"}}
+	counter := 0
+	_, err = x.Query(q, func(search *common.Query, result *common.Result) error {
+		assert.Equal(t, "1", result.Rows[0])
+		assert.Equal(t, "NAME", result.GetRowValueByName("name"))
+		counter++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counter)
+
+	q = &common.Query{TableName: testStructTable,
+		DataStruct: &TestData{},
+		Search:     "ID='1'",
+		Fields:     []string{"ID", "Name", "LobData"}}
+	counter = 0
+	_, err = x.Query(q, func(search *common.Query, result *common.Result) error {
+		record := result.Data.(*TestData)
+		assert.Equal(t, "NAME", record.Name)
+		assert.Equal(t, []byte{1, 2, 3, 4, 5}, record.LobData)
+		counter++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counter)
+	finalCheck(t, 1)
+}
+
 func TestSearchPgRowsOrdered(t *testing.T) {
 	InitLog(t)
 	pgUrl, err := postgresUserTarget(t)
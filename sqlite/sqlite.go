@@ -0,0 +1,586 @@
+//go:build !flynn_nosqlite
+// +build !flynn_nosqlite
+
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/flynn/dbsql"
+	"github.com/tknie/log"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	layer      = "sqlite"
+	memoryPath = ":memory:"
+)
+
+// Sqlite instance for SQLite
+type Sqlite struct {
+	common.CommonDatabase
+	openDB *sql.DB
+	// sharedDB openDB belongs to the instance this one is cloned from
+	sharedDB     bool
+	dbTableNames []string
+	tx           *sql.Tx
+	ctx          context.Context
+}
+
+// queryer query interface of database or transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func init() {
+	common.RegisterDriverDefinition("sqlite", &common.Driver{Type: common.SqliteType,
+		Name: "SQLite", Parse: common.ParsePath, Factory: NewInstance})
+}
+
+// NewInstance create new SQLite reference instance
+func NewInstance(id common.RegDbID, reference *common.Reference, password string) (common.Database, error) {
+	if reference == nil || reference.Database == "" {
+		return nil, errorrepo.NewError("DB000036", "sqlite://")
+	}
+	sqlite := &Sqlite{common.NewCommonDatabase(id, "sqlite"),
+		nil, false, nil, nil, nil}
+	sqlite.ConRef = reference
+	log.Log.Debugf("%s: create new instance", sqlite.ID().String())
+	return sqlite, nil
+}
+
+// New create new SQLite reference instance
+func New(id common.RegDbID, url string) (common.Database, error) {
+	ref, p, err := common.NewReference(url)
+	if err != nil {
+		return nil, err
+	}
+	return NewInstance(id, ref, p)
+}
+
+// Clone clone the SQLite instance sharing the opened database, the clone
+// does not close the shared database
+func (sqlite *Sqlite) Clone() common.Database {
+	newSqlite := &Sqlite{}
+	*newSqlite = *sqlite
+	newSqlite.sharedDB = sqlite.openDB != nil
+	newSqlite.tx = nil
+	newSqlite.ctx = nil
+	return newSqlite
+}
+
+// SetCredentials set credentials to connect to database, not used by SQLite
+func (sqlite *Sqlite) SetCredentials(user, password string) error {
+	return nil
+}
+
+// generateURL generate the data source name of the SQLite driver. In-memory
+// databases use the `memdb` VFS so that all connections of the handler
+// share the same database.
+func (sqlite *Sqlite) generateURL() string {
+	reference := sqlite.ConRef
+	url := "file:" + reference.Database
+	if reference.Database == memoryPath {
+		url = fmt.Sprintf("file:/flynn-%d?vfs=memdb&", uint64(sqlite.ID()))
+	} else {
+		url += "?"
+	}
+	url += "_pragma=busy_timeout(5000)"
+	for _, o := range reference.Options {
+		url += "&" + o
+	}
+	return url
+}
+
+func (sqlite *Sqlite) open() (dbOpen *sql.DB, err error) {
+	if sqlite.openDB == nil {
+		log.Log.Debugf("%s: Open SQLite database to %s", sqlite.ID().String(), sqlite.URL())
+		db, err := sql.Open(layer, sqlite.generateURL())
+		if err != nil {
+			return nil, err
+		}
+		// keep a connection open, in-memory databases are removed if the
+		// last connection is closed
+		err = db.Ping()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		sqlite.openDB = db
+		sqlite.sharedDB = false
+	}
+	return sqlite.openDB, nil
+}
+
+// Open open the database connection
+func (sqlite *Sqlite) Open() (dbOpen any, err error) {
+	db, err := sqlite.open()
+	if err != nil {
+		log.Log.Debugf("%s: error open connection: %v", sqlite.ID().String(), err)
+		return nil, err
+	}
+	if sqlite.IsTransaction() && sqlite.tx == nil {
+		_, _, err = sqlite.StartTransaction()
+		if err != nil {
+			log.Log.Debugf("%s: error begin transaction: %v", sqlite.ID().String(), err)
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// queryer return transaction if in transaction, otherwise the database
+func (sqlite *Sqlite) queryer() (queryer, error) {
+	dbOpen, err := sqlite.Open()
+	if err != nil {
+		return nil, err
+	}
+	if sqlite.tx != nil && sqlite.IsTransaction() {
+		return sqlite.tx, nil
+	}
+	return dbOpen.(*sql.DB), nil
+}
+
+// BeginTransaction start transaction the database connection
+func (sqlite *Sqlite) BeginTransaction() error {
+	if sqlite.tx != nil && sqlite.ctx != nil {
+		return nil
+	}
+	_, _, err := sqlite.StartTransaction()
+	if err != nil {
+		log.Log.Debugf("%s: error start transaction: %v", sqlite.ID().String(), err)
+		return err
+	}
+	sqlite.Transaction = true
+	return nil
+}
+
+// EndTransaction end the transaction and commit if commit parameter is
+// true.
+func (sqlite *Sqlite) EndTransaction(commit bool) (err error) {
+	if sqlite.tx == nil && sqlite.ctx == nil {
+		return nil
+	}
+	if sqlite.IsTransaction() {
+		return nil
+	}
+	log.Log.Debugf("%s: Commit/Rollback transaction %p commit = %v", sqlite.ID().String(), sqlite.tx, commit)
+	if commit {
+		err = sqlite.tx.Commit()
+	} else {
+		err = sqlite.tx.Rollback()
+	}
+	sqlite.tx = nil
+	sqlite.ctx = nil
+	if err != nil {
+		log.Log.Debugf("%s: error end transaction: %v", sqlite.ID().String(), err)
+	}
+	return
+}
+
+// Close close the database connection. The database itself is kept open
+// until the handler is freed.
+func (sqlite *Sqlite) Close() {
+	log.Log.Debugf("%s: Close SQLite", sqlite.ID().String())
+	if sqlite.ctx != nil {
+		sqlite.EndTransaction(false)
+	}
+}
+
+// FreeHandler don't use the driver anymore
+func (sqlite *Sqlite) FreeHandler() {
+	log.Log.Debugf("%s: free handler", sqlite.ID().String())
+	if sqlite.openDB != nil && !sqlite.sharedDB {
+		sqlite.openDB.Close()
+	}
+	sqlite.openDB = nil
+}
+
+// IndexNeeded index needed for the SELECT statement value reference
+func (sqlite *Sqlite) IndexNeeded() bool {
+	return false
+}
+
+// ByteArrayAvailable byte array available in SQL database
+func (sqlite *Sqlite) ByteArrayAvailable() bool {
+	return false
+}
+
+// Reference reference to SQLite URL
+func (sqlite *Sqlite) Reference() (string, string) {
+	return layer, sqlite.generateURL()
+}
+
+// ID current id used
+func (sqlite *Sqlite) ID() common.RegDbID {
+	return sqlite.RegDbID
+}
+
+// URL current URL used
+func (sqlite *Sqlite) URL() string {
+	return "sqlite://" + sqlite.ConRef.Database + sqlite.ConRef.OptionString()
+}
+
+// Maps database maps, tables or views
+func (sqlite *Sqlite) Maps() ([]string, error) {
+	if sqlite.dbTableNames == nil {
+		err := sqlite.Ping()
+		if err != nil {
+			log.Log.Debugf("%s: error reading maps: %v", sqlite.ID().String(), err)
+			return nil, err
+		}
+	}
+	return sqlite.dbTableNames, nil
+}
+
+// Ping create short test database connection
+func (sqlite *Sqlite) Ping() error {
+	return sqlite.PingContext(context.Background())
+}
+
+// PingContext create short test database connection using context
+func (sqlite *Sqlite) PingContext(ctx context.Context) error {
+	db, err := sqlite.queryer()
+	if err != nil {
+		return err
+	}
+	defer sqlite.Close()
+
+	rows, err := db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type IN ('table','view')
+		AND name NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY name`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	dbTableNames := make([]string, 0)
+	tableName := ""
+	for rows.Next() {
+		err = rows.Scan(&tableName)
+		if err != nil {
+			return err
+		}
+		dbTableNames = append(dbTableNames, tableName)
+	}
+	sqlite.dbTableNames = dbTableNames
+	return rows.Err()
+}
+
+// Delete Delete database records
+func (sqlite *Sqlite) Delete(name string, remove *common.Entries) (int64, error) {
+	return sqlite.DeleteContext(context.Background(), name, remove)
+}
+
+// DeleteContext Delete database records using context
+func (sqlite *Sqlite) DeleteContext(ctx context.Context, name string, remove *common.Entries) (int64, error) {
+	return dbsql.DeleteContext(ctx, sqlite, common.SqliteType, name, remove)
+}
+
+// GetTableColumn get table columne names
+func (sqlite *Sqlite) GetTableColumn(tableName string) ([]string, error) {
+	return sqlite.GetTableColumnContext(context.Background(), tableName)
+}
+
+// GetTableColumnContext get table column names using context
+func (sqlite *Sqlite) GetTableColumnContext(ctx context.Context, tableName string) ([]string, error) {
+	db, err := sqlite.queryer()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlite.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tableRows := make([]string, 0)
+	tableRow := ""
+	for rows.Next() {
+		err = rows.Scan(&tableRow)
+		if err != nil {
+			return nil, err
+		}
+		tableRows = append(tableRows, strings.ToLower(tableRow))
+	}
+	return tableRows, rows.Err()
+}
+
+// DescribeTableContext read the column definitions of the table
+func (sqlite *Sqlite) DescribeTableContext(ctx context.Context, tableName string) (*common.TableInfo, error) {
+	if _, err := sqlite.open(); err != nil {
+		return nil, err
+	}
+	return dbsql.DescribeTable(ctx, sqlite, common.SqliteType, tableName)
+}
+
+// CreateIndexContext create the index on the columns of the table
+func (sqlite *Sqlite) CreateIndexContext(ctx context.Context, tableName string, index *common.Index) error {
+	if _, err := sqlite.open(); err != nil {
+		return err
+	}
+	return dbsql.CreateIndex(ctx, sqlite, common.SqliteType, tableName, index)
+}
+
+// DropIndexContext drop the index of the table
+func (sqlite *Sqlite) DropIndexContext(ctx context.Context, tableName, indexName string) error {
+	if _, err := sqlite.open(); err != nil {
+		return err
+	}
+	return dbsql.DropIndex(ctx, sqlite, common.SqliteType, tableName, indexName)
+}
+
+// Query query database records with search or SELECT
+func (sqlite *Sqlite) Query(search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	return sqlite.QueryContext(context.Background(), search, f)
+}
+
+// QueryContext query database records with search or SELECT using context
+func (sqlite *Sqlite) QueryContext(ctx context.Context, search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	search.Driver = common.SqliteType
+	db, err := sqlite.queryer()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlite.Close()
+
+	selectCmd, args, err := search.SelectParameters()
+	if err != nil {
+		return nil, err
+	}
+	log.Log.Debugf("Query: %s", selectCmd)
+	rows, err := db.QueryContext(ctx, selectCmd, args...)
+	if err != nil {
+		log.Log.Debugf("%s: error query data: %v", sqlite.ID().String(), err)
+		return nil, err
+	}
+	defer rows.Close()
+	if search.DataStruct == nil {
+		return search.ParseRows(rows, f)
+	}
+	return search.ParseStruct(rows, f)
+}
+
+// CreateTable create a new table
+func (sqlite *Sqlite) CreateTable(name string, columns any) error {
+	return sqlite.CreateTableContext(context.Background(), name, columns)
+}
+
+// CreateTableContext create a new table using context
+func (sqlite *Sqlite) CreateTableContext(ctx context.Context, name string, columns any) error {
+	if _, err := sqlite.open(); err != nil {
+		return err
+	}
+	return dbsql.CreateTableContext(ctx, sqlite, name, columns)
+}
+
+// AdaptTable adapt table to new struct
+func (sqlite *Sqlite) AdaptTable(name string, newStruct any) error {
+	if _, err := sqlite.open(); err != nil {
+		return err
+	}
+	return dbsql.AdaptTable(context.Background(), sqlite, common.SqliteType, name, newStruct)
+}
+
+// PlanAdaptTableContext plan the changes adapting the table to the new
+// struct. SQLite can only add and drop columns.
+func (sqlite *Sqlite) PlanAdaptTableContext(ctx context.Context, name string, newStruct any) (*common.AdaptPlan, error) {
+	if _, err := sqlite.open(); err != nil {
+		return nil, err
+	}
+	return dbsql.PlanAdaptTable(ctx, sqlite, common.SqliteType, name, newStruct)
+}
+
+// DeleteTable delete a table
+func (sqlite *Sqlite) DeleteTable(name string) error {
+	return sqlite.DeleteTableContext(context.Background(), name)
+}
+
+// DeleteTableContext delete a table using context
+func (sqlite *Sqlite) DeleteTableContext(ctx context.Context, name string) error {
+	if _, err := sqlite.open(); err != nil {
+		return err
+	}
+	return dbsql.DeleteTableContext(ctx, sqlite, name)
+}
+
+// Insert insert record into table
+func (sqlite *Sqlite) Insert(name string, insert *common.Entries) ([][]any, error) {
+	return sqlite.InsertContext(context.Background(), name, insert)
+}
+
+// InsertContext insert record into table using context
+func (sqlite *Sqlite) InsertContext(ctx context.Context, name string, insert *common.Entries) ([][]any, error) {
+	return dbsql.BulkInsertContext(ctx, sqlite, common.SqliteType, name, insert)
+}
+
+// Upsert insert record into table or update the record with the same key
+func (sqlite *Sqlite) Upsert(name string, upsert *common.Entries) ([][]any, error) {
+	return sqlite.UpsertContext(context.Background(), name, upsert)
+}
+
+// UpsertContext insert or update record in table using context
+func (sqlite *Sqlite) UpsertContext(ctx context.Context, name string, upsert *common.Entries) ([][]any, error) {
+	return dbsql.UpsertContext(ctx, sqlite, common.SqliteType, name, upsert)
+}
+
+// Update update record in table
+func (sqlite *Sqlite) Update(name string, insert *common.Entries) ([][]any, int64, error) {
+	return sqlite.UpdateContext(context.Background(), name, insert)
+}
+
+// UpdateContext update record in table using context
+func (sqlite *Sqlite) UpdateContext(ctx context.Context, name string, insert *common.Entries) ([][]any, int64, error) {
+	return dbsql.UpdateContext(ctx, sqlite, common.SqliteType, name, insert)
+}
+
+// BatchUpdate update records of all value rows using one prepared statement
+func (sqlite *Sqlite) BatchUpdate(name string, update *common.Entries) ([]int64, error) {
+	return sqlite.BatchUpdateContext(context.Background(), name, update)
+}
+
+// BatchUpdateContext update records of all value rows using context
+func (sqlite *Sqlite) BatchUpdateContext(ctx context.Context, name string, update *common.Entries) ([]int64, error) {
+	return dbsql.BatchUpdateContext(ctx, sqlite, common.SqliteType, name, update)
+}
+
+// BatchDelete delete records of all value rows using one prepared statement
+func (sqlite *Sqlite) BatchDelete(name string, remove *common.Entries) ([]int64, error) {
+	return sqlite.BatchDeleteContext(context.Background(), name, remove)
+}
+
+// BatchDeleteContext delete records of all value rows using context
+func (sqlite *Sqlite) BatchDeleteContext(ctx context.Context, name string, remove *common.Entries) ([]int64, error) {
+	return dbsql.BatchDeleteContext(ctx, sqlite, common.SqliteType, name, remove)
+}
+
+// Batch batch SQL query in table
+func (sqlite *Sqlite) Batch(batch string) error {
+	return sqlite.BatchContext(context.Background(), batch)
+}
+
+// BatchContext batch SQL query in table using context
+func (sqlite *Sqlite) BatchContext(ctx context.Context, batch string) error {
+	if _, err := sqlite.open(); err != nil {
+		return err
+	}
+	return dbsql.BatchContext(ctx, sqlite, batch)
+}
+
+// BatchSelect batch SQL query in table with values returned
+func (sqlite *Sqlite) BatchSelect(batch string) ([][]interface{}, error) {
+	return sqlite.BatchSelectContext(context.Background(), batch)
+}
+
+// BatchSelectContext batch SQL query in table with values returned using context
+func (sqlite *Sqlite) BatchSelectContext(ctx context.Context, batch string) ([][]interface{}, error) {
+	if _, err := sqlite.open(); err != nil {
+		return nil, err
+	}
+	return dbsql.BatchSelectContext(ctx, sqlite, batch)
+}
+
+// BatchSelectFct batch SQL query in table with fct called
+func (sqlite *Sqlite) BatchSelectFct(search *common.Query, fct common.ResultFunction) error {
+	return sqlite.BatchSelectFctContext(context.Background(), search, fct)
+}
+
+// BatchSelectFctContext batch SQL query in table with fct called using context
+func (sqlite *Sqlite) BatchSelectFctContext(ctx context.Context, search *common.Query, fct common.ResultFunction) error {
+	db, err := sqlite.queryer()
+	if err != nil {
+		return err
+	}
+	defer sqlite.Close()
+
+	selectCmd, args, err := common.BindParameters(common.SqliteType, search.Search, search.Parameters)
+	if err != nil {
+		return err
+	}
+	log.Log.Debugf("Query: %s", selectCmd)
+	rows, err := db.QueryContext(ctx, selectCmd, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if search.DataStruct == nil {
+		_, err = search.ParseRows(rows, fct)
+	} else {
+		ti := common.CreateInterface(search.DataStruct, search.Fields)
+		search.TypeInfo = ti
+		_, err = search.ParseStruct(rows, fct)
+	}
+	return err
+}
+
+// StartTransaction start transaction
+func (sqlite *Sqlite) StartTransaction() (*sql.Tx, context.Context, error) {
+	db, err := sqlite.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	if sqlite.tx != nil && sqlite.IsTransaction() {
+		return sqlite.tx, sqlite.ctx, nil
+	}
+	sqlite.ctx = context.Background()
+	sqlite.tx, err = db.BeginTx(sqlite.ctx, nil)
+	if err != nil {
+		sqlite.ctx = nil
+		sqlite.tx = nil
+		return nil, nil, err
+	}
+	log.Log.Debugf("Transaction tx=%p", sqlite.tx)
+	return sqlite.tx, sqlite.ctx, nil
+}
+
+// BeginTx begin a transaction handle using its own database connection
+func (sqlite *Sqlite) BeginTx(ctx context.Context, opts *common.TxOptions) (common.Transaction, error) {
+	// keep the database of the handler open, in-memory databases are
+	// removed if the last connection is closed
+	if _, err := sqlite.open(); err != nil {
+		return nil, err
+	}
+	return dbsql.BeginTx(ctx, sqlite, common.SqliteType, opts)
+}
+
+// Commit commit the transaction
+func (sqlite *Sqlite) Commit() error {
+	sqlite.Transaction = false
+	return sqlite.EndTransaction(true)
+}
+
+// Rollback rollback the transaction
+func (sqlite *Sqlite) Rollback() error {
+	sqlite.Transaction = false
+	return sqlite.EndTransaction(false)
+}
+
+// Stream streaming data from a field
+func (sqlite *Sqlite) Stream(search *common.Query, sf common.StreamFunction) error {
+	return sqlite.StreamContext(context.Background(), search, sf)
+}
+
+// StreamContext streaming data from a field using context
+func (sqlite *Sqlite) StreamContext(ctx context.Context, search *common.Query, sf common.StreamFunction) error {
+	db, err := sqlite.queryer()
+	if err != nil {
+		return err
+	}
+	defer sqlite.Close()
+	return dbsql.StreamBlocks(ctx, db, common.SqliteType, search, sf)
+}
@@ -0,0 +1,198 @@
+//go:build flynn_nosqlite
+// +build flynn_nosqlite
+
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package sqlite
+
+import (
+	"context"
+	"math"
+
+	"github.com/tknie/errorrepo"
+	"github.com/tknie/flynn/common"
+)
+
+type sqlite struct {
+	common.CommonDatabase
+}
+
+// NewInstance create new SQLite reference instance
+func NewInstance(id common.RegDbID, reference *common.Reference, password string) (common.Database, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// New create new SQLite reference instance
+func New(id common.RegDbID, url string) (common.Database, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// FreeHandler don't use the driver anymore
+func (ada *sqlite) FreeHandler() {
+}
+
+func (ada *sqlite) Clone() common.Database {
+	newSqlite := &sqlite{}
+	*newSqlite = *ada
+	return newSqlite
+}
+
+// SetCredentials set credentials to connect to database
+func (ada *sqlite) SetCredentials(user, password string) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// ID current id used
+func (ada *sqlite) ID() common.RegDbID {
+	return math.MaxUint64
+}
+
+// URL current URL used
+func (ada *sqlite) URL() string {
+	return ""
+}
+
+// Maps database maps, tables or views
+func (ada *sqlite) Maps() ([]string, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// Ping create short test database connection
+func (ada *sqlite) Ping() error {
+	return errorrepo.NewError("DB065535")
+}
+
+// Open open the database connection
+func (ada *sqlite) Open() (any, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// Close close the database connection
+func (ada *sqlite) Close() {
+}
+
+// Insert insert record into table
+func (ada *sqlite) Insert(name string, insert *common.Entries) ([][]any, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// Update update record in table
+func (ada *sqlite) Update(name string, insert *common.Entries) ([][]any, int64, error) {
+	return nil, 0, errorrepo.NewError("DB065535")
+}
+
+// Delete Delete database records
+func (ada *sqlite) Delete(name string, remove *common.Entries) (int64, error) {
+	return 0, errorrepo.NewError("DB065535")
+}
+
+// GetTableColumn get table columne names
+func (ada *sqlite) GetTableColumn(tableName string) ([]string, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// Query query database records with search or SELECT
+func (ada *sqlite) Query(search *common.Query, f common.ResultFunction) (*common.Result, error) {
+	search.Driver = common.SqliteType
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// CreateTable create a new table
+func (ada *sqlite) CreateTable(string, any) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// AdaptTable adapt a new table
+func (ada *sqlite) AdaptTable(string, any) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// DeleteTable delete a table
+func (ada *sqlite) DeleteTable(string) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// Batch batch SQL query in table
+func (ada *sqlite) Batch(batch string) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// BatchSelect batch SQL query in table with values returned
+func (ada *sqlite) BatchSelect(batch string) ([][]interface{}, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// BatchSelectFct batch SQL query in table with fct called
+func (ada *sqlite) BatchSelectFct(*common.Query, common.ResultFunction) error {
+	return errorrepo.NewError("DB065535")
+}
+
+func (ada *sqlite) BeginTransaction() error {
+	return errorrepo.NewError("DB065535")
+}
+
+func (ada *sqlite) Commit() error {
+	return errorrepo.NewError("DB065535")
+}
+
+func (ada *sqlite) Rollback() error {
+	return errorrepo.NewError("DB065535")
+}
+
+func (ada *sqlite) Stream(search *common.Query, sf common.StreamFunction) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// PingContext create short test database connection
+func (ada *sqlite) PingContext(context.Context) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// InsertContext insert record into table
+func (ada *sqlite) InsertContext(context.Context, string, *common.Entries) ([][]any, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// UpdateContext update record in table
+func (ada *sqlite) UpdateContext(context.Context, string, *common.Entries) ([][]any, int64, error) {
+	return nil, 0, errorrepo.NewError("DB065535")
+}
+
+// DeleteContext Delete database records
+func (ada *sqlite) DeleteContext(context.Context, string, *common.Entries) (int64, error) {
+	return 0, errorrepo.NewError("DB065535")
+}
+
+// QueryContext query database records with search or SELECT
+func (ada *sqlite) QueryContext(context.Context, *common.Query, common.ResultFunction) (*common.Result, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// BatchContext batch SQL query in table
+func (ada *sqlite) BatchContext(context.Context, string) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// BatchSelectContext batch SQL query in table with values returned
+func (ada *sqlite) BatchSelectContext(context.Context, string) ([][]interface{}, error) {
+	return nil, errorrepo.NewError("DB065535")
+}
+
+// BatchSelectFctContext batch SQL query in table with fct called
+func (ada *sqlite) BatchSelectFctContext(context.Context, *common.Query, common.ResultFunction) error {
+	return errorrepo.NewError("DB065535")
+}
+
+// StreamContext streaming data from a field
+func (ada *sqlite) StreamContext(context.Context, *common.Query, common.StreamFunction) error {
+	return errorrepo.NewError("DB065535")
+}
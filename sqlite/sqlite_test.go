@@ -0,0 +1,1266 @@
+//go:build !flynn_nosqlite
+// +build !flynn_nosqlite
+
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/log"
+)
+
+var logRus = logrus.StandardLogger()
+var once = new(sync.Once)
+
+type sqliteRecord struct {
+	ID       string `flynn:"ID::10"`
+	Name     string
+	Counter  int64
+	Flag     bool
+	Created  time.Time
+	Document []byte
+}
+
+func InitLog(t *testing.T) {
+	once.Do(startLog)
+	log.Log.Debugf("TEST: %s", t.Name())
+}
+
+func startLog() {
+	fmt.Println("Init logging")
+	fileName := "db.trace.log"
+	level := os.Getenv("ENABLE_DB_DEBUG")
+	logLevel := logrus.WarnLevel
+	switch level {
+	case "debug", "1":
+		log.SetDebugLevel(true)
+		logLevel = logrus.DebugLevel
+	case "info", "2":
+		log.SetDebugLevel(false)
+		logLevel = logrus.InfoLevel
+	default:
+	}
+	logRus.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp:   true,
+		TimestampFormat: "2006-01-02T15:04:05",
+	})
+	logRus.SetLevel(logLevel)
+	p := os.Getenv("LOGPATH")
+	if p == "" {
+		p = os.TempDir()
+	}
+	f, err := os.OpenFile(p+"/"+fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		fmt.Println("Error opening log:", err)
+		return
+	}
+	logRus.SetOutput(f)
+	logRus.Infof("Init logrus")
+	log.Log = logRus
+	fmt.Println("Logging running")
+}
+
+func sqliteInstance(t *testing.T, id common.RegDbID, url string) *Sqlite {
+	db, err := New(id, url)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	common.RegisterDbClient(db)
+	t.Cleanup(func() { id.FreeHandler() })
+	return db.(*Sqlite)
+}
+
+func TestSqliteReference(t *testing.T) {
+	InitLog(t)
+
+	ref, _, err := common.NewReference("sqlite:///tmp/flynn.db?_pragma=foreign_keys(1)")
+	assert.NoError(t, err)
+	assert.Equal(t, &common.Reference{Driver: common.SqliteType, Database: "/tmp/flynn.db",
+		Options: []string{"_pragma=foreign_keys(1)"}}, ref)
+	ref, _, err = common.NewReference("sqlite://:memory:")
+	assert.NoError(t, err)
+	assert.Equal(t, &common.Reference{Driver: common.SqliteType, Database: ":memory:"}, ref)
+	_, _, err = common.NewReference("sqlite://")
+	assert.Error(t, err)
+	assert.Contains(t, common.Drivers(), "sqlite")
+	assert.Equal(t, "SQLite", common.SqliteType.String())
+}
+
+func TestSqliteStruct(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1001, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	err := sqlite.CreateTable("SqliteStruct", &sqliteRecord{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	m, err := sqlite.Maps()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"SqliteStruct"}, m)
+	c, err := sqlite.GetTableColumn("SqliteStruct")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "counter", "flag", "created", "document"}, c)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	records := [][]any{}
+	for i := 1; i <= 3; i++ {
+		records = append(records, []any{&sqliteRecord{ID: fmt.Sprintf("ID%03d", i),
+			Name: fmt.Sprintf("Name %d", i), Counter: int64(i * 10), Flag: i%2 == 0,
+			Created: now, Document: []byte{byte(i), 1, 2}}})
+	}
+	_, err = sqlite.Insert("SqliteStruct", &common.Entries{Fields: []string{"*"},
+		DataStruct: &sqliteRecord{}, Values: records})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	result := make([]*sqliteRecord, 0)
+	_, err = sqlite.Query(&common.Query{TableName: "SqliteStruct", DataStruct: &sqliteRecord{},
+		Fields: []string{"*"}, Order: []string{"ID:ASC"}},
+		func(search *common.Query, result2 *common.Result) error {
+			record := *result2.Data.(*sqliteRecord)
+			result = append(result, &record)
+			return nil
+		})
+	assert.NoError(t, err)
+	if assert.Len(t, result, 3) {
+		assert.Equal(t, "ID002", result[1].ID)
+		assert.Equal(t, "Name 2", result[1].Name)
+		assert.Equal(t, int64(20), result[1].Counter)
+		assert.True(t, result[1].Flag)
+		assert.False(t, result[2].Flag)
+		assert.Equal(t, now, result[1].Created.UTC())
+		assert.Equal(t, []byte{2, 1, 2}, result[1].Document)
+	}
+
+	_, n, err := sqlite.Update("SqliteStruct", &common.Entries{Fields: []string{"Name", "ID"},
+		Update: []string{"ID"}, Values: [][]any{{"Changed", "ID003"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	n, err = sqlite.Delete("SqliteStruct", &common.Entries{Fields: []string{"ID"},
+		Values: [][]any{{"ID001"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	names := make([]any, 0)
+	_, err = sqlite.Query(&common.Query{TableName: "SqliteStruct",
+		Fields: []string{"Name"}, Order: []string{"ID:ASC"}},
+		func(search *common.Query, result *common.Result) error {
+			names = append(names, result.Rows[0])
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"Name 2", "Changed"}, names)
+
+	err = sqlite.DeleteTable("SqliteStruct")
+	assert.NoError(t, err)
+}
+
+func TestSqliteTransaction(t *testing.T) {
+	InitLog(t)
+
+	url := "sqlite://" + filepath.Join(t.TempDir(), "flynn.db")
+	sqlite := sqliteInstance(t, 1002, url)
+	if sqlite == nil {
+		return
+	}
+	columns := []*common.Column{{Name: "Id", DataType: common.Alpha, Length: 8},
+		{Name: "Name", DataType: common.Alpha, Length: 20}}
+	err := sqlite.CreateTable("SqliteTransaction", columns)
+	if !assert.NoError(t, err) {
+		return
+	}
+	insert := &common.Entries{Fields: []string{"Id", "Name"},
+		Values: [][]any{{"T1", "Rollback"}}}
+
+	err = sqlite.BeginTransaction()
+	assert.NoError(t, err)
+	_, err = sqlite.Insert("SqliteTransaction", insert)
+	assert.NoError(t, err)
+	assert.NoError(t, sqlite.Rollback())
+
+	insert.Values = [][]any{{"T2", "Commit"}}
+	err = sqlite.BeginTransaction()
+	assert.NoError(t, err)
+	_, err = sqlite.Insert("SqliteTransaction", insert)
+	assert.NoError(t, err)
+	assert.NoError(t, sqlite.Commit())
+
+	data, err := sqlite.BatchSelect("SELECT Id, Name FROM SqliteTransaction")
+	assert.NoError(t, err)
+	assert.Len(t, data, 1)
+	if len(data) == 1 {
+		assert.Equal(t, sql.NullString{String: "T2", Valid: true}, data[0][0])
+	}
+}
+
+func TestSqliteTxHandle(t *testing.T) {
+	InitLog(t)
+
+	url := "sqlite://" + filepath.Join(t.TempDir(), "flynn.db")
+	sqlite := sqliteInstance(t, 1004, url)
+	if sqlite == nil {
+		return
+	}
+	columns := []*common.Column{{Name: "Id", DataType: common.Alpha, Length: 8},
+		{Name: "Name", DataType: common.Alpha, Length: 20}}
+	err := sqlite.CreateTable("SqliteTxHandle", columns)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx := context.Background()
+	tx, err := sqlite.ID().Begin(ctx, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = tx.Insert("SqliteTxHandle", &common.Entries{Fields: []string{"Id", "Name"},
+		Values: [][]any{{"H1", "Rollback"}}})
+	assert.NoError(t, err)
+	ids := make([]string, 0)
+	_, err = tx.Query(&common.Query{TableName: "SqliteTxHandle", Fields: []string{"Id"}},
+		func(search *common.Query, result *common.Result) error {
+			ids = append(ids, fmt.Sprint(result.Rows[0]))
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"H1"}, ids)
+	assert.NoError(t, tx.Rollback())
+	assert.Error(t, tx.Commit())
+
+	tx, err = sqlite.ID().Begin(ctx, &common.TxOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = tx.Insert("SqliteTxHandle", &common.Entries{Fields: []string{"Id", "Name"},
+		Values: [][]any{{"H2", "Commit"}}})
+	assert.NoError(t, err)
+	_, count, err := tx.Update("SqliteTxHandle", &common.Entries{Fields: []string{"Id", "Name"},
+		Update: []string{"Id"}, Values: [][]any{{"H2", "Updated"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.NoError(t, tx.Commit())
+	_, err = tx.Insert("SqliteTxHandle", &common.Entries{Fields: []string{"Id", "Name"},
+		Values: [][]any{{"H3", "Finished"}}})
+	assert.Error(t, err)
+
+	data, err := sqlite.BatchSelect("SELECT Id, Name FROM SqliteTxHandle")
+	assert.NoError(t, err)
+	if assert.Len(t, data, 1) {
+		assert.Equal(t, sql.NullString{String: "H2", Valid: true}, data[0][0])
+		assert.Equal(t, sql.NullString{String: "Updated", Valid: true}, data[0][1])
+	}
+}
+
+func TestSqliteWithTransaction(t *testing.T) {
+	InitLog(t)
+
+	url := "sqlite://" + filepath.Join(t.TempDir(), "flynn.db")
+	sqlite := sqliteInstance(t, 1005, url)
+	if sqlite == nil {
+		return
+	}
+	columns := []*common.Column{{Name: "Id", DataType: common.Alpha, Length: 8}}
+	err := sqlite.CreateTable("SqliteSavepoint", columns)
+	if !assert.NoError(t, err) {
+		return
+	}
+	insert := func(id string) func(tx *common.Tx) error {
+		return func(tx *common.Tx) error {
+			_, err := tx.Insert("SqliteSavepoint", &common.Entries{Fields: []string{"Id"},
+				Values: [][]any{{id}}})
+			return err
+		}
+	}
+	errAbort := fmt.Errorf("abort")
+	err = sqlite.ID().WithTransaction(context.Background(), func(tx *common.Tx) error {
+		if err := insert("S1")(tx); err != nil {
+			return err
+		}
+		err := tx.WithTransaction(func(tx *common.Tx) error {
+			if err := insert("S2")(tx); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+		return tx.WithTransaction(insert("S3"))
+	})
+	assert.NoError(t, err)
+
+	data, err := sqlite.BatchSelect("SELECT Id FROM SqliteSavepoint ORDER BY Id")
+	assert.NoError(t, err)
+	if assert.Len(t, data, 2) {
+		assert.Equal(t, sql.NullString{String: "S1", Valid: true}, data[0][0])
+		assert.Equal(t, sql.NullString{String: "S3", Valid: true}, data[1][0])
+	}
+}
+
+func TestSqliteStream(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1003, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	columns := []*common.Column{{Name: "Id", DataType: common.Alpha, Length: 8},
+		{Name: "Data", DataType: common.BLOB, Length: 4096}}
+	err := sqlite.CreateTable("SqliteStream", columns)
+	if !assert.NoError(t, err) {
+		return
+	}
+	blob := bytes.Repeat([]byte("0123456789"), 105)
+	_, err = sqlite.Insert("SqliteStream", &common.Entries{Fields: []string{"Id", "Data"},
+		Values: [][]any{{"S1", blob}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var buffer bytes.Buffer
+	count := 0
+	err = sqlite.Stream(&common.Query{TableName: "SqliteStream", Fields: []string{"Data"},
+		Search: "Id='S1'", Blocksize: 100},
+		func(search *common.Query, stream *common.Stream) error {
+			buffer.Write(stream.Data)
+			count++
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, 11, count)
+	assert.Equal(t, blob, buffer.Bytes())
+	// transaction handles stream block by block, too
+	tx, err := sqlite.ID().Begin(context.Background(), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tx.Rollback()
+	buffer.Reset()
+	count = 0
+	err = tx.Stream(&common.Query{TableName: "SqliteStream", Fields: []string{"Data"},
+		Search: "Id='S1'", Blocksize: 100},
+		func(search *common.Query, stream *common.Stream) error {
+			assert.LessOrEqual(t, len(stream.Data), 100)
+			buffer.Write(stream.Data)
+			count++
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, 11, count)
+	assert.Equal(t, blob, buffer.Bytes())
+}
+
+func TestSqliteParameters(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1006, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	columns := []*common.Column{{Name: "Id", DataType: common.Alpha, Length: 8},
+		{Name: "Name", DataType: common.Alpha, Length: 40},
+		{Name: "Data", DataType: common.BLOB, Length: 100}}
+	err := sqlite.CreateTable("SqliteParameters", columns)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = sqlite.Insert("SqliteParameters", &common.Entries{Fields: []string{"Id", "Name", "Data"},
+		Values: [][]any{{"P1", "O'Brien", []byte("abc")}, {"P2", "Smith", []byte("def")}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	query := func(search string, parameters ...any) []any {
+		ids := make([]any, 0)
+		_, err := sqlite.Query(&common.Query{TableName: "SqliteParameters", Fields: []string{"Id"},
+			Search: search, Parameters: parameters, Order: []string{"Id"}},
+			func(search *common.Query, result *common.Result) error {
+				ids = append(ids, result.Rows[0])
+				return nil
+			})
+		assert.NoError(t, err, search)
+		return ids
+	}
+	assert.Equal(t, []any{"P1"}, query("Name = ?", "O'Brien"))
+	assert.Empty(t, query("Name = ?", "x' OR '1'='1"))
+	assert.Empty(t, query("Id = $1", "P1' OR Id <> '"))
+	assert.Equal(t, []any{"P2"}, query("Name = :name AND Id <> ?", sql.Named("name", "Smith"), "P1"))
+
+	names := make([]any, 0)
+	err = sqlite.BatchSelectFct(&common.Query{Search: "SELECT Name FROM SqliteParameters WHERE Id = ?",
+		Parameters: []any{"P2"}},
+		func(search *common.Query, result *common.Result) error {
+			names = append(names, result.Rows[0])
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"Smith"}, names)
+
+	var buffer bytes.Buffer
+	err = sqlite.Stream(&common.Query{TableName: "SqliteParameters", Fields: []string{"Data"},
+		Search: "Id = ?", Parameters: []any{"P1"}, Blocksize: 2},
+		func(search *common.Query, stream *common.Stream) error {
+			buffer.Write(stream.Data)
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("abc"), buffer.Bytes())
+
+	_, err = sqlite.Query(&common.Query{TableName: "SqliteParameters", Fields: []string{"Id"},
+		Search: "Id = ? AND Name = ?", Parameters: []any{"P1"}},
+		func(search *common.Query, result *common.Result) error { return nil })
+	assert.Error(t, err)
+}
+
+type sqliteAuthor struct {
+	ID   string
+	Name string
+}
+
+type sqliteBook struct {
+	ID     string
+	Title  string
+	Author *sqliteAuthor `flynn:"Author:join=a"`
+}
+
+func TestSqliteJoin(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1007, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	for _, batch := range []string{"CREATE TABLE Authors (ID VARCHAR(8), Name VARCHAR(40))",
+		"CREATE TABLE Books (ID VARCHAR(8), Title VARCHAR(40), AuthorID VARCHAR(8))",
+		"INSERT INTO Authors VALUES ('A1', 'Kafka')",
+		"INSERT INTO Books VALUES ('B1', 'Der Process', 'A1'), ('B2', 'Anonymous', NULL), ('B3', 'Das Schloss', 'A1')"} {
+		if !assert.NoError(t, sqlite.Batch(batch)) {
+			return
+		}
+	}
+	books := make([]sqliteBook, 0)
+	_, err := sqlite.Query(&common.Query{TableName: "Books", DataStruct: &sqliteBook{}, Fields: []string{"*"},
+		Joins: []common.JoinTable{{Type: common.LeftJoin, TableName: "Authors", Alias: "a", On: "a.ID = tn.AuthorID"}},
+		Order: []string{"tn.ID"}},
+		func(search *common.Query, result *common.Result) error {
+			book := *result.Data.(*sqliteBook)
+			if book.Author != nil {
+				author := *book.Author
+				book.Author = &author
+			}
+			books = append(books, book)
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []sqliteBook{{ID: "B1", Title: "Der Process", Author: &sqliteAuthor{ID: "A1", Name: "Kafka"}},
+		{ID: "B2", Title: "Anonymous"},
+		{ID: "B3", Title: "Das Schloss", Author: &sqliteAuthor{ID: "A1", Name: "Kafka"}}}, books)
+
+	titles := make([]any, 0)
+	_, err = sqlite.Query(&common.Query{TableName: "Books", Fields: []string{"tn.Title", "a.Name"},
+		Joins: []common.JoinTable{{TableName: "Authors", Alias: "a", On: "a.ID = tn.AuthorID"}},
+		Where: common.Eq("a.Name", "Kafka"), Order: []string{"tn.ID"}},
+		func(search *common.Query, result *common.Result) error {
+			titles = append(titles, result.Rows[0])
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"Der Process", "Das Schloss"}, titles)
+}
+
+func TestSqlitePage(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1008, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	for _, batch := range []string{"CREATE TABLE Pages (ID INTEGER, Name VARCHAR(40))",
+		"INSERT INTO Pages VALUES (1, 'A'), (2, 'B'), (3, 'C'), (4, 'D'), (5, 'E')"} {
+		if !assert.NoError(t, sqlite.Batch(batch)) {
+			return
+		}
+	}
+	for _, page := range []*common.Page{{Size: 2, Total: true}, {Size: 2, Keyset: true, Total: true}} {
+		query := &common.Query{TableName: "Pages", Fields: []string{"ID", "Name"},
+			Where: common.Gt("ID", 1), Sort: []common.SortField{common.Asc("ID")}, Page: page}
+		names := make([]any, 0)
+		for pages := 0; pages < 5; pages++ {
+			result, err := sqlite.ID().Query(query, func(search *common.Query, result *common.Result) error {
+				names = append(names, result.Rows[1])
+				return nil
+			})
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, int64(4), result.Total)
+			if result.NextToken == "" {
+				break
+			}
+			page.Token = result.NextToken
+		}
+		assert.Equal(t, []any{"B", "C", "D", "E"}, names)
+	}
+
+	_, err := sqlite.ID().Query(&common.Query{TableName: "Pages", Fields: []string{"ID"},
+		Page: &common.Page{Size: 2, Keyset: true}},
+		func(search *common.Query, result *common.Result) error { return nil })
+	assert.ErrorContains(t, err, "DB000078")
+}
+
+func TestSqlitePageTypedKeys(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1023, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	for _, batch := range []string{"CREATE TABLE TypedPages (Code BLOB, Name VARCHAR(40))",
+		"INSERT INTO TypedPages VALUES (x'01', 'A'), (x'02', 'B'), (x'03', 'C'), (x'04', 'D'), (x'05', 'E')"} {
+		if !assert.NoError(t, sqlite.Batch(batch)) {
+			return
+		}
+	}
+	query := &common.Query{TableName: "TypedPages", Fields: []string{"Code", "Name"},
+		Sort: []common.SortField{common.Asc("Code")}, Page: &common.Page{Size: 2, Keyset: true}}
+	names := make([]any, 0)
+	for pages := 0; pages < 5; pages++ {
+		result, err := sqlite.ID().Query(query, func(search *common.Query, result *common.Result) error {
+			names = append(names, result.Rows[1])
+			return nil
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		if result.NextToken == "" {
+			break
+		}
+		query.Page.Token = result.NextToken
+	}
+	assert.Equal(t, []any{"A", "B", "C", "D", "E"}, names)
+}
+
+type sqliteStock struct {
+	Item   string `flynn:"Item:key"`
+	Amount int64
+}
+
+func TestSqliteUpsert(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1009, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	if !assert.NoError(t, sqlite.Batch("CREATE TABLE Stock (Item VARCHAR(10) PRIMARY KEY, Amount INTEGER)")) {
+		return
+	}
+	returning, err := sqlite.ID().Upsert("Stock", &common.Entries{Fields: []string{"Item", "Amount"},
+		Update: []string{"Item"}, Values: [][]any{{"A", 1}, {"B", 2}}, Returning: []string{"Amount"}})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"1"}, {"2"}}, returning)
+
+	returning, err = sqlite.ID().Upsert("Stock", &common.Entries{DataStruct: &sqliteStock{},
+		Fields: []string{"*"}, Values: [][]any{{&sqliteStock{Item: "A", Amount: 5}}},
+		Returning: []string{"Item", "Amount"}})
+	assert.NoError(t, err)
+	if assert.Len(t, returning, 1) {
+		assert.Equal(t, &sqliteStock{Item: "A", Amount: 5}, returning[0][0])
+	}
+
+	amounts := make([]any, 0)
+	_, err = sqlite.Query(&common.Query{TableName: "Stock", Fields: []string{"Amount"},
+		Order: []string{"Item"}}, func(search *common.Query, result *common.Result) error {
+		amounts = append(amounts, result.Rows[0])
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"5", "2"}, amounts)
+
+	_, err = sqlite.ID().Upsert("Stock", &common.Entries{Fields: []string{"Item", "Amount"},
+		Values: [][]any{{"A", 1}}})
+	assert.Error(t, err)
+}
+
+func TestSqliteBulkInsert(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1010, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	if !assert.NoError(t, sqlite.Batch("CREATE TABLE Stock (Item VARCHAR(10) PRIMARY KEY, Amount INTEGER)")) {
+		return
+	}
+	batchSize := common.BulkBatchSize
+	defer func() { common.BulkBatchSize = batchSize }()
+	common.BulkBatchSize = 3
+
+	records := make([][]any, 0)
+	for i := 1; i <= 10; i++ {
+		records = append(records, []any{&sqliteStock{Item: fmt.Sprintf("I%02d", i), Amount: int64(i)}})
+	}
+	_, err := sqlite.ID().Insert("Stock", &common.Entries{DataStruct: &sqliteStock{},
+		Fields: []string{"*"}, Values: records, Bulk: true})
+	assert.NoError(t, err)
+	_, err = sqlite.ID().Insert("Stock", &common.Entries{Fields: []string{"Item", "Amount"}, Bulk: true,
+		Values: [][]any{{map[string]any{"Item": "M01", "Amount": 11}, map[string]any{"Item": "M02"}}}})
+	assert.NoError(t, err)
+
+	count := 0
+	_, err = sqlite.Query(&common.Query{TableName: "Stock", Fields: []string{"Item"}},
+		func(search *common.Query, result *common.Result) error {
+			count++
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, 12, count)
+
+	_, err = sqlite.ID().Insert("Stock", &common.Entries{Fields: []string{"Item", "Amount"}, Bulk: true,
+		Values: [][]any{{"I01", 1}}})
+	assert.Error(t, err)
+	_, err = sqlite.ID().Insert("Stock", &common.Entries{Fields: []string{"Item", "Amount"}, Bulk: true,
+		Values: [][]any{{"X01", 1}}, Returning: []string{"Item"}})
+	assert.Error(t, err)
+}
+
+func TestSqliteBatchUpdate(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1011, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	for _, batch := range []string{"CREATE TABLE Stock (Item VARCHAR(10) PRIMARY KEY, Amount INTEGER)",
+		"INSERT INTO Stock VALUES ('A', 1), ('B', 2), ('C', 3)"} {
+		if !assert.NoError(t, sqlite.Batch(batch)) {
+			return
+		}
+	}
+	rowsAffected, err := sqlite.ID().BatchUpdate("Stock", &common.Entries{DataStruct: &sqliteStock{},
+		Fields: []string{"*"}, Update: []string{"Item"},
+		Values: [][]any{{&sqliteStock{Item: "A", Amount: 10}}, {&sqliteStock{Item: "X", Amount: 20}},
+			{&sqliteStock{Item: "C", Amount: 30}}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 0, 1}, rowsAffected)
+
+	rowsAffected, err = sqlite.ID().BatchDelete("Stock", &common.Entries{Fields: []string{"Item"},
+		Values: [][]any{{"B"}, {"Y"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 0}, rowsAffected)
+
+	err = sqlite.ID().WithTransaction(context.Background(), func(tx *common.Tx) error {
+		rowsAffected, err = tx.BatchDelete("Stock", &common.Entries{Criteria: "Amount > 20"})
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, rowsAffected)
+
+	amounts := make([]any, 0)
+	_, err = sqlite.Query(&common.Query{TableName: "Stock", Fields: []string{"Amount"}},
+		func(search *common.Query, result *common.Result) error {
+			amounts = append(amounts, result.Rows[0])
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"10"}, amounts)
+}
+
+type sqliteCounter struct {
+	ID     int64 `flynn:"ID:key"`
+	Name   string
+	Amount int64
+}
+
+func TestSqliteReturning(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1012, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	if !assert.NoError(t, sqlite.Batch("CREATE TABLE Counters (ID INTEGER PRIMARY KEY AUTOINCREMENT, Name VARCHAR(10), Amount INTEGER)")) {
+		return
+	}
+	returning, err := sqlite.ID().Insert("Counters", &common.Entries{Fields: []string{"Name", "Amount"},
+		Values: [][]any{{"A", 1}, {"B", nil}}, Returning: []string{"ID", "Amount"}})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"1", "1"}, {"2", nil}}, returning)
+
+	returning, err = sqlite.ID().Insert("Counters", &common.Entries{DataStruct: &sqliteCounter{},
+		Fields: []string{"Name", "Amount"}, Values: [][]any{{&sqliteCounter{Name: "C", Amount: 3}}},
+		Returning: []string{"ID", "Name"}})
+	assert.NoError(t, err)
+	if assert.Len(t, returning, 1) {
+		assert.Equal(t, &sqliteCounter{ID: 3, Name: "C"}, returning[0][0])
+	}
+
+	returning, rowsAffected, err := sqlite.ID().Update("Counters", &common.Entries{Fields: []string{"Amount"},
+		Update: []string{"ID < 3"}, Values: [][]any{{10}}, Returning: []string{"ID", "Amount"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), rowsAffected)
+	assert.Equal(t, [][]any{{"1", "10"}, {"2", "10"}}, returning)
+
+	returning, rowsAffected, err = sqlite.ID().Update("Counters", &common.Entries{DataStruct: &sqliteCounter{},
+		Fields: []string{"*"}, Update: []string{"ID"}, Values: [][]any{{&sqliteCounter{ID: 3, Name: "D", Amount: 4}}},
+		Returning: []string{"Name", "Amount"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+	if assert.Len(t, returning, 1) {
+		assert.Equal(t, &sqliteCounter{Name: "D", Amount: 4}, returning[0][0])
+	}
+}
+
+type sqliteOrder struct {
+	Shop   string `flynn:"Shop:key"`
+	Number int64  `flynn:"Number:key"`
+	Amount int64
+}
+
+func TestSqliteUpdateStruct(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1013, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	if !assert.NoError(t, sqlite.Batch("CREATE TABLE Orders (Shop VARCHAR(10), Number INTEGER, Amount INTEGER, PRIMARY KEY (Shop, Number))")) {
+		return
+	}
+	_, err := sqlite.ID().Insert("Orders", &common.Entries{DataStruct: &sqliteOrder{}, Fields: []string{"*"},
+		Values: [][]any{{&sqliteOrder{"A", 1, 1}}, {&sqliteOrder{"A", 2, 2}}, {&sqliteOrder{"B", 1, 3}}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	rowsAffected, err := sqlite.ID().UpdateStruct("Orders", &sqliteOrder{"A", 1, 10}, &sqliteOrder{"B", 1, 30}, &sqliteOrder{"C", 1, 40})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), rowsAffected)
+
+	rowsAffected, err = sqlite.ID().DeleteStruct("Orders", &sqliteOrder{Shop: "A", Number: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+
+	orders := make([]sqliteOrder, 0)
+	_, err = sqlite.Query(&common.Query{TableName: "Orders", DataStruct: &sqliteOrder{}, Fields: []string{"*"},
+		Order: []string{"Shop", "Number"}}, func(search *common.Query, result *common.Result) error {
+		orders = append(orders, *result.Data.(*sqliteOrder))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []sqliteOrder{{"A", 1, 10}, {"B", 1, 30}}, orders)
+
+	_, err = sqlite.ID().UpdateStruct("Orders", &sqliteStock{Item: "A"})
+	assert.Error(t, err)
+}
+
+func TestSqliteUpdateCriteria(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1014, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	if !assert.NoError(t, sqlite.Batch("CREATE TABLE Stock (Item VARCHAR(10) PRIMARY KEY, Amount INTEGER)")) {
+		return
+	}
+	_, err := sqlite.ID().Insert("Stock", &common.Entries{Fields: []string{"Item", "Amount"},
+		Values: [][]any{{"O'Neil", 1}, {"B", 2}, {"C", 30}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, rowsAffected, err := sqlite.ID().Update("Stock", &common.Entries{Fields: []string{"Item", "Amount"},
+		Update: []string{"Item"}, Values: [][]any{{"O'Neil", 10}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+
+	_, rowsAffected, err = sqlite.ID().Update("Stock", &common.Entries{Fields: []string{"Amount"},
+		Update: []string{"Item <> 'O''Neil'"}, Criteria: "Amount < ?", Parameters: []any{20},
+		Values: [][]any{{5}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+
+	amounts := make([]any, 0)
+	_, err = sqlite.Query(&common.Query{TableName: "Stock", Fields: []string{"Amount"},
+		Order: []string{"Item"}}, func(search *common.Query, result *common.Result) error {
+		amounts = append(amounts, result.Rows[0])
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"5", "30", "10"}, amounts)
+
+	rowsAffected, err = sqlite.ID().Delete("Stock", &common.Entries{Criteria: "Amount > :amount",
+		Parameters: []any{sql.Named("amount", 7)}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), rowsAffected)
+
+	// unknown keys are rejected instead of widening the condition
+	_, _, err = sqlite.ID().Update("Stock", &common.Entries{Fields: []string{"Amount", "Item"},
+		Update: []string{"Itme"}, Values: [][]any{{1, "B"}}})
+	assert.Error(t, err)
+
+	// LIKE patterns are bound, quotes are no SQL syntax
+	rowsAffected, err = sqlite.ID().Delete("Stock", &common.Entries{Fields: []string{"%Item"},
+		Values: [][]any{{"B' OR 'x'='x"}, {"B%"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+}
+
+type sqliteVersioned struct {
+	ID      int64 `flynn:"ID:key"`
+	Name    string
+	Version int64 `flynn:"version:version"`
+}
+
+func TestSqliteVersion(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1015, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	if !assert.NoError(t, sqlite.CreateTable("Versioned", &sqliteVersioned{})) {
+		return
+	}
+	record := &sqliteVersioned{ID: 1, Name: "A"}
+	_, err := sqlite.ID().Insert("Versioned", &common.Entries{DataStruct: record, Fields: []string{"*"},
+		Values: [][]any{{record}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), record.Version)
+
+	stale := *record
+	record.Name = "B"
+	_, rowsAffected, err := sqlite.ID().Update("Versioned", &common.Entries{DataStruct: record, Fields: []string{"*"},
+		Update: []string{"ID"}, Values: [][]any{{record}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+	assert.Equal(t, int64(2), record.Version)
+
+	stale.Name = "C"
+	_, _, err = sqlite.ID().Update("Versioned", &common.Entries{DataStruct: &stale, Fields: []string{"*"},
+		Update: []string{"ID"}, Values: [][]any{{&stale}}})
+	assert.ErrorIs(t, err, common.ErrStaleVersion)
+	assert.Equal(t, int64(1), stale.Version)
+	_, err = sqlite.ID().UpdateStruct("Versioned", &stale)
+	assert.ErrorIs(t, err, common.ErrStaleVersion)
+
+	record.Name = "D"
+	rowsAffected, err = sqlite.ID().UpdateStruct("Versioned", record)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+	assert.Equal(t, int64(3), record.Version)
+
+	var versions []sqliteVersioned
+	_, err = sqlite.Query(&common.Query{TableName: "Versioned", DataStruct: &sqliteVersioned{}, Fields: []string{"*"}},
+		func(search *common.Query, result *common.Result) error {
+			versions = append(versions, *result.Data.(*sqliteVersioned))
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []sqliteVersioned{{1, "D", 3}}, versions)
+
+	inserted := &sqliteVersioned{ID: 2, Name: "E"}
+	_, err = sqlite.ID().Upsert("Versioned", &common.Entries{DataStruct: inserted, Fields: []string{"*"},
+		Values: [][]any{{inserted}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), inserted.Version)
+	updated := &sqliteVersioned{ID: 1, Name: "F", Version: 3}
+	_, err = sqlite.ID().Upsert("Versioned", &common.Entries{DataStruct: updated, Fields: []string{"*"},
+		Values: [][]any{{updated}}})
+	assert.NoError(t, err)
+
+	versions = nil
+	_, err = sqlite.Query(&common.Query{TableName: "Versioned", DataStruct: &sqliteVersioned{}, Fields: []string{"*"},
+		Order: []string{"ID:ASC"}}, func(search *common.Query, result *common.Result) error {
+		versions = append(versions, *result.Data.(*sqliteVersioned))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []sqliteVersioned{{1, "F", 4}, {2, "E", 1}}, versions)
+}
+
+type sqliteTimestamped struct {
+	ID      int64 `flynn:"ID:key"`
+	Name    string
+	Created time.Time  `flynn:"Created:created"`
+	Updated time.Time  `flynn:"Updated:updated"`
+	Deleted *time.Time `flynn:"Deleted:deleted"`
+}
+
+func TestSqliteTimestamps(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1016, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	if !assert.NoError(t, sqlite.CreateTable("Timestamped", &sqliteTimestamped{})) {
+		return
+	}
+	records := []*sqliteTimestamped{{ID: 1, Name: "A"}, {ID: 2, Name: "B"}}
+	_, err := sqlite.ID().Insert("Timestamped", &common.Entries{DataStruct: records[0], Fields: []string{"*"},
+		Values: [][]any{{records[0]}, {records[1]}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, records[0].Created.IsZero())
+	assert.Equal(t, records[0].Created, records[0].Updated)
+
+	created := records[0].Created
+	records[0].Name = "C"
+	rowsAffected, err := sqlite.ID().UpdateStruct("Timestamped", records[0])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+	assert.Equal(t, created, records[0].Created)
+	assert.False(t, records[0].Updated.Before(created))
+
+	rowsAffected, err = sqlite.ID().DeleteStruct("Timestamped", records[1])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+	rowsAffected, err = sqlite.ID().Delete("Timestamped", &common.Entries{DataStruct: &sqliteTimestamped{},
+		Fields: []string{"ID"}, Values: [][]any{{2}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), rowsAffected)
+
+	query := func(includeDeleted bool) []sqliteTimestamped {
+		var rows []sqliteTimestamped
+		_, err := sqlite.Query(&common.Query{TableName: "Timestamped", DataStruct: &sqliteTimestamped{},
+			Fields: []string{"*"}, Order: []string{"ID:ASC"}, IncludeDeleted: includeDeleted},
+			func(search *common.Query, result *common.Result) error {
+				rows = append(rows, *result.Data.(*sqliteTimestamped))
+				return nil
+			})
+		assert.NoError(t, err)
+		return rows
+	}
+	rows := query(false)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, "C", rows[0].Name)
+	}
+	rows = query(true)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, "B", rows[1].Name)
+		assert.NotNil(t, rows[1].Deleted)
+	}
+
+	upserted := []*sqliteTimestamped{{ID: 1, Name: "D"}, {ID: 3, Name: "E"}}
+	_, err = sqlite.ID().Upsert("Timestamped", &common.Entries{DataStruct: upserted[0], Fields: []string{"*"},
+		Values: [][]any{{upserted[0]}, {upserted[1]}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, upserted[1].Created.IsZero())
+	assert.Equal(t, upserted[1].Created, upserted[1].Updated)
+	rows = query(false)
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, "D", rows[0].Name)
+		assert.True(t, created.Equal(rows[0].Created), "created %v kept, got %v", created, rows[0].Created)
+		assert.True(t, upserted[0].Updated.Equal(rows[0].Updated))
+		assert.True(t, upserted[1].Created.Equal(rows[1].Created))
+	}
+}
+
+type sqliteAdaptOld struct {
+	ID   int64
+	Name string
+	Old  string
+}
+
+type sqliteAdaptNew struct {
+	ID     int64
+	Name   string
+	Street string
+}
+
+type sqliteAdaptLength struct {
+	ID     int64
+	Name   string `flynn:"Name::50"`
+	Street string
+	City   string
+}
+
+func TestSqliteAdaptPlan(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1017, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	id := sqlite.ID()
+	if !assert.NoError(t, sqlite.CreateTable("Adapted", &sqliteAdaptOld{})) {
+		return
+	}
+	_, err := id.PlanAdaptTable("Unknown", &sqliteAdaptNew{})
+	assert.Error(t, err)
+
+	plan, err := id.PlanAdaptTable("Adapted", &sqliteAdaptNew{})
+	if !assert.NoError(t, err) || !assert.Len(t, plan.Changes, 2) {
+		return
+	}
+	assert.Equal(t, common.AddColumn, plan.Changes[0].Type)
+	assert.Equal(t, "Street", plan.Changes[0].Column)
+	assert.Equal(t, common.DropColumn, plan.Changes[1].Type)
+	assert.True(t, plan.Destructive())
+	assert.Equal(t, "ALTER TABLE Adapted ADD COLUMN Street VARCHAR(255);\nALTER TABLE Adapted DROP COLUMN Old;\n",
+		plan.SQL())
+
+	// dry run plan does not change the table
+	columns, err := sqlite.GetTableColumn("Adapted")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "old"}, columns)
+
+	assert.Error(t, id.ApplyAdaptPlan(plan, nil))
+	assert.NoError(t, id.AdaptTable("Adapted", &sqliteAdaptNew{}))
+	columns, err = sqlite.GetTableColumn("Adapted")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "old", "street"}, columns)
+
+	plan, err = id.PlanAdaptTable("Adapted", &sqliteAdaptNew{})
+	if assert.NoError(t, err) && assert.Len(t, plan.Changes, 1) {
+		assert.NoError(t, id.ApplyAdaptPlan(plan, &common.AdaptOptions{Destructive: true}))
+	}
+	columns, err = sqlite.GetTableColumn("Adapted")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "street"}, columns)
+
+	// changed length is not supported by SQLite and only reported
+	plan, err = id.PlanAdaptTable("Adapted", &sqliteAdaptLength{})
+	if assert.NoError(t, err) && assert.Len(t, plan.Changes, 2) {
+		assert.Equal(t, common.AlterColumn, plan.Changes[0].Type)
+		assert.True(t, plan.Changes[0].Unsupported)
+		assert.Equal(t, "ALTER TABLE Adapted ADD COLUMN City VARCHAR(255);\n", plan.SQL())
+	}
+	assert.NoError(t, id.AdaptTable("Adapted", &sqliteAdaptLength{}))
+	columns, err = sqlite.GetTableColumn("Adapted")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "street", "city"}, columns)
+}
+
+func TestSqliteAdapt(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1020, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	id := sqlite.ID()
+	columns := []*common.Column{{Name: "Id", DataType: common.Alpha, Length: 8},
+		{Name: "Name", DataType: common.Alpha, Length: 10},
+		{Name: "FirstName", DataType: common.Alpha, Length: 20},
+		{Name: "LastName", DataType: common.Alpha, Length: 20}}
+	if !assert.NoError(t, id.CreateTable("AdaptRecords", columns)) {
+		return
+	}
+	_, err := id.Insert("AdaptRecords", &common.Entries{Fields: []string{"Id", "Name", "FirstName", "LastName"},
+		Values: [][]any{{"TEST1", "Eins", "Ernie", "Sesamstrasse"}, {"TEST2", "Letztes", "Anton", "X"}}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the longer columns of the structure cannot be altered by SQLite and
+	// are skipped, the missing columns are added
+	newStructure := struct {
+		Id        string
+		Name      string
+		FirstName string
+		LastName  string
+		Street    string
+		Home      bool
+		Counter   int
+	}{"TEST3", "Müller abc", "Otto", "Walkes", "Sonnenalle", false, 100}
+	if !assert.NoError(t, id.AdaptTable("AdaptRecords", &newStructure)) {
+		return
+	}
+	_, err = id.Insert("AdaptRecords", &common.Entries{Fields: []string{"*"},
+		DataStruct: newStructure, Values: [][]any{{newStructure}}})
+	assert.NoError(t, err)
+
+	err = id.AdaptTable("AdaptRecords", []*common.Column{{Name: "adaptId", DataType: common.Alpha, Length: 8},
+		{Name: "adaptName", DataType: common.Alpha, Length: 10}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = id.Insert("AdaptRecords", &common.Entries{Fields: []string{"Id", "adaptId", "adaptName", "Counter"},
+		Values: [][]any{{"TEST4", "A4", "Adapted", -1}}})
+	assert.NoError(t, err)
+	c, err := sqlite.GetTableColumn("AdaptRecords")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "firstname", "lastname", "street", "home", "counter",
+		"adaptid", "adaptname"}, c)
+
+	assert.NoError(t, id.DeleteTable("AdaptRecords"))
+	assert.Error(t, id.DeleteTable("AdaptRecords"))
+}
+
+func TestSqliteTableContext(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1021, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	id := sqlite.ID()
+	ctx := context.Background()
+	if !assert.NoError(t, id.CreateTableContext(ctx, "Contexted", &sqliteAdaptNew{})) {
+		return
+	}
+	columns, err := id.GetTableColumnContext(ctx, "Contexted")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "street"}, columns)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, id.DeleteTableContext(canceled, "Contexted"), context.Canceled)
+	_, err = id.TablesContext(canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, id.DeleteTableContext(ctx, "Contexted"))
+}
+
+func TestSqliteClone(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1022, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	if !assert.NoError(t, sqlite.CreateTable("Cloned", &sqliteAdaptNew{})) {
+		return
+	}
+	clone := sqlite.Clone()
+	columns, err := clone.GetTableColumn("Cloned")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "street"}, columns)
+	clone.Close()
+	clone.FreeHandler()
+
+	// the original still uses the shared database
+	columns, err = sqlite.ID().GetTableColumn("Cloned")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "street"}, columns)
+	_, err = sqlite.ID().Insert("Cloned", &common.Entries{Fields: []string{"ID", "Name"},
+		Values: [][]any{{1, "After free"}}})
+	assert.NoError(t, err)
+}
+
+func TestSqliteDescribeTable(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1018, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	id := sqlite.ID()
+	err := id.Batch(`CREATE TABLE Described (ID INTEGER PRIMARY KEY, Name VARCHAR(100) NOT NULL DEFAULT 'none',
+		Code CHAR(4) UNIQUE, Amount DECIMAL(10,5), Tenant BIGINT, Created TIMESTAMP)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, id.Batch("CREATE INDEX DescribedTenant ON Described (Tenant, Created)"))
+
+	info, err := id.DescribeTable("described")
+	if !assert.NoError(t, err) || !assert.Len(t, info.Columns, 6) {
+		return
+	}
+	assert.Equal(t, []string{"ID"}, info.PrimaryKey())
+	assert.Equal(t, &common.Column{Name: "Name", DataType: common.Alpha, Length: 100, Default: "'none'"},
+		info.Column("name"))
+	code := info.Column("Code")
+	assert.Equal(t, []any{common.Character, uint16(4), true, true, true},
+		[]any{code.DataType, code.Length, code.Nullable, code.Unique, code.Index})
+	amount := info.Column("Amount")
+	assert.Equal(t, []any{common.Decimal, uint16(10), uint8(5), false},
+		[]any{amount.DataType, amount.Length, amount.Digits, amount.Index})
+	assert.True(t, info.Column("Tenant").Index)
+	assert.False(t, info.Column("Tenant").Unique)
+	assert.Equal(t, common.CurrentTimestamp, info.Column("Created").DataType)
+
+	_, err = id.DescribeTable("Unknown")
+	assert.Error(t, err)
+}
+
+type sqliteCustomer struct {
+	ID   int64  `flynn:"ID:key"`
+	Mail string `flynn:"Mail::100:unique"`
+}
+
+type sqliteInvoice struct {
+	Shop       string `flynn:"Shop:key:10"`
+	Number     int64  `flynn:"Number:key"`
+	CustomerID int64  `flynn:"CustomerID:::notnull:references=Customers(ID):index"`
+	State      string `flynn:"State::1:notnull:default='N'"`
+}
+
+func TestSqliteConstraints(t *testing.T) {
+	InitLog(t)
+
+	sqlite := sqliteInstance(t, 1019, "sqlite://:memory:")
+	if sqlite == nil {
+		return
+	}
+	id := sqlite.ID()
+	if !assert.NoError(t, id.CreateTable("Customers", &sqliteCustomer{})) ||
+		!assert.NoError(t, id.CreateTable("Invoices", &sqliteInvoice{})) {
+		return
+	}
+	info, err := id.DescribeTable("Invoices")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"Shop", "Number"}, info.PrimaryKey())
+	customer := info.Column("CustomerID")
+	assert.Equal(t, []any{false, true}, []any{customer.Nullable, customer.Index})
+	assert.Equal(t, "'N'", info.Column("State").Default)
+	info, err = id.DescribeTable("Customers")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"ID"}, info.PrimaryKey())
+		assert.True(t, info.Column("Mail").Unique)
+	}
+
+	_, err = id.Insert("Customers", &common.Entries{Fields: []string{"ID", "Mail"},
+		Values: [][]any{{1, "a@example.com"}}})
+	assert.NoError(t, err)
+	_, err = id.Insert("Customers", &common.Entries{Fields: []string{"ID", "Mail"},
+		Values: [][]any{{2, "a@example.com"}}})
+	assert.Error(t, err)
+	_, err = id.Insert("Invoices", &common.Entries{Fields: []string{"Shop", "Number", "CustomerID"},
+		Values: [][]any{{"A", 1, 1}}})
+	assert.NoError(t, err)
+	_, err = id.Insert("Invoices", &common.Entries{Fields: []string{"Shop", "Number", "CustomerID"},
+		Values: [][]any{{"A", 1, 1}}})
+	assert.Error(t, err)
+
+	assert.NoError(t, id.CreateIndex("Invoices", &common.Index{Name: "InvoicesState", Columns: []string{"State", "Shop"}}))
+	info, err = id.DescribeTable("Invoices")
+	if assert.NoError(t, err) {
+		assert.True(t, info.Column("State").Index)
+	}
+	assert.NoError(t, id.DropIndex("Invoices", "InvoicesState"))
+	info, err = id.DescribeTable("Invoices")
+	if assert.NoError(t, err) {
+		assert.False(t, info.Column("State").Index)
+	}
+	assert.Error(t, id.CreateIndex("Invoices", &common.Index{Name: "Empty"}))
+}
@@ -0,0 +1,20 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package flynn
+
+import "github.com/tknie/flynn/common"
+
+// Tx transaction handle returned by RegDbID.Begin
+type Tx = common.Tx
+
+// TxOptions isolation level and read-only options of a transaction handle
+type TxOptions = common.TxOptions
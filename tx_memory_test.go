@@ -0,0 +1,90 @@
+//go:build !flynn_nomemory
+// +build !flynn_nomemory
+
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package flynn
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+	"github.com/tknie/flynn/memory"
+)
+
+var errRetry = errors.New("retry")
+
+// retryMemory memory driver with transactions retried on errRetry
+type retryMemory struct {
+	*memory.Memory
+}
+
+type retryTransaction struct {
+	common.Transaction
+}
+
+func (mem *retryMemory) BeginTx(ctx context.Context, opts *common.TxOptions) (common.Transaction, error) {
+	transaction, err := mem.Memory.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &retryTransaction{transaction}, nil
+}
+
+func (transaction *retryTransaction) Retryable(err error) bool {
+	return errors.Is(err, errRetry)
+}
+
+func TestWithTransactionRetry(t *testing.T) {
+	InitLog(t)
+
+	id := iterTestTable(t)
+	if id == 0 {
+		return
+	}
+	db, err := memory.New(id, "memory://iterator")
+	if !assert.NoError(t, err) {
+		return
+	}
+	common.RegisterDbClient(&retryMemory{db.(*memory.Memory)})
+
+	ctx := context.Background()
+	calls := 0
+	err = id.WithTransaction(ctx, func(tx *Tx) error {
+		calls++
+		if calls < 3 {
+			return errRetry
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = id.WithTransaction(ctx, func(tx *Tx) error {
+		calls++
+		return errRetry
+	})
+	assert.ErrorIs(t, err, errRetry)
+	assert.Equal(t, common.DefaultRetries+1, calls)
+
+	calls = 0
+	err = id.WithTransactionOptions(ctx, &TxOptions{Retries: -1}, func(tx *Tx) error {
+		calls++
+		return errRetry
+	})
+	assert.ErrorIs(t, err, errRetry)
+	assert.Equal(t, 1, calls)
+}
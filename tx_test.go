@@ -0,0 +1,162 @@
+/*
+* Copyright 2022-2024 Thorsten A. Knieling
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+ */
+
+package flynn
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tknie/flynn/common"
+)
+
+func TestTxCommitRollback(t *testing.T) {
+	InitLog(t)
+
+	id := iterTestTable(t)
+	if id == 0 {
+		return
+	}
+	ctx := context.Background()
+	tx, err := id.Begin(ctx, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = tx.Insert("IterRecord", &common.Entries{Fields: []string{"*"}, DataStruct: &iterRecord{},
+		Values: [][]any{{&iterRecord{ID: "TX01", Name: "Rollback", Counter: 100}}}})
+	assert.NoError(t, err)
+	count, err := tx.Delete("IterRecord", &common.Entries{Criteria: "Counter <= 5"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+	assert.NoError(t, tx.Rollback())
+	assert.Error(t, tx.Rollback())
+
+	list, err := QueryAll[iterRecord](id, &common.Query{TableName: "IterRecord", Fields: []string{"*"}})
+	assert.NoError(t, err)
+	assert.Len(t, list, 10)
+
+	tx, err = id.Begin(ctx, &TxOptions{Isolation: sql.LevelReadUncommitted})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, count, err = tx.Update("IterRecord", &common.Entries{Fields: []string{"Name"},
+		Criteria: "ID = 'IT01'", Values: [][]any{{"Committed"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.NoError(t, tx.Commit())
+	assert.Error(t, tx.Commit())
+	_, err = tx.Query(&common.Query{TableName: "IterRecord", Fields: []string{"*"}}, nil)
+	assert.Error(t, err)
+
+	record, err := QueryOne[iterRecord](id, &common.Query{TableName: "IterRecord",
+		Fields: []string{"*"}, Search: "ID = 'IT01'"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, record) {
+		assert.Equal(t, "Committed", record.Name)
+	}
+}
+
+func TestTxOptions(t *testing.T) {
+	InitLog(t)
+
+	id := iterTestTable(t)
+	if id == 0 {
+		return
+	}
+	ctx := context.Background()
+	_, err := id.Begin(ctx, &TxOptions{Isolation: sql.LevelSerializable})
+	assert.Error(t, err)
+
+	tx, err := id.Begin(ctx, &TxOptions{ReadOnly: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tx.Rollback()
+	_, err = tx.Insert("IterRecord", &common.Entries{Fields: []string{"*"}, DataStruct: &iterRecord{},
+		Values: [][]any{{&iterRecord{ID: "TX02", Name: "ReadOnly"}}}})
+	assert.Error(t, err)
+	ids := make([]string, 0)
+	_, err = tx.Query(&common.Query{TableName: "IterRecord", Fields: []string{"ID"},
+		Search: "Counter > 8", Order: []string{"ID"}},
+		func(search *common.Query, result *common.Result) error {
+			ids = append(ids, result.Rows[0].(string))
+			return nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"IT09", "IT10"}, ids)
+}
+
+func countIterRecords(t *testing.T, id common.RegDbID) int {
+	list, err := QueryAll[iterRecord](id, &common.Query{TableName: "IterRecord", Fields: []string{"*"}})
+	assert.NoError(t, err)
+	return len(list)
+}
+
+func TestWithTransaction(t *testing.T) {
+	InitLog(t)
+
+	id := iterTestTable(t)
+	if id == 0 {
+		return
+	}
+	ctx := context.Background()
+	insert := func(recordID string) func(tx *Tx) error {
+		return func(tx *Tx) error {
+			_, err := tx.Insert("IterRecord", &common.Entries{Fields: []string{"*"}, DataStruct: &iterRecord{},
+				Values: [][]any{{&iterRecord{ID: recordID, Name: "WithTransaction"}}}})
+			return err
+		}
+	}
+	errAbort := errors.New("abort")
+
+	err := id.WithTransaction(ctx, insert("WT01"))
+	assert.NoError(t, err)
+	assert.Equal(t, 11, countIterRecords(t, id))
+
+	err = id.WithTransaction(ctx, func(tx *Tx) error {
+		if err := insert("WT02")(tx); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+	assert.Equal(t, 11, countIterRecords(t, id))
+
+	assert.PanicsWithValue(t, "panic", func() {
+		id.WithTransaction(ctx, func(tx *Tx) error {
+			insert("WT03")(tx)
+			panic("panic")
+		})
+	})
+	assert.Equal(t, 11, countIterRecords(t, id))
+
+	err = id.WithTransaction(ctx, func(tx *Tx) error {
+		if err := insert("WT04")(tx); err != nil {
+			return err
+		}
+		err := id.WithTransaction(tx.Context(), func(tx *Tx) error {
+			if err := insert("WT05")(tx); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+		return tx.WithTransaction(insert("WT06"))
+	})
+	assert.NoError(t, err)
+	list, err := QueryAll[iterRecord](id, &common.Query{TableName: "IterRecord",
+		Fields: []string{"ID"}, Search: "Name = 'WithTransaction'", Order: []string{"ID"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []iterRecord{{ID: "WT01"}, {ID: "WT04"}, {ID: "WT06"}}, list)
+}
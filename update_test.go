@@ -71,7 +71,7 @@ func updateTest(t *testing.T, target *target) error {
 	if !assert.NoError(t, err) {
 		return err
 	}
-	assert.Equal(t, int64(1), dr)
+	assert.Equal(t, int64(2), dr)
 	return nil
 }
 